@@ -0,0 +1,253 @@
+// Package numfmt formats numbers for people to read, extending the
+// comma function of gopl.io/ch3/comma to signs, decimals, floats and
+// the conventions of several locales, and parses them back.
+//
+// A Locale determines the digit grouping and the decimal separator:
+//
+//	EN.Int(1234567)          // "1,234,567"
+//	DE.Float(-1234.5, 2)     // "-1.234,50"
+//	FR.Float(1234.5, 1)      // "1 234,5" (with a narrow no-break space)
+//	IN.Int(12345678)         // "1,23,45,678" (lakh and crore)
+//	EN.Bytes(1536)           // "1.5 KiB"
+//	EN.SI(0.0042, 1, "s")    // "4.2 ms"
+//
+// Parse, ParseBytes and ParseSI read the same forms back.
+package numfmt
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Locale describes how numbers are written in some region.
+type Locale struct {
+	Name     string
+	Group    string // separates groups of digits in the integer part
+	Decimal  string // separates the integer and fractional parts
+	Grouping []int  // sizes of the groups from the right; the last repeats; none => no grouping
+}
+
+// Common locales.
+var (
+	EN = Locale{"en", ",", ".", []int{3}}
+	DE = Locale{"de", ".", ",", []int{3}}
+	FR = Locale{"fr", "\u202f", ",", []int{3}} // narrow no-break space
+	IN = Locale{"en-IN", ",", ".", []int{3, 2}}
+)
+
+var locales = map[string]Locale{"en": EN, "de": DE, "fr": FR, "en-in": IN}
+
+// Lookup returns the locale with the specified name,
+// such as "de" or "en_IN", ignoring case.
+func Lookup(name string) (Locale, bool) {
+	l, ok := locales[strings.ReplaceAll(strings.ToLower(name), "_", "-")]
+	return l, ok
+}
+
+// Comma inserts commas in a decimal number string such as
+// "-1234567.891", as comma does for non-negative integers.
+func Comma(s string) string { return EN.Number(s) }
+
+// Number formats a decimal number string in the form produced by
+// strconv, with an optional sign, fraction and exponent, using the
+// grouping and decimal separator of l. It returns any other
+// string unchanged.
+func (l Locale) Number(s string) string {
+	sign, intPart, frac, exp, ok := split(s)
+	if !ok {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	b.WriteString(l.group(intPart))
+	if frac != "" {
+		b.WriteString(l.Decimal)
+		b.WriteString(frac)
+	}
+	b.WriteString(exp)
+	return b.String()
+}
+
+// split divides a decimal number string into its sign, integer
+// part, fraction (without the point) and exponent, if any.
+func split(s string) (sign, intPart, frac, exp string, ok bool) {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		s, exp = s[:i], s[i:]
+		if !isExponent(exp) {
+			return "", "", "", "", false
+		}
+	}
+	intPart, frac, hasPoint := strings.Cut(s, ".")
+	if intPart == "" || !isDigits(intPart) || hasPoint && (frac == "" || !isDigits(frac)) {
+		return "", "", "", "", false
+	}
+	return sign, intPart, frac, exp, true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isExponent(s string) bool {
+	s = s[1:] // skip 'e'
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return s != "" && isDigits(s)
+}
+
+// groupSize returns the size of the i'th group of digits from the
+// right, or 0 if the rest of the digits are not grouped.
+func (l Locale) groupSize(i int) int {
+	if len(l.Grouping) == 0 {
+		return 0
+	}
+	return l.Grouping[min(i, len(l.Grouping)-1)]
+}
+
+// group inserts l.Group between the groups of a string of digits.
+func (l Locale) group(digits string) string {
+	var groups []string
+	for i := 0; len(digits) > 0; i++ {
+		size := l.groupSize(i)
+		if size <= 0 || size >= len(digits) {
+			groups = append(groups, digits)
+			break
+		}
+		groups = append(groups, digits[len(digits)-size:])
+		digits = digits[:len(digits)-size]
+	}
+	// Reverse the groups, which were collected from the right.
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+	return strings.Join(groups, l.Group)
+}
+
+// Int formats an integer.
+func (l Locale) Int(n int64) string {
+	return l.Number(strconv.FormatInt(n, 10))
+}
+
+// Float formats f with prec digits after the decimal separator,
+// or as few as represent f exactly if prec is negative.
+func (l Locale) Float(f float64, prec int) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return special(f)
+	}
+	return l.Number(strconv.FormatFloat(f, 'f', prec, 64))
+}
+
+// Sci formats f in scientific notation, such as "1.23e+06",
+// with prec digits after the decimal separator, or as few as
+// represent f exactly if prec is negative.
+func (l Locale) Sci(f float64, prec int) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return special(f)
+	}
+	return l.Number(strconv.FormatFloat(f, 'e', prec, 64))
+}
+
+func special(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f > 0:
+		return "∞"
+	}
+	return "-∞"
+}
+
+// The IEC binary prefixes, for powers of 1024.
+var binaryPrefixes = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+
+// Bytes formats a number of bytes using binary prefixes,
+// such as "1.5 KiB" or "12.0 GiB", with one decimal,
+// or exactly if there are fewer than 1024.
+func (l Locale) Bytes(n int64) string {
+	if n > -1024 && n < 1024 {
+		return l.Int(n) + " B"
+	}
+	f := float64(n)
+	i := 0
+	for math.Abs(f) >= 1024 && i < len(binaryPrefixes)-1 {
+		f /= 1024
+		i++
+	}
+	// Rounding may carry to the next prefix, as in 1023.96 KiB.
+	if math.Abs(f) >= 1023.95 && i < len(binaryPrefixes)-1 {
+		f /= 1024
+		i++
+	}
+	return l.Float(f, 1) + " " + binaryPrefixes[i] + "B"
+}
+
+// BytesSI formats a number of bytes using decimal SI prefixes,
+// such as "1.5 kB" or "12.0 GB", with one decimal,
+// or exactly if there are fewer than 1000.
+func (l Locale) BytesSI(n int64) string {
+	if n > -1000 && n < 1000 {
+		return l.Int(n) + " B"
+	}
+	return l.SI(float64(n), 1, "B")
+}
+
+// An siPrefix is a metric prefix for 10 to the power exp.
+type siPrefix struct {
+	symbol string
+	exp    int
+}
+
+var siPrefixes = []siPrefix{
+	{"q", -30}, {"r", -27}, {"y", -24}, {"z", -21}, {"a", -18},
+	{"f", -15}, {"p", -12}, {"n", -9}, {"µ", -6}, {"m", -3},
+	{"", 0},
+	{"k", 3}, {"M", 6}, {"G", 9}, {"T", 12}, {"P", 15},
+	{"E", 18}, {"Z", 21}, {"Y", 24}, {"R", 27}, {"Q", 30},
+}
+
+// SI formats v in the specified unit with the SI prefix that makes its
+// value at least 1 and less than 1000, if there is one, such as
+// "4.2 ms" or "1.2 GW", with prec digits after the decimal separator.
+func (l Locale) SI(v float64, prec int, unit string) string {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return l.Float(v, prec) + " " + unit
+	}
+	i := prefixIndex(math.Abs(v))
+	scaled := shift(v, -siPrefixes[i].exp)
+	// Rounding may carry to the next prefix, as in 999.96 k.
+	if s := strconv.FormatFloat(math.Abs(scaled), 'f', prec, 64); len(s) >= 4 && s[:4] == "1000" && i < len(siPrefixes)-1 {
+		i++
+		scaled = shift(v, -siPrefixes[i].exp)
+	}
+	return l.Float(scaled, prec) + " " + siPrefixes[i].symbol + unit
+}
+
+// prefixIndex returns the index of the largest prefix
+// no greater than the positive value v, or of the smallest.
+func prefixIndex(v float64) int {
+	for i := len(siPrefixes) - 1; i > 0; i-- {
+		if v >= math.Pow10(siPrefixes[i].exp) {
+			return i
+		}
+	}
+	return 0
+}
+
+// shift returns v×10^exp. Dividing by a power of ten, which is exact,
+// rounds better than multiplying by its inexact inverse.
+func shift(v float64, exp int) float64 {
+	if exp < 0 {
+		return v / math.Pow10(-exp)
+	}
+	return v * math.Pow10(exp)
+}
@@ -0,0 +1,297 @@
+package numfmt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// plain is a locale without grouping.
+var plain = Locale{Name: "x", Group: ",", Decimal: "."}
+
+func TestNumber(t *testing.T) {
+	for _, test := range []struct {
+		l    Locale
+		s    string
+		want string
+	}{
+		{EN, "1", "1"},
+		{EN, "123", "123"},
+		{EN, "1234", "1,234"},
+		{EN, "1234567890", "1,234,567,890"},
+		{EN, "-1234567.891", "-1,234,567.891"},
+		{EN, "+1234", "+1,234"},
+		{EN, "0.000123", "0.000123"},
+		{EN, "1234.5e+06", "1,234.5e+06"},
+		{EN, "12a4", "12a4"},
+		{EN, "1234.", "1234."},
+		{EN, "", ""},
+		{DE, "-1234567.891", "-1.234.567,891"},
+		{FR, "1234567.5", "1\u202f234\u202f567,5"},
+		{IN, "1234", "1,234"},
+		{IN, "123456", "1,23,456"},
+		{IN, "123456789.25", "12,34,56,789.25"},
+		{plain, "-1234567.5", "-1234567.5"},
+	} {
+		if got := test.l.Number(test.s); got != test.want {
+			t.Errorf("%s.Number(%q) = %q, want %q", test.l.Name, test.s, got, test.want)
+		}
+	}
+	if got := plain.Int(1234); got != "1234" {
+		t.Errorf("Int(1234) without grouping = %q", got)
+	}
+}
+
+func TestFloat(t *testing.T) {
+	for _, test := range []struct {
+		got, want string
+	}{
+		{EN.Float(1234.5678, 2), "1,234.57"},
+		{EN.Float(-0.5, 0), "-0"},
+		{EN.Float(1e6, -1), "1,000,000"},
+		{DE.Float(1234.5, 2), "1.234,50"},
+		{EN.Float(math.Inf(-1), 2), "-∞"},
+		{EN.Float(math.NaN(), 2), "NaN"},
+		{EN.Sci(1234567, 2), "1.23e+06"},
+		{DE.Sci(-0.000123, -1), "-1,23e-04"},
+		{EN.Int(math.MinInt64), "-9,223,372,036,854,775,808"},
+		{IN.Int(-12345678), "-1,23,45,678"},
+	} {
+		if test.got != test.want {
+			t.Errorf("got %q, want %q", test.got, test.want)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	for _, test := range []struct {
+		n       int64
+		iec, si string
+	}{
+		{0, "0 B", "0 B"},
+		{999, "999 B", "999 B"},
+		{1000, "1,000 B", "1.0 kB"},
+		{1023, "1,023 B", "1.0 kB"},
+		{1024, "1.0 KiB", "1.0 kB"},
+		{1536, "1.5 KiB", "1.5 kB"},
+		{999_960, "976.5 KiB", "1.0 MB"},
+		{1<<20 - 1, "1.0 MiB", "1.0 MB"},
+		{5 << 30, "5.0 GiB", "5.4 GB"},
+		{-1536, "-1.5 KiB", "-1.5 kB"},
+		{math.MaxInt64, "8.0 EiB", "9.2 EB"},
+	} {
+		if got := EN.Bytes(test.n); got != test.iec {
+			t.Errorf("Bytes(%d) = %q, want %q", test.n, got, test.iec)
+		}
+		if got := EN.BytesSI(test.n); got != test.si {
+			t.Errorf("BytesSI(%d) = %q, want %q", test.n, got, test.si)
+		}
+	}
+}
+
+func TestSI(t *testing.T) {
+	for _, test := range []struct {
+		v    float64
+		prec int
+		unit string
+		want string
+	}{
+		{0.0042, 1, "s", "4.2 ms"},
+		{0, 1, "s", "0.0 s"},
+		{1, 0, "W", "1 W"},
+		{999.96, 1, "W", "1.0 kW"},
+		{1.2e9, 1, "W", "1.2 GW"},
+		{-3.3e-7, 2, "A", "-330.00 nA"},
+		{1e-40, 1, "m", "0.0 qm"},
+		{5e33, 0, "g", "5,000 Qg"},
+	} {
+		if got := EN.SI(test.v, test.prec, test.unit); got != test.want {
+			t.Errorf("SI(%g, %d, %q) = %q, want %q", test.v, test.prec, test.unit, got, test.want)
+		}
+	}
+	if got, want := DE.SI(1500, 1, "m"), "1,5 km"; got != want {
+		t.Errorf("DE.SI = %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		l    Locale
+		s    string
+		want float64
+	}{
+		{EN, "1,234,567.5", 1234567.5},
+		{EN, "1234567.5", 1234567.5},
+		{EN, " -12 ", -12},
+		{EN, "+1.5e3", 1500},
+		{EN, "−7", -7},
+		{EN, "-∞", math.Inf(-1)},
+		{DE, "-1.234,5", -1234.5},
+		{DE, "1.234", 1234},
+		{FR, "1\u202f234,5", 1234.5},
+		{FR, "1\u202f234\u202f567", 1234567},
+		{FR, "1\u00a0234", 1234},
+		{FR, "1 234", 1234},
+		{IN, "1,23,45,678.9", 12345678.9},
+		{IN, "12,345", 12345},
+		{plain, "1234567.5", 1234567.5},
+	} {
+		got, err := test.l.Parse(test.s)
+		if err != nil || got != test.want {
+			t.Errorf("%s.Parse(%q) = %g, %v, want %g", test.l.Name, test.s, got, err, test.want)
+		}
+	}
+	for _, test := range []struct {
+		l Locale
+		s string
+	}{
+		{EN, ""},
+		{EN, "-"},
+		{EN, "1,2345"},
+		{EN, "12,34,567"}, // lakh grouping is not English
+		{EN, "1234,567"},
+		{EN, ",123"},
+		{EN, "1.2.3"},
+		{EN, "1."},
+		{EN, ".5"},
+		{EN, "1e"},
+		{EN, "1e400"},
+		{DE, "1,234.5"},
+		{IN, "1,234,567"},
+		{plain, "1,234"},
+	} {
+		if got, err := test.l.Parse(test.s); err == nil {
+			t.Errorf("%s.Parse(%q) = %g, want error", test.l.Name, test.s, got)
+		}
+	}
+	if !math.IsNaN(must(EN.Parse("NaN"))) {
+		t.Error(`Parse("NaN") is not NaN`)
+	}
+}
+
+func must(f float64, err error) float64 {
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// TestRoundTrip checks that Parse reads what Float, Sci and Int write.
+func TestRoundTrip(t *testing.T) {
+	for _, l := range []Locale{EN, DE, FR, IN} {
+		for _, f := range []float64{0, 1, -1, 0.25, 1234.5, -98765432.125, 1e15, 123456789012} {
+			for _, test := range []struct {
+				s    string
+				want float64
+			}{
+				{l.Float(f, -1), f},
+				{l.Sci(f, -1), f},
+				{l.Int(int64(f)), math.Trunc(f)},
+			} {
+				if got, err := l.Parse(test.s); err != nil || got != test.want {
+					t.Errorf("%s.Parse(%q) = %g, %v, want %g", l.Name, test.s, got, err, test.want)
+				}
+			}
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, test := range []struct {
+		l    Locale
+		s    string
+		want int64
+	}{
+		{EN, "100", 100},
+		{EN, "100 B", 100},
+		{EN, "1.5 KiB", 1536},
+		{EN, "1.5KiB", 1536},
+		{EN, "1.5 kib", 1536},
+		{EN, "2GB", 2e9},
+		{EN, "2 gb", 2e9},
+		{EN, "2G", 2e9},
+		{EN, "3 Mi", 3 << 20},
+		{EN, "1,024 B", 1024},
+		{DE, "1,5 KiB", 1536},
+		{EN, "-1 KiB", -1024},
+		{EN, "8 EiB", 0}, // out of range
+	} {
+		got, err := test.l.ParseBytes(test.s)
+		if test.want == 0 {
+			if err == nil {
+				t.Errorf("ParseBytes(%q) = %d, want error", test.s, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%s.ParseBytes(%q) = %d, %v, want %d", test.l.Name, test.s, got, err, test.want)
+		}
+	}
+	for _, s := range []string{"", "KiB", "1 XB", "1 iB", "1 KiBB", "NaN"} {
+		if got, err := EN.ParseBytes(s); err == nil {
+			t.Errorf("ParseBytes(%q) = %d, want error", s, got)
+		}
+	}
+	// Every size that Bytes writes exactly parses back.
+	for _, n := range []int64{0, 1, 1023, 1024, 1536, 3 << 20} {
+		if got, err := EN.ParseBytes(EN.Bytes(n)); err != nil || got != n {
+			t.Errorf("ParseBytes(Bytes(%d)) = %d, %v", n, got, err)
+		}
+	}
+}
+
+func TestParseSI(t *testing.T) {
+	for _, test := range []struct {
+		s, unit string
+		want    float64
+	}{
+		{"4.2 ms", "s", 0.0042},
+		{"4.2ms", "s", 0.0042},
+		{"5 us", "s", 5e-6},
+		{"5 µs", "s", 5e-6},
+		{"5 mm", "m", 0.005},
+		{"5 m", "m", 5},
+		{"1.2 GW", "W", 1.2e9},
+		{"1.5e3 km", "m", 1.5e6},
+		{"0.1 ns", "s", 1e-10},
+		{"12", "", 12},
+	} {
+		got, err := EN.ParseSI(test.s, test.unit)
+		if err != nil || got != test.want {
+			t.Errorf("ParseSI(%q, %q) = %g, %v, want %g", test.s, test.unit, got, err, test.want)
+		}
+	}
+	for _, s := range []string{"5 s", "5 Xm", "∞ m", "m"} {
+		if got, err := EN.ParseSI(s, "m"); err == nil {
+			t.Errorf("ParseSI(%q, m) = %g, want error", s, got)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"en", "DE", "fr", "en_IN", "en-in"} {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("Lookup(%q) failed", name)
+		}
+	}
+	if _, ok := Lookup("xx"); ok {
+		t.Error(`Lookup("xx") succeeded`)
+	}
+}
+
+func Example() {
+	fmt.Println(Comma("-1234567.891"))
+	fmt.Println(DE.Float(1234567.891, 2))
+	fmt.Println(IN.Int(12345678))
+	fmt.Println(EN.Bytes(5 << 30))
+	fmt.Println(EN.SI(0.0042, 1, "s"))
+	n, _ := EN.ParseBytes("1.5 KiB")
+	fmt.Println(n)
+	// Output:
+	// -1,234,567.891
+	// 1.234.567,89
+	// 1,23,45,678
+	// 5.0 GiB
+	// 4.2 ms
+	// 1536
+}
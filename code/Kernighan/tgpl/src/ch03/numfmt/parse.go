@@ -0,0 +1,194 @@
+package numfmt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse parses a number written in the conventions of l, such as
+// "-1.234,5" in DE or "1,23,456.7e3" in IN. Group separators are
+// optional, but if present they must be where l puts them. For FR,
+// a space or no-break space may separate the groups too.
+func (l Locale) Parse(s string) (float64, error) {
+	d, err := l.decimal(s)
+	if err != nil {
+		return 0, err
+	}
+	return l.parseFloat(s, d)
+}
+
+// decimal returns the number s in the form read by strconv.ParseFloat.
+func (l Locale) decimal(s string) (string, error) {
+	sign, num := "", strings.TrimSpace(s)
+	if num != "" && (num[0] == '-' || num[0] == '+') {
+		sign, num = num[:1], num[1:]
+	} else if strings.HasPrefix(num, "−") { // minus sign
+		sign, num = "-", num[len("−"):]
+	}
+	switch num {
+	case "∞", "Inf", "inf":
+		return sign + "Inf", nil
+	case "NaN":
+		return "NaN", nil
+	}
+	num, exp := cutExponent(num)
+	intPart, frac, hasPoint := strings.Cut(num, l.Decimal)
+	digits, ok := l.ungroup(intPart)
+	if !ok || hasPoint && (frac == "" || !isDigits(frac)) || exp != "" && !isExponent(exp) {
+		return "", fmt.Errorf("numfmt: invalid %s number %q", l.Name, s)
+	}
+	if hasPoint {
+		digits += "." + frac
+	}
+	return sign + digits + exp, nil
+}
+
+// parseFloat parses d, the decimal form of s.
+func (l Locale) parseFloat(s, d string) (float64, error) {
+	f, err := strconv.ParseFloat(d, 64)
+	if err != nil {
+		return 0, fmt.Errorf("numfmt: invalid %s number %q: %v", l.Name, s, err.(*strconv.NumError).Err)
+	}
+	return f, nil
+}
+
+// cutExponent splits s before an exponent such as "e+06", if any.
+func cutExponent(s string) (num, exp string) {
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// ungroup returns the digits of an integer part written with the
+// grouping of l, and whether the groups are the right sizes.
+func (l Locale) ungroup(s string) (string, bool) {
+	if l.Name == FR.Name {
+		s = strings.NewReplacer(" ", FR.Group, "\u00a0", FR.Group).Replace(s)
+	}
+	groups := strings.Split(s, l.Group)
+	if len(groups[0]) == 0 {
+		return "", false
+	}
+	for i := range groups {
+		if !isDigits(groups[i]) {
+			return "", false
+		}
+		if i == 0 {
+			continue
+		}
+		// The groups are counted from the right.
+		if len(groups[i]) != l.groupSize(len(groups)-1-i) {
+			return "", false
+		}
+	}
+	if len(groups) > 1 {
+		if len(groups[0]) > l.groupSize(len(groups)-1) {
+			return "", false
+		}
+	}
+	return strings.Join(groups, ""), true
+}
+
+// ParseBytes parses a size in bytes such as "1.5 KiB", "2GB" or
+// "100", written in the conventions of l. Binary prefixes (Ki, Mi, ...)
+// are powers of 1024; SI prefixes (k, M, ...) are powers of 1000.
+// The unit may be in either case, and the B may be omitted.
+func (l Locale) ParseBytes(s string) (int64, error) {
+	num, unit := cutUnit(s)
+	v, err := l.Parse(num)
+	if err != nil {
+		return 0, err
+	}
+	prefix := strings.TrimSuffix(strings.ToUpper(unit), "B")
+	mult := 1.0
+	if base, ok := strings.CutSuffix(prefix, "I"); ok && base != "" {
+		i := indexOf(binaryPrefixes, base+"i")
+		if i < 0 {
+			return 0, fmt.Errorf("numfmt: unknown unit %q in %q", unit, s)
+		}
+		mult = math.Pow(1024, float64(i))
+	} else if prefix != "" {
+		i := indexOf([]string{"", "K", "M", "G", "T", "P", "E"}, prefix)
+		if i < 0 {
+			return 0, fmt.Errorf("numfmt: unknown unit %q in %q", unit, s)
+		}
+		mult = math.Pow(1000, float64(i))
+	}
+	v = math.Round(v * mult)
+	if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+		return 0, fmt.Errorf("numfmt: size %q out of range", s)
+	}
+	return int64(v), nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, x := range list {
+		if strings.EqualFold(x, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseSI parses a value such as "4.2 ms" or "1,5 kW", written in the
+// conventions of l, in the specified unit with an optional SI prefix.
+// The prefix µ may also be written u.
+// Unlike Parse, ParseSI does not accept infinities or NaN.
+func (l Locale) ParseSI(s, unit string) (float64, error) {
+	num, u := cutUnit(s)
+	prefix, ok := strings.CutSuffix(u, unit)
+	if !ok {
+		return 0, fmt.Errorf("numfmt: %q is not in %s", s, unit)
+	}
+	if prefix == "u" || prefix == "μ" { // Greek mu
+		prefix = "µ"
+	}
+	exp, found := 0, false
+	for _, p := range siPrefixes {
+		if p.symbol == prefix {
+			exp, found = p.exp, true
+			break
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("numfmt: unknown prefix %q in %q", prefix, s)
+	}
+	d, err := l.decimal(num)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasSuffix(d, "Inf") || d == "NaN" {
+		return 0, fmt.Errorf("numfmt: invalid %s number %q", l.Name, num)
+	}
+	// Add the prefix to the exponent, so that the value is
+	// rounded once, as in 4.2e-3 rather than 4.2 × 1e-3.
+	mantissa, e := cutExponent(d)
+	if e != "" {
+		n, err := strconv.Atoi(e[1:])
+		if err != nil {
+			return 0, fmt.Errorf("numfmt: invalid %s number %q", l.Name, num)
+		}
+		exp += n
+	}
+	return l.parseFloat(s, mantissa+"e"+strconv.Itoa(exp))
+}
+
+// cutUnit splits s before the letters of the unit at its end,
+// and removes the spaces between them.
+func cutUnit(s string) (num, unit string) {
+	s = strings.TrimSpace(s)
+	i := len(s)
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(s[:i])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		i -= size
+	}
+	return strings.TrimRightFunc(s[:i], unicode.IsSpace), s[i:]
+}
@@ -0,0 +1,291 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const APIURL = "https://api.github.com"
+
+// A Client makes authenticated requests to the GitHub API.
+// See https://docs.github.com/en/rest/issues.
+type Client struct {
+	BaseURL    string       // e.g. APIURL, or a test server's URL
+	Token      string       // personal access token; "" => anonymous
+	HTTPClient *http.Client // nil => http.DefaultClient
+	MaxRetries int          // times to retry a rate-limited request
+	MaxWait    time.Duration
+
+	sleep func(time.Duration) // time.Sleep, except in tests
+}
+
+// NewClient returns a client for api.github.com that authenticates
+// with token and waits out up to 3 rate-limit responses of up to a
+// minute each.
+func NewClient(token string) *Client {
+	return &Client{BaseURL: APIURL, Token: token, MaxRetries: 3, MaxWait: time.Minute}
+}
+
+// An IssueRequest creates or updates an issue.
+// Nil and empty fields are left unchanged by UpdateIssue,
+// so a Body that points to "" clears the body.
+type IssueRequest struct {
+	Title     *string   `json:"title,omitempty"`
+	Body      *string   `json:"body,omitempty"`
+	State     string    `json:"state,omitempty"` // "open" or "closed"
+	Labels    *[]string `json:"labels,omitempty"`
+	Assignees *[]string `json:"assignees,omitempty"`
+}
+
+// An Error is an unsuccessful response from the API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("github: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// CreateIssue creates an issue in the repository owner/repo.
+func (c *Client) CreateIssue(owner, repo string, req *IssueRequest) (*Issue, error) {
+	var issue Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues", owner, repo)
+	if _, err := c.do("POST", path, req, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// GetIssue returns the specified issue.
+func (c *Client) GetIssue(owner, repo string, number int) (*Issue, error) {
+	var issue Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number)
+	if _, err := c.do("GET", path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// UpdateIssue changes the specified issue as directed by req.
+func (c *Client) UpdateIssue(owner, repo string, number int, req *IssueRequest) (*Issue, error) {
+	var issue Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number)
+	if _, err := c.do("PATCH", path, req, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// CloseIssue closes the specified issue.
+func (c *Client) CloseIssue(owner, repo string, number int) (*Issue, error) {
+	return c.UpdateIssue(owner, repo, number, &IssueRequest{State: "closed"})
+}
+
+// ListIssues returns all the issues, open and closed, of the
+// repository owner/repo that have been updated since the specified
+// time, or all of them if since is zero. Pull requests, which the
+// API lists as issues, are omitted.
+func (c *Client) ListIssues(owner, repo string, since time.Time) ([]*Issue, error) {
+	q := url.Values{"state": {"all"}, "per_page": {"100"}}
+	if !since.IsZero() {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	var issues []*Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues?%s", owner, repo, q.Encode())
+	for path != "" {
+		var page []*Issue
+		next, err := c.do("GET", path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range page {
+			if issue.PullRequest == nil {
+				issues = append(issues, issue)
+			}
+		}
+		path = next
+	}
+	return issues, nil
+}
+
+// ListComments returns all the comments on the specified issue,
+// following the pagination links of the responses.
+func (c *Client) ListComments(owner, repo string, number int) ([]*Comment, error) {
+	var comments []*Comment
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments?per_page=100", owner, repo, number)
+	for path != "" {
+		var page []*Comment
+		next, err := c.do("GET", path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, page...)
+		path = next
+	}
+	return comments, nil
+}
+
+// SearchIssues returns the issues that match the search terms,
+// following the pagination links of the responses. Unlike the
+// function of the same name, it authenticates with c.Token and
+// returns all the matches, not just the first page.
+func (c *Client) SearchIssues(terms []string) (*IssuesSearchResult, error) {
+	q := url.Values{"q": {strings.Join(terms, " ")}, "per_page": {"100"}}
+	result := &IssuesSearchResult{}
+	path := "/search/issues?" + q.Encode()
+	for path != "" {
+		var page IssuesSearchResult
+		next, err := c.do("GET", path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalCount = page.TotalCount
+		result.Items = append(result.Items, page.Items...)
+		path = next
+	}
+	return result, nil
+}
+
+// do sends a request with the JSON encoding of body, if not nil, and
+// decodes the JSON response into result. The path may be relative
+// to c.BaseURL or absolute; an absolute URL, such as the link to a
+// next page, must have the scheme and host of c.BaseURL, so that the
+// token is never sent elsewhere. It returns the URL of the next page
+// of results, from the Link header, or "" if there is none.
+//
+// If the response says that the rate limit has been exceeded, do
+// waits until the limit is reset, up to c.MaxWait, and tries again,
+// up to c.MaxRetries times.
+func (c *Client) do(method, path string, body, result interface{}) (next string, err error) {
+	u, err := c.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	if body != nil {
+		if data, err = json.Marshal(body); err != nil {
+			return "", err
+		}
+	}
+
+	for try := 0; ; try++ {
+		req, err := http.NewRequest(method, u, bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		client := c.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode/100 != 2 {
+			apiErr := &Error{StatusCode: resp.StatusCode}
+			var msg struct{ Message string }
+			if json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&msg) == nil {
+				apiErr.Message = msg.Message
+			}
+			resp.Body.Close()
+			if wait, ok := rateLimited(resp); ok && try < c.MaxRetries && wait <= c.MaxWait {
+				sleep := c.sleep
+				if sleep == nil {
+					sleep = time.Sleep
+				}
+				sleep(wait)
+				continue
+			}
+			return "", apiErr
+		}
+
+		defer resp.Body.Close()
+		if result != nil {
+			if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+				return "", fmt.Errorf("decoding response from %s: %v", u, err)
+			}
+		}
+		return nextLink(resp.Header.Get("Link")), nil
+	}
+}
+
+// resolve returns the URL of path, which is relative to c.BaseURL,
+// or absolute with the same scheme and host.
+func (c *Client) resolve(path string) (string, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return strings.TrimSuffix(c.BaseURL, "/") + path, nil
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return "", fmt.Errorf("github: refusing to follow link to %s://%s, not %s://%s",
+			u.Scheme, u.Host, base.Scheme, base.Host)
+	}
+	return path, nil
+}
+
+// rateLimited reports whether resp says that a rate limit was
+// exceeded and, if so, how long to wait before trying again.
+func rateLimited(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	// Secondary rate limits say how long to wait.
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	// Primary rate limits say when the limit is reset.
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false // e.g., permission denied
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Until(time.Unix(reset, 0))
+	if wait < 0 {
+		wait = 0
+	}
+	return wait + time.Second, true // allow for clock skew
+}
+
+// nextLink returns the URL of the "next" relation in a Link
+// header such as `<https://api.github.com/...&page=2>; rel="next"`.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		u := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				if _, err := url.Parse(u); err == nil {
+					return u
+				}
+			}
+		}
+	}
+	return ""
+}
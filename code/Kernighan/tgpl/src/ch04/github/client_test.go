@@ -0,0 +1,198 @@
+package github_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopl.io/ch4/github"
+	"gopl.io/ch4/github/githubtest"
+)
+
+func newClient(t *testing.T) (*github.Client, *githubtest.Server, *[]time.Duration) {
+	t.Helper()
+	srv := githubtest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.RequireToken("secret")
+	c := &github.Client{BaseURL: srv.URL, Token: "secret", MaxRetries: 2, MaxWait: time.Minute}
+	var waits []time.Duration
+	github.SetSleep(c, func(d time.Duration) { waits = append(waits, d) })
+	return c, srv, &waits
+}
+
+func TestIssueLifecycle(t *testing.T) {
+	c, _, _ := newClient(t)
+
+	labels := []string{"bug"}
+	title, body := "crash on start", "It crashes."
+	issue, err := c.CreateIssue("gopher", "demo", &github.IssueRequest{
+		Title:  &title,
+		Body:   &body,
+		Labels: &labels,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Number != 1 || issue.State != "open" || len(issue.Labels) != 1 || issue.Labels[0].Name != "bug" {
+		t.Fatalf("CreateIssue = %+v", issue)
+	}
+
+	body = "It crashes at once."
+	issue, err = c.UpdateIssue("gopher", "demo", 1, &github.IssueRequest{Body: &body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Title != "crash on start" || issue.Body != "It crashes at once." || len(issue.Labels) != 1 {
+		t.Errorf("UpdateIssue = %+v", issue)
+	}
+
+	body = ""
+	issue, err = c.UpdateIssue("gopher", "demo", 1, &github.IssueRequest{Body: &body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Title != "crash on start" || issue.Body != "" {
+		t.Errorf("UpdateIssue to clear body = %+v", issue)
+	}
+
+	if _, err := c.CloseIssue("gopher", "demo", 1); err != nil {
+		t.Fatal(err)
+	}
+	issue, err = c.GetIssue("gopher", "demo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.State != "closed" || issue.ClosedAt == nil {
+		t.Errorf("after CloseIssue, state = %q, closed at %v", issue.State, issue.ClosedAt)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _, _ := newClient(t)
+
+	var apiErr *github.Error
+	_, err := c.GetIssue("gopher", "demo", 42)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("GetIssue of missing issue: err = %v, want 404", err)
+	}
+
+	c.Token = "wrong"
+	title := "x"
+	_, err = c.CreateIssue("gopher", "demo", &github.IssueRequest{Title: &title})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "Bad credentials" {
+		t.Errorf("CreateIssue with bad token: err = %v, want 401 Bad credentials", err)
+	}
+}
+
+func TestListCommentsPagination(t *testing.T) {
+	c, srv, _ := newClient(t)
+	srv.AddIssue("gopher", "demo", github.Issue{Title: "chatty"})
+	const n = 250 // three pages of 100
+	for i := 0; i < n; i++ {
+		srv.AddComment("gopher", "demo", 1, "gopher", fmt.Sprintf("comment %d", i))
+	}
+
+	before := srv.Requests()
+	comments, err := c.ListComments("gopher", "demo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != n {
+		t.Fatalf("got %d comments, want %d", len(comments), n)
+	}
+	for i, comment := range comments {
+		if want := fmt.Sprintf("comment %d", i); comment.Body != want {
+			t.Fatalf("comments[%d] = %q, want %q", i, comment.Body, want)
+		}
+	}
+	if got := srv.Requests() - before; got != 3 {
+		t.Errorf("ListComments made %d requests, want 3", got)
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	c, srv, waits := newClient(t)
+	srv.AddIssue("gopher", "demo", github.Issue{Title: "busy"})
+
+	srv.RateLimit(2)
+	issue, err := c.GetIssue("gopher", "demo", 1)
+	if err != nil {
+		t.Fatalf("GetIssue after 2 rate-limited responses: %v", err)
+	}
+	if issue.Title != "busy" {
+		t.Errorf("GetIssue = %+v", issue)
+	}
+	if len(*waits) != 2 {
+		t.Errorf("waited %d times, want 2", len(*waits))
+	}
+	for _, d := range *waits {
+		if d <= 0 || d > 2*time.Second {
+			t.Errorf("waited %v, want about 1s", d)
+		}
+	}
+
+	// A third rate-limited response exceeds MaxRetries.
+	srv.RateLimit(3)
+	_, err = c.GetIssue("gopher", "demo", 1)
+	var apiErr *github.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("GetIssue after 3 rate-limited responses: err = %v, want 403", err)
+	}
+}
+
+func TestSearchIssues(t *testing.T) {
+	c, srv, _ := newClient(t)
+	for i := 0; i < 150; i++ {
+		srv.AddIssue("gopher", "demo", github.Issue{Title: fmt.Sprintf("leak %d", i)})
+	}
+	srv.AddIssue("gopher", "demo", github.Issue{Title: "crash"})
+	srv.AddIssue("gopher", "other", github.Issue{Title: "another leak"})
+
+	before := srv.Requests()
+	result, err := c.SearchIssues([]string{"repo:gopher/demo", "leak"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalCount != 150 || len(result.Items) != 150 {
+		t.Fatalf("got %d of %d items, want 150", len(result.Items), result.TotalCount)
+	}
+	if got := srv.Requests() - before; got != 2 {
+		t.Errorf("SearchIssues made %d requests, want 2", got)
+	}
+
+	// The server requires the token, so an anonymous search fails.
+	c.Token = ""
+	var apiErr *github.Error
+	if _, err := c.SearchIssues([]string{"leak"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous SearchIssues: err = %v, want 401", err)
+	}
+}
+
+// TestForeignLink checks that the client does not send its token
+// to a host named in a Link header.
+func TestForeignLink(t *testing.T) {
+	var leaked string
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		leaked = req.Header.Get("Authorization")
+		fmt.Fprint(w, "[]")
+	}))
+	defer evil.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf("<%s/steal?page=2>; rel=\"next\"", evil.URL))
+		fmt.Fprint(w, "[]")
+	}))
+	defer api.Close()
+
+	c := &github.Client{BaseURL: api.URL, Token: "secret"}
+	_, err := c.ListComments("gopher", "demo", 1)
+	if err == nil || !strings.Contains(err.Error(), "refusing to follow link") {
+		t.Errorf("ListComments with foreign next link: err = %v", err)
+	}
+	if leaked != "" {
+		t.Errorf("token sent to foreign host: %q", leaked)
+	}
+}
@@ -0,0 +1,6 @@
+package github
+
+import "time"
+
+// SetSleep replaces the function c uses to wait out rate limits.
+func SetSleep(c *Client, sleep func(time.Duration)) { c.sleep = sleep }
@@ -25,6 +25,20 @@ type Issue struct {
 	User      *User
 	CreatedAt time.Time `json:"created_at"`
 	Body      string    // in Markdown format
+	//!-
+
+	// These fields are used by the Client methods.
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	Labels    []*Label
+	Assignees []*User
+	Milestone *Milestone
+	Comments  int // number of comments
+
+	// PullRequest is set if the issue is a pull request, which
+	// the API lists among the issues of a repository.
+	PullRequest *PullRequest `json:"pull_request,omitempty"`
+	//!+
 }
 
 type User struct {
@@ -33,3 +47,27 @@ type User struct {
 }
 
 //!-
+
+type Label struct {
+	Name  string
+	Color string
+}
+
+// A PullRequest links an Issue to the pull request it stands for.
+type PullRequest struct {
+	URL string
+}
+
+type Milestone struct {
+	Number int
+	Title  string
+	State  string
+}
+
+type Comment struct {
+	ID        int64
+	User      *User
+	Body      string
+	CreatedAt time.Time `json:"created_at"`
+	HTMLURL   string    `json:"html_url"`
+}
@@ -0,0 +1,444 @@
+// Package githubtest provides an in-memory stand-in for the parts of
+// the GitHub API used by gopl.io/ch4/github, for testing offline.
+//
+//	srv := githubtest.NewServer()
+//	defer srv.Close()
+//	c := &github.Client{BaseURL: srv.URL}
+//
+// The server supports issues, pull requests as they appear in the list
+// of issues, comments and a simple search, with pagination by Link
+// headers. It can require a token, and it can be told to reject
+// requests as if the rate limit were exceeded.
+package githubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopl.io/ch4/github"
+)
+
+// A Server is a fake GitHub API server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	token    string           // required token, if not empty
+	limited  int              // number of requests still to reject
+	requests int              // number of requests received
+	repos    map[string]*repo // by "owner/repo"
+	now      func() time.Time // clock for timestamps
+}
+
+type repo struct {
+	issues   []*github.Issue // issues[i].Number == i+1
+	comments map[int][]*github.Comment
+}
+
+// NewServer starts and returns a new, empty Server.
+func NewServer() *Server {
+	s := &Server{repos: make(map[string]*repo), now: time.Now}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues", s.listIssues)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/issues", s.createIssue)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues/{number}", s.getIssue)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/issues/{number}", s.updateIssue)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues/{number}/comments", s.listComments)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/issues/{number}/comments", s.createComment)
+	mux.HandleFunc("GET /search/issues", s.search)
+	s.Server = httptest.NewServer(s.check(mux))
+	return s
+}
+
+// RequireToken makes the server reject requests
+// that are not authenticated with token.
+func (s *Server) RequireToken(token string) {
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+}
+
+// RateLimit makes the server reject the next n requests
+// with 403 responses saying the rate limit has been exceeded.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	s.limited = n
+	s.mu.Unlock()
+}
+
+// Requests returns the number of requests received so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// SetClock makes the server use now for the time of changes.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	s.now = now
+	s.mu.Unlock()
+}
+
+// AddIssue adds a copy of issue to owner/repo, giving it the next
+// number, and returns the copy. Zero times are set to now.
+func (s *Server) AddIssue(owner, name string, issue github.Issue) *github.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(owner, name)
+	issue.Number = len(r.issues) + 1
+	issue.HTMLURL = fmt.Sprintf("https://github.com/%s/%s/issues/%d", owner, name, issue.Number)
+	if issue.State == "" {
+		issue.State = "open"
+	}
+	if issue.User == nil {
+		issue.User = &github.User{Login: "gopher"}
+	}
+	if issue.CreatedAt.IsZero() {
+		issue.CreatedAt = s.now()
+	}
+	if issue.UpdatedAt.IsZero() {
+		issue.UpdatedAt = issue.CreatedAt
+	}
+	r.issues = append(r.issues, &issue)
+	return &issue
+}
+
+// AddPullRequest adds a pull request to owner/repo, as AddIssue
+// does, and returns it. The API lists pull requests among the
+// issues, and they share one sequence of numbers.
+func (s *Server) AddPullRequest(owner, name string, pr github.Issue) *github.Issue {
+	issue := s.AddIssue(owner, name, pr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issue.PullRequest = &github.PullRequest{
+		URL: fmt.Sprintf("%s/repos/%s/%s/pulls/%d", s.URL, owner, name, issue.Number),
+	}
+	return issue
+}
+
+// AddComment adds a comment by user to an issue of owner/repo.
+// An empty user stands for a deleted account, which the API
+// reports as a null user.
+func (s *Server) AddComment(owner, name string, number int, user, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addComment(s.repo(owner, name), number, user, body)
+}
+
+func (s *Server) addComment(r *repo, number int, user, body string) *github.Comment {
+	id := 1
+	for _, cs := range r.comments {
+		id += len(cs)
+	}
+	c := &github.Comment{ID: int64(id), Body: body, CreatedAt: s.now()}
+	if user != "" {
+		c.User = &github.User{Login: user}
+	}
+	r.comments[number] = append(r.comments[number], c)
+	if number <= len(r.issues) {
+		r.issues[number-1].Comments++
+		r.issues[number-1].UpdatedAt = c.CreatedAt
+	}
+	return c
+}
+
+// repo returns the named repository, creating it if need be.
+// s.mu must be held.
+func (s *Server) repo(owner, name string) *repo {
+	key := owner + "/" + name
+	r := s.repos[key]
+	if r == nil {
+		r = &repo{comments: make(map[int][]*github.Comment)}
+		s.repos[key] = r
+	}
+	return r
+}
+
+// check counts requests, and rejects those that are rate-limited
+// or lack the required token.
+func (s *Server) check(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		limited := s.limited > 0
+		if limited {
+			s.limited--
+		}
+		token := s.token
+		s.mu.Unlock()
+
+		if limited {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			writeError(w, http.StatusForbidden, "API rate limit exceeded")
+			return
+		}
+		if token != "" && req.Header.Get("Authorization") != "Bearer "+token {
+			writeError(w, http.StatusUnauthorized, "Bad credentials")
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"message": msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// lookup returns the repository and the issue
+// named by the request, or writes an error.
+// s.mu must be held.
+func (s *Server) lookup(w http.ResponseWriter, req *http.Request) (*repo, *github.Issue) {
+	r := s.repos[req.PathValue("owner")+"/"+req.PathValue("repo")]
+	n, err := strconv.Atoi(req.PathValue("number"))
+	if r == nil || err != nil || n < 1 || n > len(r.issues) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, nil
+	}
+	return r, r.issues[n-1]
+}
+
+// paginate writes a Link header for the page of n items requested
+// by req, and returns the bounds of that page.
+func paginate(w http.ResponseWriter, req *http.Request, n int) (lo, hi int) {
+	perPage, err := strconv.Atoi(req.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 30
+	} else if perPage > 100 {
+		perPage = 100
+	}
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	lo = (page - 1) * perPage
+	if lo > n {
+		lo = n
+	}
+	hi = lo + perPage
+	if hi > n {
+		hi = n
+	}
+
+	last := (n + perPage - 1) / perPage
+	link := func(page int, rel string) string {
+		u := *req.URL
+		u.Scheme, u.Host = "http", req.Host
+		q := u.Query()
+		q.Set("page", strconv.Itoa(page))
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+	}
+	var links []string
+	if page < last {
+		links = append(links, link(page+1, "next"), link(last, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+	if links != nil {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	return lo, hi
+}
+
+// listIssues supports the state and since parameters,
+// and returns issues most recently updated first.
+func (s *Server) listIssues(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[req.PathValue("owner")+"/"+req.PathValue("repo")]
+	if r == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	state := req.URL.Query().Get("state")
+	if state == "" {
+		state = "open"
+	}
+	var since time.Time
+	if s := req.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid since")
+			return
+		}
+		since = t
+	}
+	var issues []*github.Issue
+	for _, issue := range r.issues {
+		if (state == "all" || issue.State == state) && !issue.UpdatedAt.Before(since) {
+			issues = append(issues, issue)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].UpdatedAt.After(issues[j].UpdatedAt) })
+	lo, hi := paginate(w, req, len(issues))
+	writeJSON(w, http.StatusOK, issues[lo:hi])
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, req *http.Request) {
+	var body github.IssueRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Title == nil || *body.Title == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+	issue := github.Issue{Title: *body.Title, State: "open"}
+	if body.Body != nil {
+		issue.Body = *body.Body
+	}
+	s.mu.Lock()
+	apply(&issue, &body)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, s.AddIssue(req.PathValue("owner"), req.PathValue("repo"), issue))
+}
+
+func (s *Server) getIssue(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, issue := s.lookup(w, req); issue != nil {
+		writeJSON(w, http.StatusOK, issue)
+	}
+}
+
+func (s *Server) updateIssue(w http.ResponseWriter, req *http.Request) {
+	var body github.IssueRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if body.State != "" && body.State != "open" && body.State != "closed" ||
+		body.Title != nil && *body.Title == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, issue := s.lookup(w, req)
+	if issue == nil {
+		return
+	}
+	if body.Title != nil {
+		issue.Title = *body.Title
+	}
+	if body.Body != nil {
+		issue.Body = *body.Body
+	}
+	if body.State != "" && body.State != issue.State {
+		issue.State = body.State
+		if issue.State == "closed" {
+			t := s.now()
+			issue.ClosedAt = &t
+		} else {
+			issue.ClosedAt = nil
+		}
+	}
+	apply(issue, &body)
+	issue.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// apply sets the labels and assignees of issue from req.
+func apply(issue *github.Issue, req *github.IssueRequest) {
+	if req.Labels != nil {
+		issue.Labels = nil
+		for _, name := range *req.Labels {
+			issue.Labels = append(issue.Labels, &github.Label{Name: name})
+		}
+	}
+	if req.Assignees != nil {
+		issue.Assignees = nil
+		for _, login := range *req.Assignees {
+			issue.Assignees = append(issue.Assignees, &github.User{Login: login})
+		}
+	}
+}
+
+func (s *Server) listComments(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, issue := s.lookup(w, req)
+	if issue == nil {
+		return
+	}
+	comments := r.comments[issue.Number]
+	lo, hi := paginate(w, req, len(comments))
+	writeJSON(w, http.StatusOK, append([]*github.Comment{}, comments[lo:hi]...))
+}
+
+func (s *Server) createComment(w http.ResponseWriter, req *http.Request) {
+	var body struct{ Body string }
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Body == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, issue := s.lookup(w, req)
+	if issue == nil {
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.addComment(r, issue.Number, "gopher", body.Body))
+}
+
+// search supports queries of words that must all appear in the
+// title or body, and the qualifiers repo:owner/name and is:open
+// or is:closed.
+func (s *Server) search(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var words []string
+	repoName, state := "", ""
+	for _, term := range strings.Fields(req.URL.Query().Get("q")) {
+		switch {
+		case strings.HasPrefix(term, "repo:"):
+			repoName = strings.TrimPrefix(term, "repo:")
+		case term == "is:open" || term == "is:closed":
+			state = strings.TrimPrefix(term, "is:")
+		default:
+			words = append(words, strings.ToLower(term))
+		}
+	}
+	var names []string
+	for name := range s.repos {
+		if repoName == "" || name == repoName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := github.IssuesSearchResult{Items: []*github.Issue{}}
+	for _, name := range names {
+	issues:
+		for _, issue := range s.repos[name].issues {
+			if state != "" && issue.State != state {
+				continue
+			}
+			text := strings.ToLower(issue.Title + " " + issue.Body)
+			for _, word := range words {
+				if !strings.Contains(text, word) {
+					continue issues
+				}
+			}
+			result.Items = append(result.Items, issue)
+		}
+	}
+	result.TotalCount = len(result.Items)
+	lo, hi := paginate(w, req, len(result.Items))
+	result.Items = result.Items[lo:hi]
+	writeJSON(w, http.StatusOK, result)
+}
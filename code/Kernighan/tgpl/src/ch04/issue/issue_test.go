@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopl.io/ch4/github"
+	"gopl.io/ch4/github/githubtest"
+)
+
+func TestSplit(t *testing.T) {
+	for _, test := range []struct {
+		text, title, body string
+	}{
+		{"Title\n\nBody\n", "Title", "Body"},
+		{"\n\n  Title  \nline 1\n\nline 2\n\n", "Title", "line 1\n\nline 2"},
+		{"Title only", "Title only", ""},
+		{"", "", ""},
+	} {
+		title, body := split(test.text)
+		if title != test.title || body != test.body {
+			t.Errorf("split(%q) = %q, %q, want %q, %q", test.text, title, body, test.title, test.body)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.RequireToken("secret")
+	c := &github.Client{BaseURL: srv.URL, Token: "secret"}
+
+	var edited []string // the texts given to the editor
+	replies := []string{
+		"Crash on start\n\nIt crashes.\n",
+		"Crash on start\n\nIt crashes at once.\n",
+		"\n\n",
+		"Crash on start\n\n",
+	}
+	saved := edit
+	defer func() { edit = saved }()
+	edit = func(text string) (string, error) {
+		edited = append(edited, text)
+		reply := replies[0]
+		replies = replies[1:]
+		return reply, nil
+	}
+
+	do := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(c, "gopher", "demo", args, &out)
+		return out.String(), err
+	}
+	mustDo := func(args ...string) string {
+		t.Helper()
+		out, err := do(args...)
+		if err != nil {
+			t.Fatalf("%s: %v", strings.Join(args, " "), err)
+		}
+		return out
+	}
+
+	if out := mustDo("create"); !strings.HasPrefix(out, "created #1 ") {
+		t.Errorf("create: %q", out)
+	}
+	mustDo("create", "-title", "Second", "-body", "No editor.")
+	if len(edited) != 1 || edited[0] != "\n\n\n" {
+		t.Errorf("editor texts = %q, want one empty one", edited)
+	}
+
+	if out := mustDo("edit", "#1"); !strings.HasPrefix(out, "updated #1 ") {
+		t.Errorf("edit: %q", out)
+	}
+	if want := "Crash on start\n\nIt crashes.\n"; edited[1] != want {
+		t.Errorf("edit gave editor %q, want %q", edited[1], want)
+	}
+	if _, err := do("edit", "1"); err == nil || !strings.Contains(err.Error(), "empty title") {
+		t.Errorf("edit to empty title: err = %v", err)
+	}
+
+	srv.AddComment("gopher", "demo", 1, "rob", "Me too.")
+	out := mustDo("get", "1")
+	for _, want := range []string{"#1 Crash on start\n", "open by gopher", "1 comments\n", "It crashes at once.\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("get output lacks %q:\n%s", want, out)
+		}
+	}
+	if out := mustDo("comments", "1"); !strings.Contains(out, "--- rob, ") || !strings.Contains(out, "Me too.") {
+		t.Errorf("comments: %q", out)
+	}
+	srv.AddComment("gopher", "demo", 1, "", "Deleted account.")
+	if out := mustDo("comments", "1"); !strings.Contains(out, "--- ghost, ") {
+		t.Errorf("comments by deleted user: %q", out)
+	}
+	var buf bytes.Buffer
+	printIssue(&buf, &github.Issue{Number: 3, State: "open"})
+	if !strings.Contains(buf.String(), "open by ghost") {
+		t.Errorf("printIssue with no user: %q", buf.String())
+	}
+
+	// Clearing the body stores an empty one, not a blank.
+	mustDo("edit", "1")
+	if issue, err := c.GetIssue("gopher", "demo", 1); err != nil || issue.Body != "" {
+		t.Errorf("after clearing body: %+v, %v", issue, err)
+	}
+
+	mustDo("close", "2")
+	if out := mustDo("get", "2"); !strings.Contains(out, "closed by gopher") {
+		t.Errorf("get after close: %q", out)
+	}
+
+	for _, args := range [][]string{{"get"}, {"get", "x"}, {"get", "9"}, {"frob", "1"}} {
+		if _, err := do(args...); err == nil {
+			t.Errorf("%s: no error", strings.Join(args, " "))
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Issue creates, reads, updates and closes GitHub issues
+// from the command line (exercise 4.11).
+//
+// Usage:
+//
+//	issue [-repo owner/repo] create [-title title] [-body body]
+//	issue [-repo owner/repo] get number
+//	issue [-repo owner/repo] edit number
+//	issue [-repo owner/repo] close number
+//	issue [-repo owner/repo] comments number
+//
+// Create and edit open $EDITOR on the title and body: the first line
+// is the title, and the body follows a blank line. Create skips the
+// editor if both -title and -body are given. Requests are authenticated
+// with the token in $GITHUB_TOKEN.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"gopl.io/ch4/github"
+)
+
+var (
+	repoFlag = flag.String("repo", os.Getenv("GITHUB_REPO"), "the `owner/repo` whose issues to use; default $GITHUB_REPO")
+	apiURL   = flag.String("api", github.APIURL, "the base `URL` of the API")
+)
+
+const usage = `usage: issue [-repo owner/repo] [-api url] command [args]
+commands:
+	create [-title title] [-body body]
+	get number
+	edit number
+	close number
+	comments number
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	owner, repo, ok := strings.Cut(*repoFlag, "/")
+	if !ok || owner == "" || repo == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	c := github.NewClient(os.Getenv("GITHUB_TOKEN"))
+	c.BaseURL = *apiURL
+	if err := run(c, owner, repo, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "issue: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run carries out the command in args on the issues of owner/repo.
+func run(c *github.Client, owner, repo string, args []string, w io.Writer) error {
+	cmd, args := args[0], args[1:]
+	if cmd == "create" {
+		return create(c, owner, repo, args, w)
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("usage: issue %s number", cmd)
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return fmt.Errorf("bad issue number %q", args[0])
+	}
+	switch cmd {
+	case "get":
+		issue, err := c.GetIssue(owner, repo, number)
+		if err != nil {
+			return err
+		}
+		printIssue(w, issue)
+	case "edit":
+		issue, err := c.GetIssue(owner, repo, number)
+		if err != nil {
+			return err
+		}
+		title, body, err := editIssue(issue.Title, issue.Body)
+		if err != nil {
+			return err
+		}
+		if title == issue.Title && body == issue.Body {
+			fmt.Fprintln(w, "no changes")
+			return nil
+		}
+		var req github.IssueRequest
+		if title != issue.Title {
+			req.Title = &title
+		}
+		if body != issue.Body {
+			req.Body = &body
+		}
+		issue, err = c.UpdateIssue(owner, repo, number, &req)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "updated #%d %s\n", issue.Number, issue.HTMLURL)
+	case "close":
+		issue, err := c.CloseIssue(owner, repo, number)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "closed #%d %s\n", issue.Number, issue.HTMLURL)
+	case "comments":
+		comments, err := c.ListComments(owner, repo, number)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			fmt.Fprintf(w, "--- %s, %s\n%s\n", login(comment.User),
+				comment.CreatedAt.Format("2006-01-02 15:04"), comment.Body)
+		}
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+func create(c *github.Client, owner, repo string, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	title := fs.String("title", "", "the issue's title")
+	body := fs.String("body", "", "the issue's body")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *title == "" || *body == "" {
+		var err error
+		if *title, *body, err = editIssue(*title, *body); err != nil {
+			return err
+		}
+	}
+	issue, err := c.CreateIssue(owner, repo, &github.IssueRequest{Title: title, Body: body})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "created #%d %s\n", issue.Number, issue.HTMLURL)
+	return nil
+}
+
+func printIssue(w io.Writer, issue *github.Issue) {
+	fmt.Fprintf(w, "#%d %s\n", issue.Number, issue.Title)
+	fmt.Fprintf(w, "%s by %s, %s\n", issue.State, login(issue.User),
+		issue.CreatedAt.Format("2006-01-02"))
+	if len(issue.Labels) > 0 {
+		var names []string
+		for _, label := range issue.Labels {
+			names = append(names, label.Name)
+		}
+		fmt.Fprintf(w, "labels: %s\n", strings.Join(names, ", "))
+	}
+	if issue.Comments > 0 {
+		fmt.Fprintf(w, "%d comments\n", issue.Comments)
+	}
+	fmt.Fprintf(w, "%s\n\n%s\n", issue.HTMLURL, issue.Body)
+}
+
+// login returns the login name of u, or "ghost",
+// as GitHub shows a user whose account has been deleted.
+func login(u *github.User) string {
+	if u == nil {
+		return "ghost"
+	}
+	return u.Login
+}
+
+// editIssue lets the user edit a title and body,
+// and returns the new ones.
+func editIssue(title, body string) (string, string, error) {
+	text, err := edit(title + "\n\n" + body + "\n")
+	if err != nil {
+		return "", "", err
+	}
+	title, body = split(text)
+	if title == "" {
+		return "", "", errors.New("empty title; abandoned")
+	}
+	return title, body, nil
+}
+
+// split splits edited text into a title, the first line,
+// and a body, the rest.
+func split(text string) (title, body string) {
+	title, body, _ = strings.Cut(strings.TrimLeft(text, "\n"), "\n")
+	return strings.TrimSpace(title), strings.TrimSpace(body)
+}
+
+// edit opens the user's editor on text, and returns the result.
+// It is a variable so that tests can replace it.
+var edit = func(text string) (string, error) {
+	f, err := os.CreateTemp("", "issue*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.WriteString(f, text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// $EDITOR may include arguments, as in "code --wait".
+	words := strings.Fields(editor)
+	cmd := exec.Command(words[0], append(words[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s: %v", editor, err)
+	}
+	data, err := os.ReadFile(f.Name())
+	return string(data), err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopl.io/ch4/github"
+)
+
+// A Cache is a local copy of the issues of one repository.
+type Cache struct {
+	Repo   string                // "owner/repo"
+	Synced time.Time             // latest update time seen, or zero
+	Issues map[int]*github.Issue // by number
+}
+
+// load reads the cache stored in the named file.
+// A missing file is an empty cache.
+func load(filename string) (*Cache, error) {
+	cache := &Cache{Issues: make(map[int]*github.Issue)}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", filename, err)
+	}
+	if cache.Issues == nil {
+		cache.Issues = make(map[int]*github.Issue)
+	}
+	return cache, nil
+}
+
+// save writes the cache to the named file. It writes a temporary
+// file first and renames it, so that the file is never left half
+// written.
+func (cache *Cache) save(filename string) error {
+	data, err := json.MarshalIndent(cache, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// sync fetches the issues of owner/repo that have been updated since
+// the cache was last synced, and returns how many there were.
+//
+// The next sync starts from the latest update time seen rather than
+// the local clock, so that clock skew cannot cause updates to be
+// missed. The issues updated at that instant are fetched again.
+func (cache *Cache) sync(c *github.Client, owner, repo string) (int, error) {
+	if name := owner + "/" + repo; cache.Repo != name {
+		if cache.Repo != "" {
+			return 0, fmt.Errorf("cache holds %s, not %s", cache.Repo, name)
+		}
+		cache.Repo = name
+	}
+	issues, err := c.ListIssues(owner, repo, cache.Synced)
+	if err != nil {
+		return 0, err
+	}
+	for _, issue := range issues {
+		cache.Issues[issue.Number] = issue
+		if issue.UpdatedAt.After(cache.Synced) {
+			cache.Synced = issue.UpdatedAt
+		}
+	}
+	return len(issues), nil
+}
+
+// list returns the cached issues in the given state
+// ("open", "closed" or "all"), by number.
+func (cache *Cache) list(state string) []*github.Issue {
+	var issues []*github.Issue
+	for _, issue := range cache.Issues {
+		if state == "all" || issue.State == state {
+			issues = append(issues, issue)
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	return issues
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopl.io/ch4/github"
+	"gopl.io/ch4/github/githubtest"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func day(n int) time.Time { return now.AddDate(0, 0, -n) }
+
+func TestSync(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	var clock time.Time
+	srv.SetClock(func() time.Time { return clock })
+	c := &github.Client{BaseURL: srv.URL}
+	for i := 0; i < 150; i++ {
+		srv.AddIssue("gopher", "demo", github.Issue{Title: "old", CreatedAt: day(550 - i)})
+	}
+	// Pull requests are listed as issues, but not cached.
+	srv.AddPullRequest("gopher", "demo", github.Issue{Title: "fix", CreatedAt: day(400)})
+	filename := filepath.Join(t.TempDir(), "issues.json")
+
+	var out bytes.Buffer
+	if err := run(c, filename, "gopher/demo", []string{"sync"}, &out, now); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "150 issues updated; 150 cached\n"; got != want {
+		t.Errorf("first sync: %q, want %q", got, want)
+	}
+
+	// Change one issue and add another, a day later. The
+	// latest issue of the first sync is fetched again too.
+	clock = day(399)
+	if _, err := c.CloseIssue("gopher", "demo", 7); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddIssue("gopher", "demo", github.Issue{Title: "new"})
+
+	out.Reset()
+	before := srv.Requests()
+	if err := run(c, filename, "", []string{"sync"}, &out, now); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "3 issues updated; 151 cached\n"; got != want {
+		t.Errorf("second sync: %q, want %q", got, want)
+	}
+	if n := srv.Requests() - before; n != 1 {
+		t.Errorf("second sync made %d requests, want 1", n)
+	}
+
+	cache, err := load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Repo != "gopher/demo" || !cache.Synced.Equal(day(399)) {
+		t.Errorf("cache of %q synced at %v, want gopher/demo at %v", cache.Repo, cache.Synced, day(399))
+	}
+	if issue, ok := cache.Issues[151]; ok {
+		t.Errorf("pull request %q was cached", issue.Title)
+	}
+	if got := cache.Issues[7].State; got != "closed" {
+		t.Errorf("issue 7 is %s, want closed", got)
+	}
+	if got := len(cache.list("open")); got != 150 {
+		t.Errorf("%d open issues, want 150", got)
+	}
+
+	if err := run(c, filename, "other/repo", []string{"sync"}, &out, now); err == nil {
+		t.Errorf("sync of another repository: no error")
+	}
+}
+
+func testIssues() []*github.Issue {
+	alice, bob := &github.User{Login: "alice"}, &github.User{Login: "bob"}
+	bug, doc := &github.Label{Name: "bug"}, &github.Label{Name: "doc"}
+	v1 := &github.Milestone{Title: "v1"}
+	return []*github.Issue{
+		{Number: 1, Title: "ancient", State: "open", User: alice, CreatedAt: day(500), Labels: []*github.Label{bug}},
+		{Number: 2, Title: "recent", State: "open", User: bob, CreatedAt: day(3), Labels: []*github.Label{bug, doc}, Assignees: []*github.User{alice}, Milestone: v1},
+		{Number: 3, Title: "this year", State: "open", User: bob, CreatedAt: day(100), Assignees: []*github.User{alice, bob}},
+		{Number: 4, Title: "month old", State: "open", User: alice, CreatedAt: day(30), Milestone: v1},
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	for _, test := range []struct {
+		key  string
+		want string
+	}{
+		{"age", "less than a month old: 2; less than a year old: 3 4; more than a year old: 1"},
+		{"label", "bug: 1 2; doc: 2; (none): 3 4"},
+		{"assignee", "alice: 2 3; bob: 3; (none): 1 4"},
+		{"milestone", "v1: 2 4; (none): 1 3"},
+	} {
+		groups, err := groupBy(test.key, testIssues(), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parts []string
+		for _, g := range groups {
+			s := g.Name + ":"
+			for _, issue := range g.Issues {
+				s += " " + strconv.Itoa(issue.Number)
+			}
+			parts = append(parts, s)
+		}
+		if got := strings.Join(parts, "; "); got != test.want {
+			t.Errorf("groupBy(%q) = %s, want %s", test.key, got, test.want)
+		}
+	}
+	if _, err := groupBy("color", nil, now); err == nil {
+		t.Errorf("groupBy(color): no error")
+	}
+}
+
+func TestFormats(t *testing.T) {
+	cache := &Cache{Issues: make(map[int]*github.Issue)}
+	for _, issue := range testIssues() {
+		cache.Issues[issue.Number] = issue
+	}
+	cache.Issues[5] = &github.Issue{Number: 5, Title: "<closed>", State: "closed", CreatedAt: day(1)}
+
+	var buf bytes.Buffer
+	if err := report(cache, []string{"-by", "milestone"}, &buf, now); err != nil {
+		t.Fatal(err)
+	}
+	want := `v1: 2 issues
+#2       open       bob    3d recent
+#4       open     alice   30d month old
+
+(none): 2 issues
+#1       open     alice  500d ancient
+#3       open       bob  100d this year
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("text report:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := report(cache, []string{"-format", "csv", "-state", "all"}, &buf, now); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 || records[0][0] != "group" {
+		t.Fatalf("csv report has %d records, want header and 5", len(records))
+	}
+	if got := strings.Join(records[1][:5], ","); got != "less than a month old,2,open,bob,recent" {
+		t.Errorf("csv record 1 = %s", got)
+	}
+
+	buf.Reset()
+	if err := report(cache, []string{"-format", "html", "-state", "closed"}, &buf, now); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, want := range []string{"<td>1</td>", "&lt;closed&gt;", "data-numeric", "addEventListener"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html report lacks %q", want)
+		}
+	}
+	if strings.Contains(html, "ancient") {
+		t.Errorf("html report of closed issues includes an open one")
+	}
+
+	for _, args := range [][]string{{"-format", "pdf"}, {"-state", "merged"}, {"-by", "color"}} {
+		if err := report(cache, args, &buf, now); err == nil {
+			t.Errorf("report %s: no error", strings.Join(args, " "))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Issuecache keeps a local copy of a repository's GitHub issues
+// and reports on them offline.
+//
+// Usage:
+//
+//	issuecache [-db file] -repo owner/repo sync
+//	issuecache [-db file] report [-by age|label|assignee|milestone]
+//		[-state open|closed|all] [-format text|html|csv]
+//
+// Sync fetches only the issues updated since the previous sync, using
+// the token in $GITHUB_TOKEN, if any. Report groups the cached issues
+// by age (less than a month, less than a year, or older), label,
+// assignee or milestone, and prints them as text, as an HTML table
+// that can be sorted by clicking on its headings, or as CSV.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopl.io/ch4/github"
+)
+
+var (
+	db       = flag.String("db", "issues.json", "the `file` holding the cache")
+	repoFlag = flag.String("repo", os.Getenv("GITHUB_REPO"), "the `owner/repo` to sync; default $GITHUB_REPO")
+	apiURL   = flag.String("api", github.APIURL, "the base `URL` of the API")
+)
+
+const usage = `usage: issuecache [-db file] [-repo owner/repo] [-api url] command [args]
+commands:
+	sync
+	report [-by age|label|assignee|milestone] [-state open|closed|all] [-format text|html|csv]
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	c := github.NewClient(os.Getenv("GITHUB_TOKEN"))
+	c.BaseURL = *apiURL
+	if err := run(c, *db, *repoFlag, flag.Args(), os.Stdout, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "issuecache: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run carries out the command in args on the cache in the named file.
+func run(c *github.Client, filename, repo string, args []string, w io.Writer, now time.Time) error {
+	cache, err := load(filename)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "sync":
+		if repo == "" {
+			repo = cache.Repo
+		}
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok || owner == "" || name == "" {
+			return fmt.Errorf("sync: need -repo owner/repo")
+		}
+		n, err := cache.sync(c, owner, name)
+		if err != nil {
+			return err
+		}
+		if err := cache.save(filename); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d issues updated; %d cached\n", n, len(cache.Issues))
+		return nil
+	case "report":
+		return report(cache, args[1:], w, now)
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+var formats = map[string]func(io.Writer, []group, time.Time) error{
+	"text": writeText,
+	"html": writeHTML,
+	"csv":  writeCSV,
+}
+
+func report(cache *Cache, args []string, w io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	by := fs.String("by", "age", "group by `key`: age, label, assignee or milestone")
+	state := fs.String("state", "open", "report issues in this `state`: open, closed or all")
+	format := fs.String("format", "text", "output `format`: text, html or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	write := formats[*format]
+	if write == nil {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *state != "open" && *state != "closed" && *state != "all" {
+		return fmt.Errorf("unknown state %q", *state)
+	}
+	groups, err := groupBy(*by, cache.list(*state), now)
+	if err != nil {
+		return err
+	}
+	return write(w, groups, now)
+}
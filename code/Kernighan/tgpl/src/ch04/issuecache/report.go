@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopl.io/ch4/github"
+)
+
+// A group is a set of issues with something in common.
+type group struct {
+	Name   string
+	Issues []*github.Issue
+}
+
+const none = "(none)"
+
+// groupBy groups issues by age ("age"), label, assignee or milestone.
+// An issue with several labels or assignees is in several groups.
+// Age groups are in order of age, and the others in order of name,
+// with issues that have no label, assignee or milestone last.
+func groupBy(key string, issues []*github.Issue, now time.Time) ([]group, error) {
+	var keys func(*github.Issue) []string
+	switch key {
+	case "age":
+		var groups []group
+		for _, b := range buckets {
+			groups = append(groups, group{Name: b.name})
+		}
+		for _, issue := range issues {
+			i := bucket(issue.CreatedAt, now)
+			groups[i].Issues = append(groups[i].Issues, issue)
+		}
+		return groups, nil
+	case "label":
+		keys = func(issue *github.Issue) (names []string) {
+			for _, label := range issue.Labels {
+				names = append(names, label.Name)
+			}
+			return names
+		}
+	case "assignee":
+		keys = func(issue *github.Issue) (logins []string) {
+			for _, user := range issue.Assignees {
+				logins = append(logins, user.Login)
+			}
+			return logins
+		}
+	case "milestone":
+		keys = func(issue *github.Issue) []string {
+			if issue.Milestone == nil {
+				return nil
+			}
+			return []string{issue.Milestone.Title}
+		}
+	default:
+		return nil, fmt.Errorf("cannot group by %q", key)
+	}
+
+	byName := make(map[string][]*github.Issue)
+	for _, issue := range issues {
+		names := keys(issue)
+		if len(names) == 0 {
+			names = []string{none}
+		}
+		for _, name := range names {
+			byName[name] = append(byName[name], issue)
+		}
+	}
+	var groups []group
+	for name, issues := range byName {
+		groups = append(groups, group{name, issues})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		x, y := groups[i].Name, groups[j].Name
+		if (x == none) != (y == none) {
+			return y == none
+		}
+		return x < y
+	})
+	return groups, nil
+}
+
+var buckets = []struct {
+	name string
+	max  time.Duration // maximum age
+}{
+	{"less than a month old", 30 * 24 * time.Hour},
+	{"less than a year old", 365 * 24 * time.Hour},
+	{"more than a year old", 1<<63 - 1},
+}
+
+// bucket returns the index of the age bucket of an issue created at t.
+func bucket(t, now time.Time) int {
+	age := now.Sub(t)
+	for i, b := range buckets {
+		if age < b.max {
+			return i
+		}
+	}
+	return len(buckets) - 1
+}
+
+func daysAgo(t, now time.Time) int {
+	return int(now.Sub(t).Hours() / 24)
+}
+
+// writeText prints each group as a heading and a table
+// in the style of gopl.io/ch4/issues.
+func writeText(w io.Writer, groups []group, now time.Time) error {
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s: %d issues\n", g.Name, len(g.Issues))
+		for _, issue := range g.Issues {
+			fmt.Fprintf(w, "#%-5d %6s %9.9s %4dd %.55s\n", issue.Number,
+				issue.State, login(issue.User), daysAgo(issue.CreatedAt, now), issue.Title)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV prints a row for each issue in each group.
+func writeCSV(w io.Writer, groups []group, now time.Time) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"group", "number", "state", "user", "title", "created", "updated", "age_days", "url"})
+	for _, g := range groups {
+		for _, issue := range g.Issues {
+			cw.Write([]string{
+				g.Name,
+				strconv.Itoa(issue.Number),
+				issue.State,
+				login(issue.User),
+				issue.Title,
+				issue.CreatedAt.Format(time.RFC3339),
+				issue.UpdatedAt.Format(time.RFC3339),
+				strconv.Itoa(daysAgo(issue.CreatedAt, now)),
+				issue.HTMLURL,
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeHTML prints a table of the groups, whose rows can be sorted
+// by clicking on the column headings.
+func writeHTML(w io.Writer, groups []group, now time.Time) error {
+	return issueTable.Execute(w, struct {
+		Groups []group
+		Now    time.Time
+	}{groups, now})
+}
+
+func login(u *github.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.Login
+}
+
+var issueTable = template.Must(template.New("issuetable").Funcs(template.FuncMap{
+	"daysAgo": daysAgo,
+	"login":   login,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Issues</title>
+<style>
+th { text-align: left; cursor: pointer; }
+td, th { padding: 0 0.5em; }
+</style>
+</head>
+<body>
+<table id="issues">
+<thead>
+<tr>
+  <th>Group</th>
+  <th data-numeric>#</th>
+  <th>State</th>
+  <th>User</th>
+  <th data-numeric>Age (days)</th>
+  <th>Title</th>
+</tr>
+</thead>
+<tbody>
+{{range $g := .Groups}}{{range .Issues}}
+<tr>
+  <td>{{$g.Name}}</td>
+  <td><a href='{{.HTMLURL}}'>{{.Number}}</a></td>
+  <td>{{.State}}</td>
+  <td>{{login .User}}</td>
+  <td>{{daysAgo .CreatedAt $.Now}}</td>
+  <td><a href='{{.HTMLURL}}'>{{.Title}}</a></td>
+</tr>
+{{end}}{{end}}
+</tbody>
+</table>
+<script>
+// Clicking a heading sorts the rows by that column,
+// or reverses them if they are already sorted by it.
+document.querySelectorAll("#issues th").forEach(function(th, col) {
+	th.addEventListener("click", function() {
+		var tbody = document.querySelector("#issues tbody");
+		var rows = Array.from(tbody.rows);
+		var numeric = th.hasAttribute("data-numeric");
+		var dir = th.dataset.dir === "asc" ? -1 : 1;
+		th.parentNode.querySelectorAll("th").forEach(function(h) { delete h.dataset.dir; });
+		th.dataset.dir = dir > 0 ? "asc" : "desc";
+		rows.sort(function(a, b) {
+			var x = a.cells[col].textContent, y = b.cells[col].textContent;
+			if (numeric) {
+				return dir * (Number(x) - Number(y));
+			}
+			return dir * x.localeCompare(y);
+		});
+		rows.forEach(function(row) { tbody.appendChild(row); });
+	});
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,192 @@
+// Textstats reports statistics of Unicode text, generalizing
+// charcount and dup.
+//
+// Usage:
+//
+//	textstats [-format table|json|csv] [-top 10] [-ngram 2]
+//		[-stop english|none|file] [file ...]
+//
+// It reads the named files, or the standard input, and reports the
+// numbers of bytes, runes, lines and words; the counts of runes by
+// category (letter, digit, space, punctuation and so on), by Unicode
+// script, and by length of UTF-8 encoding; the most frequent words,
+// ignoring the stop words; the most frequent sequences of -ngram
+// words; the non-blank lines that occur more than once, in any of the
+// files, and where; and the position of each invalid UTF-8 byte.
+// Words are compared without regard to case.
+//
+// The stop words are a built-in English list, none, or those in the
+// named file, separated by white space.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "textstats: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+var formats = map[string]func(io.Writer, *Report) error{
+	"table": writeTable,
+	"json":  writeJSON,
+	"csv":   writeCSV,
+}
+
+// run reports on the files named in args, or on stdin.
+func run(args []string, stdin io.Reader, w io.Writer) error {
+	fs := flag.NewFlagSet("textstats", flag.ContinueOnError)
+	format := fs.String("format", "table", "output `format`: table, json or csv")
+	top := fs.Int("top", 10, "list at most `n` words, n-grams, duplicates and invalid bytes; 0 => all")
+	ngram := fs.Int("ngram", 2, "count sequences of `n` words; 0 => none")
+	stopFlag := fs.String("stop", "english", "stop words: english, none, or a `file` of words")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	write := formats[*format]
+	if write == nil {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *ngram < 0 {
+		return fmt.Errorf("invalid -ngram %d", *ngram)
+	}
+	stop, err := stopWords(*stopFlag)
+	if err != nil {
+		return err
+	}
+
+	c := newCounter(stop, *ngram, *top)
+	if fs.NArg() == 0 {
+		if err := c.add("<stdin>", stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = c.add(name, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return write(w, c.result())
+}
+
+// english is a list of common English words that say little
+// about the subject of a text.
+const english = `a about after all also an and any are as at be because been but by
+can could did do does for from had has have he her him his how i if in into is
+it its just me more most my no not now of on one only or other our out over she
+so some than that the their them then there these they this to up us very was
+we were what when which who will with would you your`
+
+// stopWords returns the set of stop words specified by the -stop flag.
+func stopWords(name string) (map[string]bool, error) {
+	var text string
+	switch name {
+	case "none":
+	case "english":
+		text = english
+	default:
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	stop := make(map[string]bool)
+	for _, w := range strings.Fields(text) {
+		stop[strings.ToLower(w)] = true
+	}
+	return stop, nil
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func writeTable(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "bytes\t%d\nrunes\t%d\nlines\t%d\nwords\t%d\ninvalid\t%d\n",
+		r.Bytes, r.Runes, r.Lines, r.Words, r.Invalid)
+	section := func(title string, counts []Count) {
+		if len(counts) == 0 {
+			return
+		}
+		fmt.Fprintf(tw, "\n%s\tcount\n", title)
+		for _, c := range counts {
+			fmt.Fprintf(tw, "%s\t%d\n", c.Key, c.Count)
+		}
+	}
+	var lens []Count
+	for i, n := range r.UTF8Len {
+		lens = append(lens, Count{strconv.Itoa(i + 1), n})
+	}
+	section("category", r.Categories)
+	section("script", r.Scripts)
+	section("utf8 len", lens)
+	section("word", r.TopWords)
+	section("n-gram", r.NGrams)
+	if len(r.Duplicates) > 0 {
+		fmt.Fprintf(tw, "\nduplicate line\tcount\twhere\n")
+		for _, d := range r.Duplicates {
+			fmt.Fprintf(tw, "%q\t%d\t%s\n", d.Line, d.Count, strings.Join(d.Where, " "))
+		}
+	}
+	if len(r.InvalidAt) > 0 {
+		fmt.Fprintf(tw, "\ninvalid byte\tline\toffset\n")
+		for _, p := range r.InvalidAt {
+			fmt.Fprintf(tw, "%s: %#02x\t%d\t%d\n", p.File, p.Byte, p.Line, p.Offset)
+		}
+	}
+	return tw.Flush()
+}
+
+// writeCSV writes the report as records of the form
+// section,key,count,where.
+func writeCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"section", "key", "count", "where"})
+	totals := []Count{
+		{"bytes", int(r.Bytes)}, {"runes", r.Runes}, {"lines", r.Lines},
+		{"words", r.Words}, {"invalid", r.Invalid},
+	}
+	section := func(name string, counts []Count) {
+		for _, c := range counts {
+			cw.Write([]string{name, c.Key, strconv.Itoa(c.Count), ""})
+		}
+	}
+	section("total", totals)
+	section("category", r.Categories)
+	section("script", r.Scripts)
+	for i, n := range r.UTF8Len {
+		cw.Write([]string{"utf8len", strconv.Itoa(i + 1), strconv.Itoa(n), ""})
+	}
+	section("word", r.TopWords)
+	section("ngram", r.NGrams)
+	for _, d := range r.Duplicates {
+		cw.Write([]string{"duplicate", d.Line, strconv.Itoa(d.Count), strings.Join(d.Where, " ")})
+	}
+	for _, p := range r.InvalidAt {
+		where := fmt.Sprintf("%s:%d@%d", p.File, p.Line, p.Offset)
+		cw.Write([]string{"invalid", fmt.Sprintf("%#02x", p.Byte), "1", where})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Count is the number of occurrences of a key.
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// A Duplicate is a line that occurs more than once.
+type Duplicate struct {
+	Line  string   `json:"line"`
+	Count int      `json:"count"`
+	Where []string `json:"where"` // file:line of each occurrence
+}
+
+// A Position locates an invalid byte.
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"` // in bytes from the start of the file
+	Byte   byte   `json:"byte"`
+}
+
+// A Report holds the statistics of a set of files.
+type Report struct {
+	Files      []string         `json:"files"`
+	Bytes      int64            `json:"bytes"`
+	Runes      int              `json:"runes"`
+	Lines      int              `json:"lines"`
+	Words      int              `json:"words"`
+	UTF8Len    [utf8.UTFMax]int `json:"utf8len"` // UTF8Len[i] runes are encoded in i+1 bytes
+	Categories []Count          `json:"categories"`
+	Scripts    []Count          `json:"scripts"`
+	TopWords   []Count          `json:"topWords"`
+	NGrams     []Count          `json:"ngrams"`
+	Duplicates []Duplicate      `json:"duplicates"`
+	Invalid    int              `json:"invalid"`
+	InvalidAt  []Position       `json:"invalidAt"`
+}
+
+// categories are the categories of runes, in the order reported.
+var categories = []string{"letter", "mark", "digit", "number", "space", "punct", "symbol", "control", "other"}
+
+func category(r rune) string {
+	switch {
+	case unicode.IsLetter(r):
+		return "letter"
+	case unicode.IsMark(r):
+		return "mark"
+	case unicode.IsDigit(r):
+		return "digit"
+	case unicode.IsNumber(r): // e.g., Ⅻ or ½
+		return "number"
+	case unicode.IsSpace(r):
+		return "space"
+	case unicode.IsPunct(r):
+		return "punct"
+	case unicode.IsSymbol(r):
+		return "symbol"
+	case unicode.IsControl(r):
+		return "control"
+	}
+	return "other"
+}
+
+// A counter accumulates the statistics of the files added to it.
+type counter struct {
+	stop  map[string]bool // words not counted in TopWords
+	ngram int             // number of words in an n-gram; 0 => none
+	top   int             // length of lists in the report; 0 => all
+
+	report     Report
+	categories map[string]int
+	scripts    map[string]int
+	scriptOf   map[rune]string // cache of script lookups
+	words      map[string]int
+	ngrams     map[string]int
+	lines      map[string]*seenLine
+}
+
+// A seenLine records where a line first occurred, and any duplicates.
+// Only lines that occur again pay for a Duplicate.
+type seenLine struct {
+	file, line int // index in report.Files and line number
+	dup        *Duplicate
+}
+
+func newCounter(stop map[string]bool, ngram, top int) *counter {
+	return &counter{
+		stop:       stop,
+		ngram:      ngram,
+		top:        top,
+		categories: make(map[string]int),
+		scripts:    make(map[string]int),
+		scriptOf:   make(map[rune]string),
+		words:      make(map[string]int),
+		ngrams:     make(map[string]int),
+		lines:      make(map[string]*seenLine),
+	}
+}
+
+// script returns the name of the Unicode script of r,
+// such as "Latin", "Han" or "Common".
+func (c *counter) script(r rune) string {
+	if s, ok := c.scriptOf[r]; ok {
+		return s
+	}
+	s := "Unknown"
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			s = name
+			break
+		}
+	}
+	c.scriptOf[r] = s
+	return s
+}
+
+// A file is the state of the scan of one file.
+type file struct {
+	name   string
+	index  int // in report.Files
+	line   int
+	offset int64
+	word   []rune
+	recent []string // the last ngram-1 words, for n-grams
+}
+
+// add counts the contents of the file read from r.
+func (c *counter) add(name string, r io.Reader) error {
+	f := &file{name: name, index: len(c.report.Files)}
+	c.report.Files = append(c.report.Files, name)
+	in := bufio.NewReader(r)
+	for {
+		line, err := in.ReadBytes('\n')
+		if len(line) > 0 {
+			f.line++
+			c.scanLine(f, line)
+			f.offset += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	c.endWord(f)
+	return nil
+}
+
+func (c *counter) scanLine(f *file, line []byte) {
+	c.report.Lines++
+	c.report.Bytes += int64(len(line))
+
+	// As in dup, the line excludes its end, including any carriage return.
+	text := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if strings.TrimSpace(text) != "" {
+		if seen := c.lines[text]; seen == nil {
+			c.lines[text] = &seenLine{file: f.index, line: f.line}
+		} else {
+			if seen.dup == nil {
+				first := fmt.Sprintf("%s:%d", c.report.Files[seen.file], seen.line)
+				seen.dup = &Duplicate{Line: text, Count: 1, Where: []string{first}}
+			}
+			seen.dup.Count++
+			seen.dup.Where = append(seen.dup.Where, fmt.Sprintf("%s:%d", f.name, f.line))
+		}
+	}
+
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		if r == utf8.RuneError && size == 1 {
+			c.report.Invalid++
+			if c.top <= 0 || len(c.report.InvalidAt) < c.top {
+				c.report.InvalidAt = append(c.report.InvalidAt, Position{f.name, f.line, f.offset + int64(i), line[i]})
+			}
+			c.endWord(f)
+			i++
+			continue
+		}
+		c.report.Runes++
+		c.report.UTF8Len[size-1]++
+		c.categories[category(r)]++
+		c.scripts[c.script(r)]++
+		if isWordRune(r) || isApostrophe(r) && len(f.word) > 0 && i+size < len(line) && startsWord(line[i+size:]) {
+			f.word = append(f.word, r)
+		} else {
+			c.endWord(f)
+		}
+		i += size
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+// isApostrophe reports whether r may join the parts of a word, as in "don't".
+func isApostrophe(r rune) bool { return r == '\'' || r == '’' }
+
+func startsWord(b []byte) bool {
+	r, _ := utf8.DecodeRune(b)
+	return unicode.IsLetter(r)
+}
+
+// endWord counts the word, if any, that f has scanned.
+func (c *counter) endWord(f *file) {
+	if len(f.word) == 0 {
+		return
+	}
+	w := strings.ToLower(string(f.word))
+	f.word = f.word[:0]
+	c.report.Words++
+	if !c.stop[w] {
+		c.words[w]++
+	}
+	if c.ngram > 0 {
+		f.recent = append(f.recent, w)
+		if len(f.recent) == c.ngram {
+			c.ngrams[strings.Join(f.recent, " ")]++
+			f.recent = f.recent[1:]
+		}
+	}
+}
+
+// result returns the report, with at most c.top words, n-grams,
+// duplicates and invalid positions (all, if c.top is not positive).
+// Only the first c.top invalid positions are recorded at all.
+func (c *counter) result() *Report {
+	r := c.report
+	limit := func(n int) int {
+		if c.top > 0 && n > c.top {
+			return c.top
+		}
+		return n
+	}
+	for _, name := range categories {
+		if n := c.categories[name]; n > 0 {
+			r.Categories = append(r.Categories, Count{name, n})
+		}
+	}
+	r.Scripts = sortCounts(c.scripts)
+	r.TopWords = sortCounts(c.words)
+	r.TopWords = r.TopWords[:limit(len(r.TopWords))]
+	r.NGrams = sortCounts(c.ngrams)
+	r.NGrams = r.NGrams[:limit(len(r.NGrams))]
+
+	for _, seen := range c.lines {
+		if seen.dup != nil {
+			r.Duplicates = append(r.Duplicates, *seen.dup)
+		}
+	}
+	sort.Slice(r.Duplicates, func(i, j int) bool {
+		x, y := r.Duplicates[i], r.Duplicates[j]
+		if x.Count != y.Count {
+			return x.Count > y.Count
+		}
+		return x.Line < y.Line
+	})
+	r.Duplicates = r.Duplicates[:limit(len(r.Duplicates))]
+	return &r
+}
+
+// sortCounts returns the counts in decreasing order,
+// with equal counts in order of key.
+func sortCounts(m map[string]int) []Count {
+	var counts []Count
+	for k, n := range m {
+		counts = append(counts, Count{k, n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return counts
+}
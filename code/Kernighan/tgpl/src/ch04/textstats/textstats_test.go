@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func report(t *testing.T, top int, files map[string]string) *Report {
+	t.Helper()
+	stop, _ := stopWords("english")
+	c := newCounter(stop, 2, top)
+	for _, name := range []string{"a", "b", "c"} {
+		if text, ok := files[name]; ok {
+			if err := c.add(name, strings.NewReader(text)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return c.result()
+}
+
+func TestCounts(t *testing.T) {
+	r := report(t, 0, map[string]string{
+		"a": "Héllo, wörld! 42\n",
+		"b": "Привет мир ½\r\n世界\n",
+	})
+	if r.Lines != 3 || r.Bytes != 19+24+7 || r.Words != 6 {
+		t.Errorf("lines, bytes, words = %d, %d, %d", r.Lines, r.Bytes, r.Words)
+	}
+	if r.Runes != 17+14+3 {
+		t.Errorf("runes = %d", r.Runes)
+	}
+	want := []Count{{"letter", 10 + 9 + 2}, {"digit", 2}, {"number", 1}, {"space", 3 + 4 + 1}, {"punct", 2}}
+	if !reflect.DeepEqual(r.Categories, want) {
+		t.Errorf("categories = %v, want %v", r.Categories, want)
+	}
+	want = []Count{{"Cyrillic", 9}, {"Common", 13}, {"Latin", 10}, {"Han", 2}}
+	got := make(map[string]int)
+	for _, c := range r.Scripts {
+		got[c.Key] = c.Count
+	}
+	for _, c := range want {
+		if got[c.Key] != c.Count {
+			t.Errorf("script %s = %d, want %d", c.Key, got[c.Key], c.Count)
+		}
+	}
+	if r.UTF8Len != [4]int{20, 12, 2, 0} {
+		t.Errorf("utf8 lengths = %v", r.UTF8Len)
+	}
+}
+
+func TestWords(t *testing.T) {
+	r := report(t, 3, map[string]string{
+		"a": "The cat sat on the mat.\nThe CAT didn't sit; the cat's hat\n",
+		"b": "'cat' hat",
+	})
+	want := []Count{{"cat", 3}, {"hat", 2}, {"cat's", 1}}
+	if !reflect.DeepEqual(r.TopWords, want) {
+		t.Errorf("top words = %v, want %v", r.TopWords, want)
+	}
+	// N-grams include stop words and cross lines, but not files.
+	want = []Count{{"the cat", 2}, {"cat didn't", 1}, {"cat hat", 1}}
+	if !reflect.DeepEqual(r.NGrams, want) {
+		t.Errorf("n-grams = %v, want %v", r.NGrams, want)
+	}
+	if r.Words != 15 {
+		t.Errorf("words = %d, want 15", r.Words)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	r := report(t, 0, map[string]string{
+		"a": "x\ny\n\n\nx\nz",
+		"b": "y\r\nx\n",
+		"c": "z\n",
+	})
+	want := []Duplicate{
+		{"x", 3, []string{"a:1", "a:5", "b:2"}},
+		{"y", 2, []string{"a:2", "b:1"}},
+		{"z", 2, []string{"a:6", "c:1"}},
+	}
+	if !reflect.DeepEqual(r.Duplicates, want) {
+		t.Errorf("duplicates = %v, want %v", r.Duplicates, want)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	r := report(t, 2, map[string]string{
+		"a": "ok\nab\xffcd\n",
+		"b": "\x80\xe4\xb8\n",
+	})
+	if r.Invalid != 4 {
+		t.Errorf("invalid = %d, want 4", r.Invalid)
+	}
+	want := []Position{{"a", 2, 5, 0xff}, {"b", 1, 0, 0x80}}
+	if !reflect.DeepEqual(r.InvalidAt, want) {
+		t.Errorf("invalid at %v, want %v", r.InvalidAt, want)
+	}
+	if cap(r.InvalidAt) > 2 {
+		t.Errorf("recorded %d invalid positions beyond -top 2", cap(r.InvalidAt))
+	}
+	// The invalid byte separates words.
+	if r.Words != 3 {
+		t.Errorf("words = %d, want 3", r.Words)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	stop := filepath.Join(dir, "stop")
+	os.WriteFile(stop, []byte("go\n"), 0666)
+	name := filepath.Join(dir, "in.txt")
+	os.WriteFile(name, []byte("go go gopher\ngo go gopher\n"), 0666)
+
+	var out bytes.Buffer
+	if err := run([]string{"-format", "json", "-stop", stop, name}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.TopWords) != 1 || r.TopWords[0] != (Count{"gopher", 2}) {
+		t.Errorf("top words = %v", r.TopWords)
+	}
+	if len(r.Duplicates) != 1 || r.Duplicates[0].Where[1] != name+":2" {
+		t.Errorf("duplicates = %v", r.Duplicates)
+	}
+
+	out.Reset()
+	if err := run([]string{"-format", "csv", "-ngram", "0"}, strings.NewReader("a,b\na,b\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, rec := range records {
+		if rec[0] == "ngram" {
+			t.Errorf("n-gram with -ngram 0: %q", rec)
+		}
+		if rec[0] == "duplicate" {
+			found = reflect.DeepEqual(rec, []string{"duplicate", "a,b", "2", "<stdin>:1 <stdin>:2"})
+		}
+	}
+	if !found {
+		t.Errorf("no duplicate record in %q", records)
+	}
+
+	out.Reset()
+	if err := run(nil, strings.NewReader("Hello, world\n\xff\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"words    2", "hello  1", "hello world  1", "<stdin>: 0xff  2     13"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("table lacks %q:\n%s", s, out.String())
+		}
+	}
+
+	for _, args := range [][]string{{"-format", "xml"}, {"-ngram", "-1"}, {"-stop", filepath.Join(dir, "missing")}, {filepath.Join(dir, "missing")}} {
+		if err := run(args, strings.NewReader(""), &out); err == nil {
+			t.Errorf("run(%q) succeeded", args)
+		}
+	}
+}
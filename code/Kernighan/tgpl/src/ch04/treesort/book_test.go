@@ -0,0 +1,20 @@
+package treesort
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestTreeSort(t *testing.T) {
+	data := make([]int, 50)
+	for i := range data {
+		data[i] = rand.Int() % 50
+	}
+	want := slices.Clone(data)
+	Sort(want)
+	treeSort(data)
+	if !slices.Equal(data, want) {
+		t.Errorf("treeSort = %v, want %v", data, want)
+	}
+}
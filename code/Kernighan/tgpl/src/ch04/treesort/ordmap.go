@@ -0,0 +1,346 @@
+package treesort
+
+import (
+	"cmp"
+	"iter"
+)
+
+// An OrderedMap is a map whose keys are kept in order, in a left-leaning
+// red-black tree (Sedgewick, 2008). Put, Get, Delete, Floor, Ceiling,
+// Rank and Select take O(log n) time, even if the keys are added in
+// order. Its zero value is an empty map.
+type OrderedMap[K cmp.Ordered, V any] struct {
+	root *node[K, V]
+}
+
+type node[K cmp.Ordered, V any] struct {
+	key         K
+	value       V
+	left, right *node[K, V]
+	red         bool // color of the link from the parent
+	size        int  // number of nodes in this subtree
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMap[K, V]) Len() int { return m.root.len() }
+
+func (n *node[K, V]) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *node[K, V]) isRed() bool { return n != nil && n.red }
+
+// Get returns the value for key, and whether it is present.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	for n := m.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Put sets the value for key, replacing any previous value.
+func (m *OrderedMap[K, V]) Put(key K, value V) {
+	m.root = put(m.root, key, value)
+	m.root.red = false
+}
+
+func put[K cmp.Ordered, V any](h *node[K, V], key K, value V) *node[K, V] {
+	if h == nil {
+		return &node[K, V]{key: key, value: value, red: true, size: 1}
+	}
+	switch c := cmp.Compare(key, h.key); {
+	case c < 0:
+		h.left = put(h.left, key, value)
+	case c > 0:
+		h.right = put(h.right, key, value)
+	default:
+		h.value = value
+	}
+	return h.balance()
+}
+
+// Delete removes key from the map, and reports whether it was present.
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	if _, ok := m.Get(key); !ok {
+		return false
+	}
+	if !m.root.left.isRed() && !m.root.right.isRed() {
+		m.root.red = true
+	}
+	m.root = m.root.delete(key)
+	if m.root != nil {
+		m.root.red = false
+	}
+	return true
+}
+
+// delete removes key, which must be present, from the tree rooted
+// at h. On the way down it keeps the current node or its left child
+// red, so that the node removed at the bottom is red.
+func (h *node[K, V]) delete(key K) *node[K, V] {
+	if cmp.Less(key, h.key) {
+		if !h.left.isRed() && !h.left.left.isRed() {
+			h = h.moveRedLeft()
+		}
+		h.left = h.left.delete(key)
+	} else {
+		if h.left.isRed() {
+			h = h.rotateRight()
+		}
+		if cmp.Compare(key, h.key) == 0 && h.right == nil {
+			return nil
+		}
+		if !h.right.isRed() && !h.right.left.isRed() {
+			h = h.moveRedRight()
+		}
+		if cmp.Compare(key, h.key) == 0 {
+			min := h.right.min()
+			h.key, h.value = min.key, min.value
+			h.right = h.right.deleteMin()
+		} else {
+			h.right = h.right.delete(key)
+		}
+	}
+	return h.balance()
+}
+
+func (h *node[K, V]) deleteMin() *node[K, V] {
+	if h.left == nil {
+		return nil
+	}
+	if !h.left.isRed() && !h.left.left.isRed() {
+		h = h.moveRedLeft()
+	}
+	h.left = h.left.deleteMin()
+	return h.balance()
+}
+
+func (h *node[K, V]) rotateLeft() *node[K, V] {
+	x := h.right
+	h.right = x.left
+	x.left = h
+	x.red, h.red = h.red, true
+	x.size = h.size
+	h.size = 1 + h.left.len() + h.right.len()
+	return x
+}
+
+func (h *node[K, V]) rotateRight() *node[K, V] {
+	x := h.left
+	h.left = x.right
+	x.right = h
+	x.red, h.red = h.red, true
+	x.size = h.size
+	h.size = 1 + h.left.len() + h.right.len()
+	return x
+}
+
+func (h *node[K, V]) flipColors() {
+	h.red = !h.red
+	h.left.red = !h.left.red
+	h.right.red = !h.right.red
+}
+
+func (h *node[K, V]) moveRedLeft() *node[K, V] {
+	h.flipColors()
+	if h.right.left.isRed() {
+		h.right = h.right.rotateRight()
+		h = h.rotateLeft()
+		h.flipColors()
+	}
+	return h
+}
+
+func (h *node[K, V]) moveRedRight() *node[K, V] {
+	h.flipColors()
+	if h.left.left.isRed() {
+		h = h.rotateRight()
+		h.flipColors()
+	}
+	return h
+}
+
+// balance restores the invariants of a left-leaning red-black
+// tree at h, and its size, after a change below it.
+func (h *node[K, V]) balance() *node[K, V] {
+	if h.right.isRed() && !h.left.isRed() {
+		h = h.rotateLeft()
+	}
+	if h.left.isRed() && h.left.left.isRed() {
+		h = h.rotateRight()
+	}
+	if h.left.isRed() && h.right.isRed() {
+		h.flipColors()
+	}
+	h.size = 1 + h.left.len() + h.right.len()
+	return h
+}
+
+func (n *node[K, V]) min() *node[K, V] {
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func (n *node[K, V]) max() *node[K, V] {
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// Min returns the smallest key and its value,
+// or false if the map is empty.
+func (m *OrderedMap[K, V]) Min() (K, V, bool) {
+	if m.root == nil {
+		return none[K, V]()
+	}
+	n := m.root.min()
+	return n.key, n.value, true
+}
+
+// Max returns the largest key and its value,
+// or false if the map is empty.
+func (m *OrderedMap[K, V]) Max() (K, V, bool) {
+	if m.root == nil {
+		return none[K, V]()
+	}
+	n := m.root.max()
+	return n.key, n.value, true
+}
+
+func none[K, V any]() (K, V, bool) {
+	var k K
+	var v V
+	return k, v, false
+}
+
+// Floor returns the largest key no greater than key, and its value,
+// or false if there is none.
+func (m *OrderedMap[K, V]) Floor(key K) (K, V, bool) {
+	var best *node[K, V]
+	for n := m.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			best, n = n, n.right
+		default:
+			return n.key, n.value, true
+		}
+	}
+	if best == nil {
+		return none[K, V]()
+	}
+	return best.key, best.value, true
+}
+
+// Ceiling returns the smallest key no less than key, and its value,
+// or false if there is none.
+func (m *OrderedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	var best *node[K, V]
+	for n := m.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			best, n = n, n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.key, n.value, true
+		}
+	}
+	if best == nil {
+		return none[K, V]()
+	}
+	return best.key, best.value, true
+}
+
+// Rank returns the number of keys less than key.
+func (m *OrderedMap[K, V]) Rank(key K) int {
+	rank := 0
+	for n := m.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			rank += 1 + n.left.len()
+			n = n.right
+		default:
+			return rank + n.left.len()
+		}
+	}
+	return rank
+}
+
+// Select returns the key of rank i, that is, the (i+1)th smallest
+// key, and its value, or false if i is not in [0, m.Len()).
+func (m *OrderedMap[K, V]) Select(i int) (K, V, bool) {
+	if i < 0 || i >= m.Len() {
+		return none[K, V]()
+	}
+	n := m.root
+	for {
+		switch left := n.left.len(); {
+		case i < left:
+			n = n.left
+		case i > left:
+			i -= left + 1
+			n = n.right
+		default:
+			return n.key, n.value, true
+		}
+	}
+}
+
+// All returns an iterator over the keys and values
+// of the map in increasing order of key:
+//
+//	for k, v := range m.All() { ... }
+//
+// The map must not be changed during the iteration.
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.root.each(nil, nil, yield)
+	}
+}
+
+// Range returns an iterator over the keys and values of the map
+// whose keys lie in the half-open interval [lo, hi), in order.
+func (m *OrderedMap[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.root.each(&lo, &hi, yield)
+	}
+}
+
+// each calls yield for the nodes of the tree whose keys lie in
+// [*lo, *hi), in order, omitting either bound if it is nil.
+// It reports whether it ran to completion.
+func (n *node[K, V]) each(lo, hi *K, yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	aboveLo := lo == nil || !cmp.Less(n.key, *lo)
+	belowHi := hi == nil || cmp.Less(n.key, *hi)
+	if aboveLo && !n.left.each(lo, hi, yield) {
+		return false
+	}
+	if aboveLo && belowHi && !yield(n.key, n.value) {
+		return false
+	}
+	if belowHi {
+		return n.right.each(lo, hi, yield)
+	}
+	return true
+}
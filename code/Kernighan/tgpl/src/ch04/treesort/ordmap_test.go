@@ -0,0 +1,173 @@
+package treesort
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func ExampleOrderedMap() {
+	var m OrderedMap[string, int]
+	for i, w := range []string{"delta", "alpha", "echo", "charlie", "bravo"} {
+		m.Put(w, i)
+	}
+	m.Delete("charlie")
+	for k, v := range m.Range("b", "e") {
+		fmt.Println(k, v)
+	}
+	k, _, _ := m.Floor("c")
+	fmt.Println(k, m.Rank("c"))
+	k, _, _ = m.Select(0)
+	fmt.Println(k, m.Len())
+
+	// Output:
+	// bravo 4
+	// delta 0
+	// bravo 2
+	// alpha 4
+}
+
+// check reports the first violation of the invariants of a
+// left-leaning red-black tree in m, and returns its height in black links.
+func check[K int, V any](t *testing.T, n *node[K, V], lo, hi *K) int {
+	t.Helper()
+	if n == nil {
+		return 0
+	}
+	if lo != nil && n.key <= *lo || hi != nil && n.key >= *hi {
+		t.Fatalf("key %v out of order", n.key)
+	}
+	if n.right.isRed() {
+		t.Fatalf("red right link at %v", n.key)
+	}
+	if n.red && n.left.isRed() {
+		t.Fatalf("two red links in a row at %v", n.key)
+	}
+	if n.size != 1+n.left.len()+n.right.len() {
+		t.Fatalf("wrong size at %v", n.key)
+	}
+	l, r := check(t, n.left, lo, &n.key), check(t, n.right, &n.key, hi)
+	if l != r {
+		t.Fatalf("unbalanced at %v: %d black links on the left, %d on the right", n.key, l, r)
+	}
+	if !n.red {
+		l++
+	}
+	return l
+}
+
+func TestOrderedMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var m OrderedMap[int, int]
+	ref := make(map[int]int)
+	for i := 0; i < 20000; i++ {
+		k := rng.Intn(2000)
+		if rng.Intn(3) == 0 {
+			_, want := ref[k]
+			if got := m.Delete(k); got != want {
+				t.Fatalf("Delete(%d) = %t, want %t", k, got, want)
+			}
+			delete(ref, k)
+		} else {
+			m.Put(k, i)
+			ref[k] = i
+		}
+		if i%100 == 0 {
+			if m.root != nil && m.root.red {
+				t.Fatalf("red root")
+			}
+			check(t, m.root, nil, nil)
+		}
+	}
+
+	var keys []int
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	if m.Len() != len(keys) {
+		t.Fatalf("Len = %d, want %d", m.Len(), len(keys))
+	}
+	var got []int
+	for k, v := range m.All() {
+		if v != ref[k] {
+			t.Fatalf("All yields %d: %d, want %d", k, v, ref[k])
+		}
+		got = append(got, k)
+	}
+	if !slices.Equal(got, keys) {
+		t.Fatalf("All yields %v, want %v", got, keys)
+	}
+
+	for k := -1; k <= 2001; k++ {
+		v, ok := m.Get(k)
+		if want, wantOK := ref[k]; v != want || ok != wantOK {
+			t.Fatalf("Get(%d) = %d, %t, want %d, %t", k, v, ok, want, wantOK)
+		}
+		i := sort.SearchInts(keys, k) // rank, and index of ceiling
+		if r := m.Rank(k); r != i {
+			t.Fatalf("Rank(%d) = %d, want %d", k, r, i)
+		}
+		c, _, ok := m.Ceiling(k)
+		if ok != (i < len(keys)) || ok && c != keys[i] {
+			t.Fatalf("Ceiling(%d) = %d, %t", k, c, ok)
+		}
+		j := sort.SearchInts(keys, k+1) - 1 // index of floor
+		f, _, ok := m.Floor(k)
+		if ok != (j >= 0) || ok && f != keys[j] {
+			t.Fatalf("Floor(%d) = %d, %t", k, f, ok)
+		}
+	}
+	for i, k := range keys {
+		if s, _, ok := m.Select(i); !ok || s != k {
+			t.Fatalf("Select(%d) = %d, %t, want %d", i, s, ok, k)
+		}
+	}
+	if _, _, ok := m.Select(len(keys)); ok {
+		t.Errorf("Select(Len()) succeeded")
+	}
+
+	for _, r := range [][2]int{{-5, 10}, {100, 200}, {150, 150}, {1990, 3000}} {
+		var got []int
+		for k := range m.Range(r[0], r[1]) {
+			got = append(got, k)
+		}
+		want := keys[sort.SearchInts(keys, r[0]):sort.SearchInts(keys, r[1])]
+		if !slices.Equal(got, want) && len(got)+len(want) > 0 {
+			t.Errorf("Range(%d, %d) = %v, want %v", r[0], r[1], got, want)
+		}
+	}
+
+	for _, k := range keys {
+		m.Delete(k)
+	}
+	if m.Len() != 0 || m.root != nil {
+		t.Errorf("after deleting every key, Len = %d", m.Len())
+	}
+	if _, _, ok := m.Min(); ok {
+		t.Errorf("Min of empty map succeeded")
+	}
+}
+
+// TestSortedInput checks that the tree stays shallow
+// when keys are added in order.
+func TestSortedInput(t *testing.T) {
+	var m OrderedMap[int, bool]
+	const n = 1 << 16
+	for i := 0; i < n; i++ {
+		m.Put(i, true)
+	}
+	check(t, m.root, nil, nil)
+	if h := height(m.root); h > 2*16 {
+		t.Errorf("height of tree of %d sorted keys is %d", n, h)
+	}
+}
+
+func height[K int, V any](n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return 1 + max(height(n.left), height(n.right))
+}
@@ -1,50 +1,29 @@
 // Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
 // License: https://creativecommons.org/licenses/by-nc-sa/4.0/
 
-// See page 101.
-
-// Package treesort provides insertion sort using an unbalanced binary tree.
+// Package treesort provides an ordered map based on a balanced
+// binary tree, and a tree sort built on it.
+//
+// The book's version, in book.go, used an unbalanced tree, which takes
+// O(n²) time to sort values that are already in order; this one takes
+// O(n log n).
 package treesort
 
-//!+
-type tree struct {
-	value       int
-	left, right *tree
-}
+import "cmp"
 
 // Sort sorts values in place.
-func Sort(values []int) {
-	var root *tree
+//
+// Values that compare equal but are distinct, such as 0 and -0, or
+// NaNs with different payloads, keep their relative order, so the
+// result is always a permutation of the input.
+func Sort[T cmp.Ordered](values []T) {
+	var m OrderedMap[T, []T] // the values equal to each key, in input order
 	for _, v := range values {
-		root = add(root, v)
+		same, _ := m.Get(v)
+		m.Put(v, append(same, v))
 	}
-	appendValues(values[:0], root)
-}
-
-// appendValues appends the elements of t to values in order
-// and returns the resulting slice.
-func appendValues(values []int, t *tree) []int {
-	if t != nil {
-		values = appendValues(values, t.left)
-		values = append(values, t.value)
-		values = appendValues(values, t.right)
+	values = values[:0]
+	for _, same := range m.All() {
+		values = append(values, same...)
 	}
-	return values
 }
-
-func add(t *tree, value int) *tree {
-	if t == nil {
-		// Equivalent to return &tree{value: value}.
-		t = new(tree)
-		t.value = value
-		return t
-	}
-	if value < t.value {
-		t.left = add(t.left, value)
-	} else {
-		t.right = add(t.right, value)
-	}
-	return t
-}
-
-//!-
@@ -4,7 +4,9 @@
 package treesort_test
 
 import (
+	"math"
 	"math/rand"
+	"slices"
 	"sort"
 	"testing"
 
@@ -21,3 +23,80 @@ func TestSort(t *testing.T) {
 		t.Errorf("not sorted: %v", data)
 	}
 }
+
+func TestSortStrings(t *testing.T) {
+	data := []string{"b", "c", "a", "b", "", "a"}
+	treesort.Sort(data)
+	if want := []string{"", "a", "a", "b", "b", "c"}; !slices.Equal(data, want) {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+// TestSortPermutes checks that values that compare equal but
+// differ, such as 0 and -0, all survive.
+func TestSortPermutes(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	for _, data := range [][]float64{{0, negZero}, {negZero, 0}, {1, negZero, 0, -1, negZero}} {
+		want := 0
+		for _, v := range data {
+			if math.Signbit(v) && v == 0 {
+				want++
+			}
+		}
+		treesort.Sort(data)
+		got := 0
+		for _, v := range data {
+			if math.Signbit(v) && v == 0 {
+				got++
+			}
+		}
+		if got != want || !sort.Float64sAreSorted(data) {
+			t.Errorf("got %v with %d negative zeros, want sorted with %d", data, got, want)
+		}
+	}
+}
+
+// Benchmarks compare Sort with sort.Ints on random input and
+// on sorted and reversed input, which an unbalanced tree
+// would sort in quadratic time.
+
+var inputs = []struct {
+	name string
+	make func(n int) []int
+}{
+	{"random", func(n int) []int { return rand.New(rand.NewSource(1)).Perm(n) }},
+	{"sorted", func(n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = i
+		}
+		return data
+	}},
+	{"reversed", func(n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = n - i
+		}
+		return data
+	}},
+}
+
+func BenchmarkSort(b *testing.B) {
+	const n = 10000
+	for _, in := range inputs {
+		data := in.make(n)
+		buf := make([]int, n)
+		b.Run(in.name+"/treesort", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(buf, data)
+				treesort.Sort(buf)
+			}
+		})
+		b.Run(in.name+"/sort.Ints", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(buf, data)
+				sort.Ints(buf)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+// Package depgraph analyzes dependency graphs such as the course
+// prerequisites of gopl.io/ch5/toposort.
+//
+// Unlike topoSort, which silently produces an order even when the
+// graph has cycles, the analyses here report every cycle. Beyond a
+// topological order, they compute the levels of nodes that may be
+// processed in parallel, the critical (longest) path through a graph
+// whose nodes take time, and the transitive reduction, and write
+// graphs in Graphviz DOT and Mermaid formats. Run processes the nodes
+// of a graph concurrently, level by level.
+//
+// Throughout, an edge from a to b means that a depends on b,
+// so b must come first.
+package depgraph
+
+import (
+	"sort"
+	"strings"
+)
+
+// A Graph is a set of nodes, each with prerequisites,
+// a weight (the time it takes), and commands to run.
+type Graph struct {
+	Prereqs  map[string][]string // nodes that must precede each node
+	Weight   map[string]float64  // missing => 1
+	Commands map[string][]string // shell commands for each node
+}
+
+// New returns a graph with the specified prerequisites.
+// Every node has weight 1 and no commands.
+func New(prereqs map[string][]string) *Graph {
+	return &Graph{
+		Prereqs:  prereqs,
+		Weight:   make(map[string]float64),
+		Commands: make(map[string][]string),
+	}
+}
+
+// weight returns the weight of node n.
+func (g *Graph) weight(n string) float64 {
+	if w, ok := g.Weight[n]; ok {
+		return w
+	}
+	return 1
+}
+
+// Nodes returns the names of all the nodes in sorted order,
+// including those that appear only as prerequisites.
+func (g *Graph) Nodes() []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	add := func(n string) {
+		if !seen[n] {
+			seen[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+	for n, prereqs := range g.Prereqs {
+		add(n)
+		for _, p := range prereqs {
+			add(p)
+		}
+	}
+	for n := range g.Weight {
+		add(n)
+	}
+	for n := range g.Commands {
+		add(n)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// An index is a graph with nodes numbered in sorted order.
+type index struct {
+	names []string
+	num   map[string]int
+	edges [][]int // edges[i] are the prerequisites of node i, increasing
+}
+
+func (g *Graph) index() *index {
+	x := &index{names: g.Nodes(), num: make(map[string]int)}
+	for i, n := range x.names {
+		x.num[n] = i
+	}
+	x.edges = make([][]int, len(x.names))
+	for i, n := range x.names {
+		seen := make(map[int]bool)
+		for _, p := range g.Prereqs[n] {
+			if j := x.num[p]; !seen[j] {
+				seen[j] = true
+				x.edges[i] = append(x.edges[i], j)
+			}
+		}
+		sort.Ints(x.edges[i])
+	}
+	return x
+}
+
+// A Cycle is an elementary cycle of a graph: a path of dependencies
+// from a node back to itself that visits no other node twice.
+type Cycle struct {
+	Nodes []string // the members, sorted
+	Path  []string // the cycle from its first member, ending where it starts
+}
+
+func (c Cycle) String() string { return strings.Join(c.Path, " -> ") }
+
+// A CycleError reports that a graph has cycles.
+type CycleError struct {
+	Cycles []Cycle
+}
+
+func (e *CycleError) Error() string {
+	var paths []string
+	for _, c := range e.Cycles {
+		paths = append(paths, c.String())
+	}
+	return "dependency cycle: " + strings.Join(paths, "; ")
+}
+
+// Cycles returns every elementary cycle of the graph, in order of
+// their paths, or nil if it is acyclic. A dense graph may have very
+// many cycles: n nodes that all depend on each other have more than
+// (n-1)! of them.
+func (g *Graph) Cycles() []Cycle {
+	return g.index().cycles()
+}
+
+// cycles finds the elementary cycles using Johnson's algorithm.
+// For each node s in turn, it finds the cycles through s among the
+// nodes numbered s or more, searching only the strongly connected
+// component of s, and blocking nodes from which s cannot (yet) be
+// reached, so that each search step leads to a cycle.
+func (x *index) cycles() []Cycle {
+	n := len(x.names)
+	blocked := make([]bool, n)
+	blockers := make([]map[int]bool, n) // blockers[w] are blocked until w is unblocked
+	var in []bool                       // members of the component being searched
+	var stack []int
+	var cycles []Cycle
+
+	var unblock func(v int)
+	unblock = func(v int) {
+		blocked[v] = false
+		for w := range blockers[v] {
+			delete(blockers[v], w)
+			if blocked[w] {
+				unblock(w)
+			}
+		}
+	}
+
+	// circuit extends the path on stack with v, records each cycle
+	// that continues back to s, and reports whether there was any.
+	var circuit func(v, s int) bool
+	circuit = func(v, s int) bool {
+		found := false
+		stack = append(stack, v)
+		blocked[v] = true
+		for _, w := range x.edges[v] {
+			if !in[w] {
+				continue
+			}
+			if w == s {
+				cycles = append(cycles, x.cycle(stack))
+				found = true
+			} else if !blocked[w] && circuit(w, s) {
+				found = true
+			}
+		}
+		if found {
+			unblock(v)
+		} else {
+			for _, w := range x.edges[v] {
+				if in[w] {
+					blockers[w][v] = true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		return found
+	}
+
+	for s := 0; s < n; s++ {
+		comp := x.component(s)
+		if len(comp) == 1 && !x.hasEdge(s, s) {
+			continue
+		}
+		in = make([]bool, n)
+		for _, v := range comp {
+			in[v] = true
+			blocked[v] = false
+			blockers[v] = make(map[int]bool)
+		}
+		circuit(s, s)
+	}
+	return cycles
+}
+
+// cycle returns the Cycle whose path is stack followed by its first node.
+func (x *index) cycle(stack []int) Cycle {
+	var c Cycle
+	for _, v := range stack {
+		c.Path = append(c.Path, x.names[v])
+	}
+	c.Path = append(c.Path, x.names[stack[0]])
+	c.Nodes = append(c.Nodes, c.Path[:len(stack)]...)
+	sort.Strings(c.Nodes)
+	return c
+}
+
+// component returns the strongly connected component containing s
+// of the subgraph of nodes numbered s or more, found using Tarjan's
+// algorithm.
+func (x *index) component(s int) []int {
+	order := make(map[int]int) // order of discovery, from 1
+	low := make(map[int]int)   // least order reachable
+	onStack := make(map[int]bool)
+	var stack []int
+	var comp []int
+
+	var visit func(v int)
+	visit = func(v int) {
+		order[v] = len(order) + 1
+		low[v] = order[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range x.edges[v] {
+			if w < s {
+				continue
+			}
+			if order[w] == 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], order[w])
+			}
+		}
+		if low[v] == order[v] {
+			// Pop the component rooted at v. Since the search
+			// starts at s, the last of these contains s.
+			comp = nil
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp = append(comp, w)
+				if w == v {
+					break
+				}
+			}
+		}
+	}
+	visit(s)
+	return comp
+}
+
+func (x *index) hasEdge(v, w int) bool {
+	i := sort.SearchInts(x.edges[v], w)
+	return i < len(x.edges[v]) && x.edges[v][i] == w
+}
+
+// TopoSort returns the nodes in an order in which each comes after
+// all its prerequisites, or a *CycleError if there is no such order.
+// As in topoSort, the nodes are visited depth first in sorted order,
+// so the result is the same on every run.
+func (g *Graph) TopoSort() ([]string, error) {
+	x := g.index()
+	if cycles := x.cycles(); cycles != nil {
+		return nil, &CycleError{cycles}
+	}
+	var order []string
+	seen := make([]bool, len(x.names))
+	var visitAll func(items []int)
+	visitAll = func(items []int) {
+		for _, v := range items {
+			if !seen[v] {
+				seen[v] = true
+				visitAll(x.edges[v])
+				order = append(order, x.names[v])
+			}
+		}
+	}
+	all := make([]int, len(x.names))
+	for v := range all {
+		all[v] = v
+	}
+	visitAll(all)
+	return order, nil
+}
+
+// topo returns the node numbers of an acyclic graph in topological order.
+func (x *index) topo() ([]int, error) {
+	if cycles := x.cycles(); cycles != nil {
+		return nil, &CycleError{cycles}
+	}
+	var order []int
+	seen := make([]bool, len(x.names))
+	var visit func(v int)
+	visit = func(v int) {
+		if !seen[v] {
+			seen[v] = true
+			for _, w := range x.edges[v] {
+				visit(w)
+			}
+			order = append(order, v)
+		}
+	}
+	for v := range x.names {
+		visit(v)
+	}
+	return order, nil
+}
+
+// Levels divides the nodes into levels: level 0 holds the nodes with
+// no prerequisites, and level i+1 holds the nodes whose prerequisites
+// are all in levels up to i. The nodes of a level are independent of
+// one another, so they may be processed in parallel. Each level is
+// sorted.
+func (g *Graph) Levels() ([][]string, error) {
+	x := g.index()
+	order, err := x.topo()
+	if err != nil {
+		return nil, err
+	}
+	level := make([]int, len(x.names))
+	var levels [][]string
+	for _, v := range order {
+		for _, w := range x.edges[v] {
+			level[v] = max(level[v], level[w]+1)
+		}
+		for len(levels) <= level[v] {
+			levels = append(levels, nil)
+		}
+		levels[level[v]] = append(levels[level[v]], x.names[v])
+	}
+	for _, l := range levels {
+		sort.Strings(l)
+	}
+	return levels, nil
+}
+
+// CriticalPath returns the path through the graph with the greatest
+// total weight, which is the least time in which all the nodes can be
+// processed given unlimited parallelism. The path starts with a node
+// that has no prerequisites.
+func (g *Graph) CriticalPath() (float64, []string, error) {
+	x := g.index()
+	order, err := x.topo()
+	if err != nil {
+		return 0, nil, err
+	}
+	finish := make([]float64, len(x.names)) // earliest finish time
+	prev := make([]int, len(x.names))       // the prerequisite that finishes last
+	end := -1
+	for _, v := range order {
+		prev[v] = -1
+		for _, w := range x.edges[v] {
+			if prev[v] < 0 || finish[w] > finish[prev[v]] {
+				prev[v] = w
+			}
+		}
+		if prev[v] >= 0 {
+			finish[v] = finish[prev[v]]
+		}
+		finish[v] += g.weight(x.names[v])
+		if end < 0 || finish[v] > finish[end] {
+			end = v
+		}
+	}
+	if end < 0 {
+		return 0, nil, nil
+	}
+	var path []string
+	for v := end; v >= 0; v = prev[v] {
+		path = append([]string{x.names[v]}, path...)
+	}
+	return finish[end], path, nil
+}
+
+// TransitiveReduction returns the graph with the fewest edges that has
+// the same order constraints as g: it omits each prerequisite implied
+// by another. The weights and commands are those of g.
+func (g *Graph) TransitiveReduction() (*Graph, error) {
+	x := g.index()
+	order, err := x.topo()
+	if err != nil {
+		return nil, err
+	}
+	// reach[v] is the set of nodes on which v depends, directly or not.
+	reach := make([]map[int]bool, len(x.names))
+	for _, v := range order {
+		reach[v] = make(map[int]bool)
+		for _, w := range x.edges[v] {
+			reach[v][w] = true
+			for u := range reach[w] {
+				reach[v][u] = true
+			}
+		}
+	}
+	r := &Graph{Prereqs: make(map[string][]string), Weight: g.Weight, Commands: g.Commands}
+	for v, name := range x.names {
+		var prereqs []string
+	edges:
+		for _, w := range x.edges[v] {
+			for _, u := range x.edges[v] {
+				if u != w && reach[u][w] {
+					continue edges // implied by u
+				}
+			}
+			prereqs = append(prereqs, x.names[w])
+		}
+		if _, ok := g.Prereqs[name]; ok || prereqs != nil {
+			r.Prereqs[name] = prereqs
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,312 @@
+package depgraph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Copied from gopl.io/ch5/toposort.
+var prereqs = map[string][]string{
+	"algorithms": {"data structures"},
+	"calculus":   {"linear algebra"},
+
+	"compilers": {
+		"data structures",
+		"formal languages",
+		"computer organization",
+	},
+
+	"data structures":       {"discrete math"},
+	"databases":             {"data structures"},
+	"discrete math":         {"intro to programming"},
+	"formal languages":      {"discrete math"},
+	"networks":              {"operating systems"},
+	"operating systems":     {"data structures", "computer organization"},
+	"programming languages": {"data structures", "computer organization"},
+}
+
+func TestTopoSort(t *testing.T) {
+	order, err := New(prereqs).TopoSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := make(map[string]int)
+	for i, n := range order {
+		pos[n] = i
+	}
+	if len(order) != 13 || len(pos) != 13 {
+		t.Fatalf("got %d nodes, want 13: %v", len(order), order)
+	}
+	for n, ps := range prereqs {
+		for _, p := range ps {
+			if pos[p] > pos[n] {
+				t.Errorf("%s comes before its prerequisite %s", n, p)
+			}
+		}
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := New(map[string][]string{
+		"a": {"b"},
+		"b": {"c", "x"},
+		"c": {"a", "b"},
+		"d": {"d"},
+		"e": {"a"}, // depends on a cycle, but is not in one
+		"x": {"y"},
+		"y": {},
+	})
+	want := []Cycle{
+		{Nodes: []string{"a", "b", "c"}, Path: []string{"a", "b", "c", "a"}},
+		{Nodes: []string{"b", "c"}, Path: []string{"b", "c", "b"}},
+		{Nodes: []string{"d"}, Path: []string{"d", "d"}},
+	}
+	if got := g.Cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cycles = %v, want %v", got, want)
+	}
+
+	for name, f := range map[string]func() error{
+		"TopoSort":            func() error { _, err := g.TopoSort(); return err },
+		"Levels":              func() error { _, err := g.Levels(); return err },
+		"CriticalPath":        func() error { _, _, err := g.CriticalPath(); return err },
+		"TransitiveReduction": func() error { _, err := g.TransitiveReduction(); return err },
+	} {
+		var cerr *CycleError
+		if err := f(); !errors.As(err, &cerr) || len(cerr.Cycles) != 3 {
+			t.Errorf("%s: err = %v, want all three cycles", name, err)
+		} else if want := "dependency cycle: a -> b -> c -> a; b -> c -> b; d -> d"; err.Error() != want {
+			t.Errorf("%s: err = %q, want %q", name, err, want)
+		}
+	}
+
+	// Cycles that share a node are reported separately.
+	g = New(map[string][]string{"a": {"b", "c"}, "b": {"a"}, "c": {"a"}})
+	if _, err := g.TopoSort(); err == nil || err.Error() != "dependency cycle: a -> b -> a; a -> c -> a" {
+		t.Errorf("TopoSort: err = %v, want both cycles through a", err)
+	}
+
+	// Every node depends on every other: a complete graph on 4
+	// nodes has 6 cycles of 2 nodes, 8 of 3, and 6 of 4.
+	complete := make(map[string][]string)
+	for _, n := range []string{"a", "b", "c", "d"} {
+		for _, m := range []string{"a", "b", "c", "d"} {
+			if m != n {
+				complete[n] = append(complete[n], m)
+			}
+		}
+	}
+	if n := len(New(complete).Cycles()); n != 20 {
+		t.Errorf("complete graph on 4 nodes has %d cycles, want 20", n)
+	}
+
+	if c := New(prereqs).Cycles(); c != nil {
+		t.Errorf("Cycles of course prerequisites = %v", c)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	levels, err := New(prereqs).Levels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"computer organization", "intro to programming", "linear algebra"},
+		{"calculus", "discrete math"},
+		{"data structures", "formal languages"},
+		{"algorithms", "compilers", "databases", "operating systems", "programming languages"},
+		{"networks"},
+	}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("Levels = %q, want %q", levels, want)
+	}
+}
+
+const build = `# A small build.
+lib 2: gen
+	echo lib
+gen 1:
+app 3: lib gen  # gen is implied by lib
+	echo app
+test 10: lib
+"docs and more" 0.5:
+`
+
+func TestCriticalPath(t *testing.T) {
+	g, err := Parse(strings.NewReader(build))
+	if err != nil {
+		t.Fatal(err)
+	}
+	length, path, err := g.CriticalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length != 13 || !slices.Equal(path, []string{"gen", "lib", "test"}) {
+		t.Errorf("CriticalPath = %g, %q, want 13, [gen lib test]", length, path)
+	}
+
+	length, path, err = New(prereqs).CriticalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length != 5 || path[4] != "networks" {
+		t.Errorf("CriticalPath of courses = %g, %q", length, path)
+	}
+}
+
+func TestTransitiveReduction(t *testing.T) {
+	g, err := Parse(strings.NewReader(build))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := g.TransitiveReduction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	r.Write(&buf)
+	want := `app 3: lib
+	echo app
+"docs and more" 0.5:
+gen 1:
+lib 2: gen
+	echo lib
+test 10: lib
+`
+	if buf.String() != want {
+		t.Errorf("reduction:\n%s\nwant:\n%s", &buf, want)
+	}
+
+	// The output can be read back.
+	g2, err := Parse(strings.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	g2.Write(&buf)
+	if buf.String() != want {
+		t.Errorf("Write(Parse(Write(g))):\n%s\nwant:\n%s", &buf, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct{ input, err string }{
+		{"\techo hi\n", "line 1: command outside node"},
+		{"a b c: d\n", "line 1: want name [weight]: prerequisites..."},
+		{"a\n", "line 1: want name [weight]: prerequisites..."},
+		{"a x: b\n", `line 1: bad weight "x"`},
+		{"a -1: b\n", `line 1: bad weight "-1"`},
+		{"a: b\n\na: c\n", "line 3: a defined again"},
+		{`"a: b` + "\n", `line 1: bad quoted name at "a: b`},
+	} {
+		_, err := Parse(strings.NewReader(test.input))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Parse(%q): err = %v, want %s", test.input, err, test.err)
+		}
+	}
+}
+
+func TestWriteDOTMermaid(t *testing.T) {
+	g := New(map[string][]string{"b": {"a"}, `say "hi"`: {"b"}})
+	g.Weight["b"] = 2
+	var buf bytes.Buffer
+	g.WriteDOT(&buf)
+	want := `digraph deps {
+	"a";
+	"b" [label="b (2)"];
+	"say \"hi\"";
+	"b" -> "a";
+	"say \"hi\"" -> "b";
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteDOT:\n%s\nwant:\n%s", &buf, want)
+	}
+
+	buf.Reset()
+	g.WriteMermaid(&buf)
+	want = `flowchart LR
+	n0["a"]
+	n1["b (2)"]
+	n2["say #quot;hi#quot;"]
+	n1 --> n0
+	n2 --> n1
+`
+	if buf.String() != want {
+		t.Errorf("WriteMermaid:\n%s\nwant:\n%s", &buf, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	g := New(prereqs)
+	levels, _ := g.Levels()
+	level := make(map[string]int)
+	for i, l := range levels {
+		for _, n := range l {
+			level[n] = i
+		}
+	}
+
+	var (
+		mu                  sync.Mutex // guards the following
+		done                []string
+		running, maxRunning int
+	)
+	err := g.Run(context.Background(), 2, func(ctx context.Context, node string) error {
+		mu.Lock()
+		for _, d := range done {
+			if level[d] > level[node] {
+				mu.Unlock()
+				return fmt.Errorf("ran after %s, of a later level", d)
+			}
+		}
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond) // let the others start
+
+		mu.Lock()
+		running--
+		done = append(done, node)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 13 || maxRunning != 2 {
+		t.Errorf("ran %d nodes, up to %d at once; want 13, up to 2", len(done), maxRunning)
+	}
+
+	// A failure stops Run after the failing level.
+	done = nil
+	err = g.Run(context.Background(), 4, func(ctx context.Context, node string) error {
+		mu.Lock()
+		done = append(done, node)
+		mu.Unlock()
+		if node == "discrete math" || node == "calculus" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if want := "calculus: failed\ndiscrete math: failed"; err == nil || err.Error() != want {
+		t.Errorf("Run: err = %v, want %q", err, want)
+	}
+	if len(done) != 5 {
+		t.Errorf("Run went on to process %d nodes after the failure, want 5 in all", len(done))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.Run(ctx, 1, func(context.Context, string) error { return nil }); err != context.Canceled {
+		t.Errorf("Run with cancelled context: err = %v", err)
+	}
+}
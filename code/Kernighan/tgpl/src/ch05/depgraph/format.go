@@ -0,0 +1,192 @@
+package depgraph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Parse reads a graph in a format like that of a makefile:
+//
+//	# Comments and blank lines are ignored.
+//	compilers 5: "data structures" "formal languages"
+//		go build ./compilers
+//	"data structures": "discrete math"
+//
+// Each unindented line names a node, optionally followed by its
+// weight, then a colon and its prerequisites. Names containing spaces
+// or colons are quoted as in Go. The indented lines after a node's
+// line are its commands.
+func Parse(r io.Reader) (*Graph, error) {
+	g := New(make(map[string][]string))
+	in := bufio.NewScanner(r)
+	var node string // the node of the preceding unindented line
+	for line := 1; in.Scan(); line++ {
+		text := in.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if text[0] == ' ' || text[0] == '\t' {
+			if node == "" {
+				return nil, fmt.Errorf("line %d: command outside node", line)
+			}
+			g.Commands[node] = append(g.Commands[node], trimmed)
+			continue
+		}
+
+		words, err := fields(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		colon := -1
+		for i, w := range words {
+			if w == ":" {
+				colon = i
+				break
+			}
+		}
+		if colon < 1 || colon > 2 {
+			return nil, fmt.Errorf("line %d: want name [weight]: prerequisites...", line)
+		}
+		node = words[0]
+		if _, ok := g.Prereqs[node]; ok {
+			return nil, fmt.Errorf("line %d: %s defined again", line, node)
+		}
+		if colon == 2 {
+			w, err := strconv.ParseFloat(words[1], 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("line %d: bad weight %q", line, words[1])
+			}
+			g.Weight[node] = w
+		}
+		g.Prereqs[node] = append([]string{}, words[colon+1:]...)
+	}
+	if err := in.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Load reads a graph from the named file, or
+// from the standard input if filename is "-".
+func Load(filename string) (*Graph, error) {
+	if filename == "-" {
+		return Parse(os.Stdin)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	g, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return g, nil
+}
+
+// fields splits a line into names, quoted strings and colons.
+func fields(s string) ([]string, error) {
+	var words []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case s == "" || s[0] == '#':
+			return words, nil
+		case s[0] == ':':
+			words = append(words, ":")
+			s = s[1:]
+		case s[0] == '"' || s[0] == '`':
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("bad quoted name at %s", s)
+			}
+			name, _ := strconv.Unquote(q)
+			words = append(words, name)
+			s = s[len(q):]
+		default:
+			i := strings.IndexAny(s, " \t:#\"`")
+			if i < 0 {
+				i = len(s)
+			}
+			words = append(words, s[:i])
+			s = s[i:]
+		}
+	}
+}
+
+// quote returns name as it must appear in the format read by Parse.
+func quote(name string) string {
+	if name == "" || strings.ContainsAny(name, " \t:#\"`") || name != strings.TrimSpace(name) {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// Write writes the graph in the format read by Parse.
+func (g *Graph) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, n := range g.Nodes() {
+		fmt.Fprint(bw, quote(n))
+		if wt, ok := g.Weight[n]; ok {
+			fmt.Fprintf(bw, " %g", wt)
+		}
+		fmt.Fprint(bw, ":")
+		for _, p := range g.Prereqs[n] {
+			fmt.Fprintf(bw, " %s", quote(p))
+		}
+		fmt.Fprintln(bw)
+		for _, cmd := range g.Commands[n] {
+			fmt.Fprintf(bw, "\t%s\n", cmd)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz.
+// Nodes whose weight is not 1 are labeled with it.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph deps {")
+	for _, n := range g.Nodes() {
+		if wt := g.weight(n); wt != 1 {
+			fmt.Fprintf(bw, "\t%q [label=%q];\n", n, fmt.Sprintf("%s (%g)", n, wt))
+		} else {
+			fmt.Fprintf(bw, "\t%q;\n", n)
+		}
+	}
+	for _, n := range g.Nodes() {
+		for _, p := range g.Prereqs[n] {
+			fmt.Fprintf(bw, "\t%q -> %q;\n", n, p)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+// Nodes are numbered, and labeled with their names.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	nodes := g.Nodes()
+	id := make(map[string]int)
+	for i, n := range nodes {
+		id[n] = i
+		label := n
+		if wt := g.weight(n); wt != 1 {
+			label = fmt.Sprintf("%s (%g)", n, wt)
+		}
+		fmt.Fprintf(bw, "\tn%d[\"%s\"]\n", i, strings.ReplaceAll(label, `"`, "#quot;"))
+	}
+	for _, n := range nodes {
+		for _, p := range g.Prereqs[n] {
+			fmt.Fprintf(bw, "\tn%d --> n%d\n", id[n], id[p])
+		}
+	}
+	return bw.Flush()
+}
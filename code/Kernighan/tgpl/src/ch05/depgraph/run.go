@@ -0,0 +1,52 @@
+package depgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Run calls process for each node of the graph, level by level, as
+// computed by Levels. The nodes of a level are processed concurrently,
+// at most parallel at a time, and each level starts once the previous
+// one has finished. If process fails for any node of a level, Run
+// returns the errors without starting the next level. It also stops
+// if ctx is cancelled.
+func (g *Graph) Run(ctx context.Context, parallel int, process func(ctx context.Context, node string) error) error {
+	levels, err := g.Levels()
+	if err != nil {
+		return err
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	sema := make(chan struct{}, parallel) // counting semaphore
+	for _, level := range levels {
+		var wg sync.WaitGroup
+		errs := make([]error, len(level)) // errs[i] is the error for level[i]
+		for i, node := range level {
+			wg.Add(1)
+			go func(i int, node string) {
+				defer wg.Done()
+				select {
+				case sema <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-sema }()
+				if err := process(ctx, node); err != nil {
+					errs[i] = fmt.Errorf("%s: %w", node, err)
+				}
+			}(i, node)
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
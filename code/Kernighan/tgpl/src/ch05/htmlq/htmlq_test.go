@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html><head><title>Test page</title><style>p { color: red }</style></head>
+<body>
+<div id="content">
+  <h1>Welcome</h1>
+  <p class="intro lead">Read <a href="/docs/">the docs</a> first.</p>
+  <ul class="nav">
+    <li><a href="a.html" title="first page">A</a></li>
+    <li><a href="https://example.com/b.pdf">B</a><br></li>
+  </ul>
+  <div><p>Nested <b>paragraph</b></p></div>
+</div>
+<p>Footer &amp; more</p>
+</body></html>`
+
+// serve starts a server for page at /page.html.
+func serve(t *testing.T) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/page.html"
+}
+
+func runQuery(t *testing.T, q *query, selector string) string {
+	t.Helper()
+	return runQueryURL(t, q, selector, serve(t))
+}
+
+func runQueryURL(t *testing.T, q *query, selector, url string) string {
+	t.Helper()
+	sels, err := parse(selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.sels = sels
+	doc, base, err := load(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	q.run(&out, doc, base)
+	if q.counts != nil {
+		printCounts(&out, q.counts)
+	}
+	return out.String()
+}
+
+func TestText(t *testing.T) {
+	for _, test := range []struct{ sel, want string }{
+		{"h1", "Welcome\n"},
+		{"#content > p", "Read the docs first.\n"},
+		{"#content p", "Read the docs first.\nNested paragraph\n"},
+		{"body > p", "Footer & more\n"},
+		{"p.intro.lead a", "the docs\n"},
+		{"ul.nav li:first", ""}, // pseudo-classes are unsupported
+		{"a[title='first page'], a[href$=.pdf]", "A\nB\n"},
+		{"a[ href ^= https ]", "B\n"},
+		{"head", "Test page\n"}, // no style text
+		{"DIV#content > H1", "Welcome\n"},
+		{"* > b", "paragraph\n"},
+		{`a[title="first page"], a[title="x, y"]`, "A\n"}, // comma within quotes
+		{"a[title='a]b'], li\t>\ta[href$=pdf]", "B\n"},    // ] within quotes; tabs
+	} {
+		if _, err := parse(test.sel); err != nil {
+			if test.want != "" {
+				t.Errorf("parse(%q): %v", test.sel, err)
+			}
+			continue
+		}
+		if got := runQuery(t, &query{text: true}, test.sel); got != test.want {
+			t.Errorf("-text %q: got %q, want %q", test.sel, got, test.want)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	got := runQuery(t, &query{attr: "href"}, "a")
+	if want := "/docs/\na.html\nhttps://example.com/b.pdf\n"; got != want {
+		t.Errorf("-a href: got %q, want %q", got, want)
+	}
+	url := serve(t)
+	got = runQueryURL(t, &query{attr: "href", abs: true}, "li a", url)
+	base := url[:len(url)-len("page.html")]
+	if want := base + "a.html\nhttps://example.com/b.pdf\n"; got != want {
+		t.Errorf("-a href -abs: got %q, want %q", got, want)
+	}
+}
+
+func TestCount(t *testing.T) {
+	got := runQuery(t, &query{counts: make(map[string]int)}, "#content *")
+	want := "3\ta\n2\tli\n2\tp\n1\tb\n1\tbr\n1\tdiv\n1\th1\n1\tul\n"
+	if got != want {
+		t.Errorf("-count: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPretty(t *testing.T) {
+	got := runQuery(t, &query{}, "li")
+	want := `<li>
+  <a href="a.html" title="first page">
+    A
+  </a>
+</li>
+<li>
+  <a href="https://example.com/b.pdf">
+    B
+  </a>
+  <br>
+</li>
+`
+	if got != want {
+		t.Errorf("pretty: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, bad := range []string{"", "> a", "a >", "a > > b", "a[href", "a[=x]", "a.", "p,", "a:hover", "svg|rect", "a[x:href]"} {
+		if _, err := parse(bad); err == nil {
+			t.Errorf("parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	url := serve(t)
+	if _, _, err := load(url + ".missing"); err == nil {
+		t.Errorf("load of missing page succeeded")
+	}
+	if _, _, err := load("no-such-file.html"); err == nil {
+		t.Errorf("load of missing file succeeded")
+	}
+}
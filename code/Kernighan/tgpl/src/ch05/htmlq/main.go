@@ -0,0 +1,191 @@
+// Htmlq queries HTML documents with CSS selectors.
+//
+// It fetches each URL argument, or reads each file argument (or the
+// standard input if there are none), finds the elements that match
+// the selector, and prints them indented, as gopl.io/ch5/outline2 does,
+// or prints their text, an attribute, or a count of them by tag:
+//
+//	htmlq 'div#content > p' https://golang.org
+//	htmlq -a href -abs 'a[href]' https://golang.org
+//	htmlq -text 'h1, h2' page.html
+//	htmlq -count '*' <page.html
+//
+// The selectors are those of CSS without pseudo-classes: tag names,
+// *, #id, .class, [attr], [attr=val], [attr~=val], [attr^=val],
+// [attr$=val], [attr*=val], descendant (space) and child (>)
+// combinators, and comma-separated alternatives, as parsed by
+// gopl.io/ch7/selector.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopl.io/ch7/selector"
+)
+
+var (
+	attr  = flag.String("a", "", "print attribute `name` of each match")
+	text  = flag.Bool("text", false, "print the text of each match")
+	count = flag.Bool("count", false, "print the number of matches of each tag")
+	abs   = flag.Bool("abs", false, "with -a, resolve URLs against the document's URL")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: htmlq [-a name [-abs] | -text | -count] selector [url|file...]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	sels, err := parse(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "htmlq: %v\n", err)
+		os.Exit(2)
+	}
+	q := &query{sels: sels, attr: *attr, text: *text, abs: *abs}
+	if *count {
+		q.counts = make(map[string]int)
+	}
+
+	inputs := flag.Args()[1:]
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	status := 0
+	for _, input := range inputs {
+		doc, base, err := load(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "htmlq: %v\n", err)
+			status = 1
+			continue
+		}
+		q.run(os.Stdout, doc, base)
+	}
+	if q.counts != nil {
+		printCounts(os.Stdout, q.counts)
+	}
+	os.Exit(status)
+}
+
+// load parses the HTML document named by input: a URL,
+// a file name, or "-" for the standard input. It also
+// returns the document's URL, if it has one.
+func load(input string) (*html.Node, *url.URL, error) {
+	if input == "-" {
+		doc, err := html.Parse(os.Stdin)
+		return doc, nil, err
+	}
+	if !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://") {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		doc, err := html.Parse(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing %s as HTML: %v", input, err)
+		}
+		return doc, nil, nil
+	}
+
+	resp, err := http.Get(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("getting %s: %s", input, resp.Status)
+	}
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing %s as HTML: %v", input, err)
+	}
+	return doc, resp.Request.URL, nil
+}
+
+// A query describes what to print about the matching elements.
+type query struct {
+	sels   []selector.Selector
+	attr   string         // print this attribute, if not empty
+	text   bool           // print the text
+	abs    bool           // resolve attr values as URLs
+	counts map[string]int // if not nil, count matches by tag instead
+}
+
+// run prints what q asks for about the elements of doc,
+// whose URL is base (or nil), in document order.
+func (q *query) run(w io.Writer, doc *html.Node, base *url.URL) {
+	forEachNode(doc, func(n *html.Node) {
+		if !q.matches(n) {
+			return
+		}
+		switch {
+		case q.counts != nil:
+			q.counts[n.Data]++
+		case q.attr != "":
+			for _, a := range n.Attr {
+				if a.Key != q.attr {
+					continue
+				}
+				val := a.Val
+				if q.abs && base != nil {
+					if u, err := base.Parse(val); err == nil {
+						val = u.String()
+					}
+				}
+				fmt.Fprintln(w, val)
+			}
+		case q.text:
+			fmt.Fprintln(w, textOf(n))
+		default:
+			pretty(w, n)
+		}
+	}, nil)
+}
+
+func (q *query) matches(n *html.Node) bool {
+	for _, sel := range q.sels {
+		if sel.Match(element{n}) {
+			return true
+		}
+	}
+	return false
+}
+
+// printCounts prints the counts, most frequent first.
+func printCounts(w io.Writer, counts map[string]int) {
+	var tags []string
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%d\t%s\n", counts[tag], tag)
+	}
+}
+
+// Copied from gopl.io/ch5/outline2.
+func forEachNode(n *html.Node, pre, post func(n *html.Node)) {
+	if pre != nil {
+		pre(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		forEachNode(c, pre, post)
+	}
+	if post != nil {
+		post(n)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// void elements have no end tag.
+var void = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// raw elements contain text that is not escaped.
+var raw = map[string]bool{"script": true, "style": true}
+
+// pretty prints the tree rooted at n, one tag, comment
+// or piece of text per line, indented to show nesting.
+func pretty(w io.Writer, n *html.Node) {
+	var depth int
+	start := func(n *html.Node) {
+		switch n.Type {
+		case html.ElementNode:
+			fmt.Fprintf(w, "%*s<%s", depth*2, "", n.Data)
+			for _, a := range n.Attr {
+				fmt.Fprintf(w, " %s=\"%s\"", a.Key, html.EscapeString(a.Val))
+			}
+			fmt.Fprintln(w, ">")
+			if !void[n.Data] {
+				depth++
+			}
+		case html.TextNode:
+			text := strings.TrimSpace(n.Data)
+			if text == "" {
+				return
+			}
+			if n.Parent == nil || !raw[n.Parent.Data] {
+				text = html.EscapeString(strings.Join(strings.Fields(text), " "))
+			}
+			for _, line := range strings.Split(text, "\n") {
+				fmt.Fprintf(w, "%*s%s\n", depth*2, "", line)
+			}
+		case html.CommentNode:
+			fmt.Fprintf(w, "%*s<!--%s-->\n", depth*2, "", n.Data)
+		}
+	}
+	end := func(n *html.Node) {
+		if n.Type == html.ElementNode && !void[n.Data] {
+			depth--
+			fmt.Fprintf(w, "%*s</%s>\n", depth*2, "", n.Data)
+		}
+	}
+	forEachNode(n, start, end)
+}
+
+// textOf returns the text within n, with white space
+// collapsed, omitting the contents of scripts and styles.
+func textOf(n *html.Node) string {
+	var words []string
+	forEachNode(n, func(n *html.Node) {
+		if n.Type == html.TextNode && (n.Parent == nil || !raw[n.Parent.Data]) {
+			words = append(words, strings.Fields(n.Data)...)
+		}
+	}, nil)
+	return strings.Join(words, " ")
+}
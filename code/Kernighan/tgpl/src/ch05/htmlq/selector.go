@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopl.io/ch7/selector"
+)
+
+// parse parses a comma-separated list of selectors.
+// HTML has no namespaces, so a prefix such as the a of a:hover,
+// which looks like a pseudo-class, is an error.
+func parse(s string) ([]selector.Selector, error) {
+	sels, err := selector.ParseList(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, sel := range sels {
+		for _, c := range sel {
+			if c.Space != "" {
+				return nil, fmt.Errorf("selector %q: unsupported %s:%s", s, c.Space, c.Local)
+			}
+			for _, a := range c.Attrs {
+				if a.Space != "" {
+					return nil, fmt.Errorf("selector %q: unsupported attribute %s:%s", s, a.Space, a.Local)
+				}
+			}
+		}
+	}
+	return sels, nil
+}
+
+// An element is an HTML element as seen by a selector.
+// Tag and attribute names are compared without regard to case.
+type element struct{ n *html.Node }
+
+func (e element) HasName(space, local string) bool {
+	return e.n.Type == html.ElementNode && space == "" &&
+		(local == "*" || strings.EqualFold(local, e.n.Data))
+}
+
+func (e element) Attr(space, local string) (string, bool) {
+	for _, a := range e.n.Attr {
+		if space == "" && a.Namespace == "" && strings.EqualFold(a.Key, local) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func (e element) Parent() selector.Element {
+	if e.n.Parent == nil || e.n.Parent.Type != html.ElementNode {
+		return nil
+	}
+	return element{e.n.Parent}
+}
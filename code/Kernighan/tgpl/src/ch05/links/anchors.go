@@ -0,0 +1,62 @@
+package links
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// An Anchor is a hyperlink found in an HTML document.
+type Anchor struct {
+	URL  string // absolute, including any fragment
+	Text string // anchor text, with spaces collapsed
+}
+
+// Anchors returns the <a href> links in the HTML document doc,
+// resolved relative to base, together with their text.
+// Unlike Extract, it leaves fetching and parsing the document
+// to the caller.
+func Anchors(doc *html.Node, base *url.URL) []Anchor {
+	var anchors []Anchor
+	forEachNode(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.Data != "a" {
+			return
+		}
+		for _, a := range n.Attr {
+			if a.Key != "href" {
+				continue
+			}
+			link, err := base.Parse(a.Val)
+			if err != nil {
+				continue // ignore bad URLs
+			}
+			var text []string
+			forEachNode(n, func(n *html.Node) {
+				if n.Type == html.TextNode {
+					text = append(text, strings.Fields(n.Data)...)
+				}
+			}, nil)
+			anchors = append(anchors, Anchor{link.String(), strings.Join(text, " ")})
+		}
+	}, nil)
+	return anchors
+}
+
+// IDs returns the set of fragment identifiers defined by doc:
+// the values of id attributes, and of name attributes of <a>
+// elements.
+func IDs(doc *html.Node) map[string]bool {
+	ids := make(map[string]bool)
+	forEachNode(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		for _, a := range n.Attr {
+			if a.Key == "id" || a.Key == "name" && n.Data == "a" {
+				ids[a.Val] = true
+			}
+		}
+	}, nil)
+	return ids
+}
@@ -0,0 +1,154 @@
+// Toposort2 analyzes a dependency graph read from a file, and runs
+// the commands of its nodes in dependency order.
+//
+// Usage:
+//
+//	toposort2 [-reduce] [-j n] command [file]
+//
+// The file, or the standard input if there is none, is in the format
+// of gopl.io/ch5/depgraph.Parse, for example:
+//
+//	"operating systems": "data structures" "computer organization"
+//	networks 3: "operating systems"
+//		echo studying networks
+//
+// The commands are:
+//
+//	order     print the nodes in a topological order, as toposort does
+//	levels    print the levels of nodes that can be processed in parallel
+//	cycles    print each cycle, and exit with status 1 if there are any
+//	critical  print the length of the critical path, and the path
+//	reduce    print the transitive reduction of the graph
+//	dot       print the graph in the DOT language of Graphviz
+//	mermaid   print the graph as a Mermaid flowchart
+//	run       run each node's commands with sh, up to -j nodes at a time,
+//	          level by level
+//
+// The -reduce flag makes dot and mermaid print the transitive reduction.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+
+	"gopl.io/ch5/depgraph"
+)
+
+var (
+	reduce   = flag.Bool("reduce", false, "with dot or mermaid, omit implied edges")
+	parallel = flag.Int("j", runtime.NumCPU(), "with run, the number of nodes to run at once")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		fmt.Fprintln(os.Stderr, "usage: toposort2 [-reduce] [-j n] order|levels|cycles|critical|reduce|dot|mermaid|run [file]")
+		os.Exit(2)
+	}
+	filename := "-"
+	if flag.NArg() == 2 {
+		filename = flag.Arg(1)
+	}
+	g, err := depgraph.Load(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "toposort2: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, g, flag.Arg(0), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "toposort2: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, g *depgraph.Graph, cmd string, w io.Writer) error {
+	if *reduce && (cmd == "dot" || cmd == "mermaid") {
+		r, err := g.TransitiveReduction()
+		if err != nil {
+			return err
+		}
+		g = r
+	}
+	switch cmd {
+	case "order":
+		order, err := g.TopoSort()
+		if err != nil {
+			return err
+		}
+		for i, node := range order {
+			fmt.Fprintf(w, "%d:\t%s\n", i+1, node)
+		}
+	case "levels":
+		levels, err := g.Levels()
+		if err != nil {
+			return err
+		}
+		for i, level := range levels {
+			fmt.Fprintf(w, "%d:\t%s\n", i, strings.Join(level, ", "))
+		}
+	case "cycles":
+		cycles := g.Cycles()
+		for _, c := range cycles {
+			fmt.Fprintln(w, c)
+		}
+		if cycles != nil {
+			return fmt.Errorf("%d cycles", len(cycles))
+		}
+	case "critical":
+		length, path, err := g.CriticalPath()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%g\t%s\n", length, strings.Join(path, " -> "))
+	case "reduce":
+		r, err := g.TransitiveReduction()
+		if err != nil {
+			return err
+		}
+		return r.Write(w)
+	case "dot":
+		return g.WriteDOT(w)
+	case "mermaid":
+		return g.WriteMermaid(w)
+	case "run":
+		var mu sync.Mutex // serializes output
+		return g.Run(ctx, *parallel, func(ctx context.Context, node string) error {
+			for _, command := range g.Commands[node] {
+				out, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+				mu.Lock()
+				fmt.Fprintf(w, "[%s] $ %s\n", node, command)
+				prefixLines(w, node, out)
+				mu.Unlock()
+				if err != nil {
+					return fmt.Errorf("%s: %v", command, err)
+				}
+			}
+			return nil
+		})
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+// prefixLines writes each line of out to w, prefixed by "[node] ".
+func prefixLines(w io.Writer, node string, out []byte) {
+	for _, line := range bytes.SplitAfter(out, []byte("\n")) {
+		if len(line) > 0 {
+			fmt.Fprintf(w, "[%s] %s", node, line)
+			if line[len(line)-1] != '\n' {
+				fmt.Fprintln(w)
+			}
+		}
+	}
+}
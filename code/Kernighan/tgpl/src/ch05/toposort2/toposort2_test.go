@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gopl.io/ch5/depgraph"
+)
+
+func TestRunCommands(t *testing.T) {
+	g, err := depgraph.Parse(strings.NewReader(`
+a:
+	echo one; echo two
+b: a
+	printf three
+c: b
+	false
+d: c
+	echo never
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = run(context.Background(), g, "run", &out)
+	if err == nil || !strings.Contains(err.Error(), "c: false: exit status 1") {
+		t.Errorf("err = %v, want failure of c", err)
+	}
+	want := `[a] $ echo one; echo two
+[a] one
+[a] two
+[b] $ printf three
+[b] three
+[c] $ false
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", &out, want)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	g, err := depgraph.Parse(strings.NewReader("a: b c\nb: c\nc:\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		cmd, want string
+	}{
+		{"order", "1:\tc\n2:\tb\n3:\ta\n"},
+		{"levels", "0:\tc\n1:\tb\n2:\ta\n"},
+		{"critical", "3\tc -> b -> a\n"},
+		{"reduce", "a: b\nb: c\nc:\n"},
+	} {
+		var out bytes.Buffer
+		if err := run(context.Background(), g, test.cmd, &out); err != nil {
+			t.Errorf("%s: %v", test.cmd, err)
+		} else if out.String() != test.want {
+			t.Errorf("%s: got %q, want %q", test.cmd, &out, test.want)
+		}
+	}
+	if err := run(context.Background(), g, "frob", new(bytes.Buffer)); err == nil {
+		t.Errorf("unknown command: no error")
+	}
+}
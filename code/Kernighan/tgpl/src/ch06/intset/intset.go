@@ -9,6 +9,9 @@ package intset
 import (
 	"bytes"
 	"fmt"
+	"iter"
+	"math"
+	"math/bits"
 )
 
 //!+intset
@@ -71,3 +74,170 @@ func (s *IntSet) String() string {
 }
 
 //!-string
+
+// Remove removes x from the set.
+func (s *IntSet) Remove(x int) {
+	word, bit := x/64, uint(x%64)
+	if x >= 0 && word < len(s.words) {
+		s.words[word] &^= 1 << bit
+		s.trim()
+	}
+}
+
+// Clear removes all elements from the set.
+func (s *IntSet) Clear() {
+	s.words = nil
+}
+
+// Len returns the number of elements in the set.
+func (s *IntSet) Len() int {
+	n := 0
+	for _, word := range s.words {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Copy returns a copy of the set.
+func (s *IntSet) Copy() *IntSet {
+	return &IntSet{words: append([]uint64(nil), s.words...)}
+}
+
+// AddAll adds the non-negative values to the set.
+func (s *IntSet) AddAll(vals ...int) {
+	for _, x := range vals {
+		s.Add(x)
+	}
+}
+
+// IntersectWith sets s to the intersection of s and t.
+func (s *IntSet) IntersectWith(t *IntSet) {
+	if len(s.words) > len(t.words) {
+		s.words = s.words[:len(t.words)]
+	}
+	for i := range s.words {
+		s.words[i] &= t.words[i]
+	}
+	s.trim()
+}
+
+// DifferenceWith sets s to the difference of s and t,
+// the elements of s that are not in t.
+func (s *IntSet) DifferenceWith(t *IntSet) {
+	for i := range s.words {
+		if i < len(t.words) {
+			s.words[i] &^= t.words[i]
+		}
+	}
+	s.trim()
+}
+
+// SymmetricDifference sets s to the symmetric difference of s and t,
+// the elements that are in one set but not both.
+func (s *IntSet) SymmetricDifference(t *IntSet) {
+	for i, tword := range t.words {
+		if i < len(s.words) {
+			s.words[i] ^= tword
+		} else {
+			s.words = append(s.words, tword)
+		}
+	}
+	s.trim()
+}
+
+// Elems returns the elements of the set in increasing order.
+func (s *IntSet) Elems() []int {
+	elems := make([]int, 0, s.Len())
+	for x := range s.All() {
+		elems = append(elems, x)
+	}
+	return elems
+}
+
+// All returns an iterator over the elements of the set
+// in increasing order:
+//
+//	for x := range s.All() { ... }
+func (s *IntSet) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i, word := range s.words {
+			for word != 0 {
+				j := bits.TrailingZeros64(word)
+				if !yield(64*i + j) {
+					return
+				}
+				word &= word - 1 // clear the lowest bit
+			}
+		}
+	}
+}
+
+// Min returns the smallest element of the set,
+// or false if it is empty.
+func (s *IntSet) Min() (int, bool) {
+	return s.NextAfter(-1)
+}
+
+// Max returns the largest element of the set,
+// or false if it is empty.
+func (s *IntSet) Max() (int, bool) {
+	for i := len(s.words) - 1; i >= 0; i-- {
+		if word := s.words[i]; word != 0 {
+			return 64*i + 63 - bits.LeadingZeros64(word), true
+		}
+	}
+	return 0, false
+}
+
+// NextAfter returns the smallest element of the set greater
+// than x, or false if there is none.
+func (s *IntSet) NextAfter(x int) (int, bool) {
+	if x == math.MaxInt {
+		return 0, false // x+1 would overflow
+	}
+	if x < -1 {
+		x = -1
+	}
+	x++
+	i := x / 64
+	if i >= len(s.words) {
+		return 0, false
+	}
+	// Ignore the bits of the first word below x.
+	if word := s.words[i] >> uint(x%64); word != 0 {
+		return x + bits.TrailingZeros64(word), true
+	}
+	for i++; i < len(s.words); i++ {
+		if word := s.words[i]; word != 0 {
+			return 64*i + bits.TrailingZeros64(word), true
+		}
+	}
+	return 0, false
+}
+
+// trim removes trailing zero words, so that Max
+// and the set operations need not skip over them.
+func (s *IntSet) trim() {
+	i := len(s.words)
+	for i > 0 && s.words[i-1] == 0 {
+		i--
+	}
+	s.words = s.words[:i]
+}
+
+// A Set is a set of non-negative integers. It is satisfied by
+// *IntSet, which suits small, dense sets, and by the compressed
+// *Bitmap of gopl.io/ch6/roaring, which suits large, sparse ones.
+type Set interface {
+	Has(x int) bool
+	Add(x int)
+	Remove(x int)
+	Clear()
+	Len() int
+	Min() (int, bool)
+	Max() (int, bool)
+	All() iter.Seq[int]
+	String() string
+}
+
+var _ Set = (*IntSet)(nil)
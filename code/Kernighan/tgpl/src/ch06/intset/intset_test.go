@@ -3,7 +3,14 @@
 
 package intset
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
 
 func Example_one() {
 	//!+main
@@ -48,3 +55,292 @@ func Example_two() {
 	// {1 9 42 144}
 	// {[4398046511618 0 65536]}
 }
+
+func Example_iteration() {
+	var x IntSet
+	x.AddAll(3, 1, 4, 159, 26, 5)
+	for e := range x.All() {
+		if e > 10 {
+			break
+		}
+		fmt.Println(e)
+	}
+	min, _ := x.Min()
+	max, _ := x.Max()
+	next, _ := x.NextAfter(5)
+	fmt.Println(x.Len(), min, max, next)
+
+	// Output:
+	// 1
+	// 3
+	// 4
+	// 5
+	// 6 1 159 26
+}
+
+// A mapSet is the obvious representation of a set, against
+// which the IntSet is tested and benchmarked.
+type mapSet map[int]bool
+
+func (m mapSet) elems() []int {
+	var elems []int
+	for x := range m {
+		elems = append(elems, x)
+	}
+	sort.Ints(elems)
+	return elems
+}
+
+func randSet(rng *rand.Rand, n, max int) (*IntSet, mapSet) {
+	s, m := new(IntSet), make(mapSet)
+	for i := 0; i < n; i++ {
+		x := rng.Intn(max)
+		s.Add(x)
+		m[x] = true
+	}
+	return s, m
+}
+
+// check reports whether s is a well-formed set with the elements of m.
+func check(t *testing.T, what string, s *IntSet, m mapSet) {
+	t.Helper()
+	if got, want := s.Elems(), m.elems(); !slices.Equal(got, want) {
+		t.Fatalf("%s: got %v, want %v", what, got, want)
+	}
+	if s.Len() != len(m) {
+		t.Fatalf("%s: Len = %d, want %d", what, s.Len(), len(m))
+	}
+	if n := len(s.words); n > 0 && s.words[n-1] == 0 {
+		t.Fatalf("%s: trailing zero word", what)
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 200; trial++ {
+		max := 1 + rng.Intn(1000)
+		a, ma := randSet(rng, rng.Intn(50), max)
+		b, mb := randSet(rng, rng.Intn(50), 1+rng.Intn(1000))
+
+		union, inter, diff, sym := make(mapSet), make(mapSet), make(mapSet), make(mapSet)
+		for x := range ma {
+			union[x] = true
+			if mb[x] {
+				inter[x] = true
+			} else {
+				diff[x] = true
+				sym[x] = true
+			}
+		}
+		for x := range mb {
+			union[x] = true
+			if !ma[x] {
+				sym[x] = true
+			}
+		}
+
+		s := a.Copy()
+		s.UnionWith(b)
+		check(t, "UnionWith", s, union)
+		s = a.Copy()
+		s.IntersectWith(b)
+		check(t, "IntersectWith", s, inter)
+		s = a.Copy()
+		s.DifferenceWith(b)
+		check(t, "DifferenceWith", s, diff)
+		s = a.Copy()
+		s.SymmetricDifference(b)
+		check(t, "SymmetricDifference", s, sym)
+		check(t, "Copy", a, ma) // a is unchanged
+
+		for x := range ma {
+			if rng.Intn(2) == 0 {
+				a.Remove(x)
+				delete(ma, x)
+			}
+		}
+		a.Remove(max + 1000) // absent
+		a.Remove(-1)
+		check(t, "Remove", a, ma)
+	}
+}
+
+func TestMinMaxNextAfter(t *testing.T) {
+	var s IntSet
+	if _, ok := s.Min(); ok {
+		t.Errorf("Min of empty set: ok")
+	}
+	if _, ok := s.Max(); ok {
+		t.Errorf("Max of empty set: ok")
+	}
+	s.AddAll(0, 63, 64, 200)
+	for _, test := range []struct {
+		after, next int
+		ok          bool
+	}{
+		{-5, 0, true},
+		{0, 63, true},
+		{62, 63, true},
+		{63, 64, true},
+		{64, 200, true},
+		{199, 200, true},
+		{200, 0, false},
+		{1000, 0, false},
+		{math.MaxInt, 0, false},
+		{math.MinInt, 0, true},
+	} {
+		next, ok := s.NextAfter(test.after)
+		if next != test.next || ok != test.ok {
+			t.Errorf("NextAfter(%d) = %d, %t, want %d, %t", test.after, next, ok, test.next, test.ok)
+		}
+	}
+	if min, _ := s.Min(); min != 0 {
+		t.Errorf("Min = %d, want 0", min)
+	}
+	if max, _ := s.Max(); max != 200 {
+		t.Errorf("Max = %d, want 200", max)
+	}
+
+	s.Remove(200)
+	if max, _ := s.Max(); max != 64 || len(s.words) != 2 {
+		t.Errorf("after Remove(200), Max = %d with %d words, want 64 with 2", max, len(s.words))
+	}
+	s.Clear()
+	if s.Len() != 0 || s.String() != "{}" {
+		t.Errorf("after Clear, set = %s", &s)
+	}
+}
+
+func TestAllStops(t *testing.T) {
+	var s IntSet
+	s.AddAll(1, 2, 3, 100)
+	var got []int
+	for x := range s.All() {
+		if x > 2 {
+			break
+		}
+		got = append(got, x)
+	}
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("got %v, want [1 2]", got)
+	}
+}
+
+// Benchmarks compare IntSet with mapSet on dense sets,
+// with every element below 1<<16, and sparse sets,
+// with 1<<10 elements below 1<<20.
+
+var benchInputs = []struct {
+	name   string
+	n, max int
+}{
+	{"dense", 1 << 16, 1 << 16},
+	{"sparse", 1 << 10, 1 << 20},
+}
+
+func benchValues(n, max int) []int {
+	rng := rand.New(rand.NewSource(1))
+	vals := make([]int, n)
+	for i := range vals {
+		vals[i] = rng.Intn(max)
+	}
+	return vals
+}
+
+func BenchmarkAdd(b *testing.B) {
+	for _, in := range benchInputs {
+		vals := benchValues(in.n, in.max)
+		b.Run(in.name+"/IntSet", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var s IntSet
+				s.AddAll(vals...)
+			}
+		})
+		b.Run(in.name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m := make(mapSet)
+				for _, x := range vals {
+					m[x] = true
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHas(b *testing.B) {
+	for _, in := range benchInputs {
+		vals := benchValues(in.n, in.max)
+		var s IntSet
+		s.AddAll(vals...)
+		m := make(mapSet)
+		for _, x := range vals {
+			m[x] = true
+		}
+		probes := benchValues(1024, in.max)
+		b.Run(in.name+"/IntSet", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.Has(probes[i%len(probes)])
+			}
+		})
+		b.Run(in.name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = m[probes[i%len(probes)]]
+			}
+		})
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	for _, in := range benchInputs {
+		var s, t IntSet
+		s.AddAll(benchValues(in.n, in.max)...)
+		t.AddAll(benchValues(in.n, in.max)...)
+		ms, mt := make(mapSet), make(mapSet)
+		for x := range s.All() {
+			ms[x] = true
+		}
+		for x := range t.All() {
+			mt[x] = true
+		}
+		b.Run(in.name+"/IntSet", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				u := s.Copy()
+				u.UnionWith(&t)
+			}
+		})
+		b.Run(in.name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				u := make(mapSet, len(ms))
+				for x := range ms {
+					u[x] = true
+				}
+				for x := range mt {
+					u[x] = true
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIterate(b *testing.B) {
+	for _, in := range benchInputs {
+		var s IntSet
+		s.AddAll(benchValues(in.n, in.max)...)
+		m := make(mapSet)
+		for x := range s.All() {
+			m[x] = true
+		}
+		b.Run(in.name+"/IntSet", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for range s.All() {
+				}
+			}
+		})
+		b.Run(in.name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for range m {
+				}
+			}
+		})
+	}
+}
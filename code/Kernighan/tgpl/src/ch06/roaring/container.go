@@ -0,0 +1,387 @@
+package roaring
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// A container holds the low 16 bits of the elements
+// that share the same high bits.
+//
+// Methods that change a container return the result, which
+// may be a different kind of container. Containers are never
+// shared between Bitmaps, so they may be changed in place.
+type container interface {
+	has(x uint16) bool
+	add(x uint16) container
+	remove(x uint16) container
+	card() int
+	min() uint16
+	max() uint16
+	each(yield func(uint16) bool) bool // reports whether it ran to completion
+	clone() container
+}
+
+// maxArray is the largest number of elements held by an array
+// container. Beyond it, a bitmap container is smaller.
+const maxArray = 4096
+
+// An array container is a sorted slice of elements.
+type array []uint16
+
+func (a array) search(x uint16) int {
+	return sort.Search(len(a), func(i int) bool { return a[i] >= x })
+}
+
+func (a array) has(x uint16) bool {
+	i := a.search(x)
+	return i < len(a) && a[i] == x
+}
+
+func (a array) add(x uint16) container {
+	i := a.search(x)
+	if i < len(a) && a[i] == x {
+		return a
+	}
+	if len(a) == maxArray {
+		return toBitmap(a).add(x)
+	}
+	a = append(a, 0)
+	copy(a[i+1:], a[i:])
+	a[i] = x
+	return a
+}
+
+func (a array) remove(x uint16) container {
+	i := a.search(x)
+	if i < len(a) && a[i] == x {
+		a = append(a[:i], a[i+1:]...)
+	}
+	return a
+}
+
+func (a array) card() int   { return len(a) }
+func (a array) min() uint16 { return a[0] }
+func (a array) max() uint16 { return a[len(a)-1] }
+
+func (a array) each(yield func(uint16) bool) bool {
+	for _, x := range a {
+		if !yield(x) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a array) clone() container { return append(array(nil), a...) }
+
+// A bitmap container is a bit vector of 1<<16 bits,
+// as in gopl.io/ch6/intset.
+type bitmap struct {
+	words [1 << 10]uint64
+	n     int // number of elements
+}
+
+func (b *bitmap) has(x uint16) bool {
+	return b.words[x/64]&(1<<(x%64)) != 0
+}
+
+func (b *bitmap) add(x uint16) container {
+	if !b.has(x) {
+		b.words[x/64] |= 1 << (x % 64)
+		b.n++
+	}
+	return b
+}
+
+func (b *bitmap) remove(x uint16) container {
+	if b.has(x) {
+		b.words[x/64] &^= 1 << (x % 64)
+		b.n--
+	}
+	return shrink(b)
+}
+
+func (b *bitmap) card() int { return b.n }
+
+func (b *bitmap) min() uint16 {
+	for i, w := range b.words {
+		if w != 0 {
+			return uint16(64*i + bits.TrailingZeros64(w))
+		}
+	}
+	panic("empty bitmap")
+}
+
+func (b *bitmap) max() uint16 {
+	for i := len(b.words) - 1; i >= 0; i-- {
+		if w := b.words[i]; w != 0 {
+			return uint16(64*i + 63 - bits.LeadingZeros64(w))
+		}
+	}
+	panic("empty bitmap")
+}
+
+func (b *bitmap) each(yield func(uint16) bool) bool {
+	for i, w := range b.words {
+		for w != 0 {
+			if !yield(uint16(64*i + bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
+func (b *bitmap) clone() container {
+	c := *b
+	return &c
+}
+
+// count recomputes b.n after a word-wise operation.
+func (b *bitmap) count() {
+	b.n = 0
+	for _, w := range b.words {
+		b.n += bits.OnesCount64(w)
+	}
+}
+
+// toBitmap returns a new bitmap container with the elements of c.
+func toBitmap(c container) *bitmap {
+	b := new(bitmap)
+	switch c := c.(type) {
+	case *bitmap:
+		*b = *c
+	case runs:
+		for _, r := range c {
+			for x := int(r.start); x <= int(r.last); x++ {
+				b.words[x/64] |= 1 << (x % 64)
+			}
+			b.n += r.len()
+		}
+	default:
+		c.each(func(x uint16) bool {
+			b.add(x)
+			return true
+		})
+	}
+	return b
+}
+
+// shrink returns b as an array container if that is smaller.
+func shrink(b *bitmap) container {
+	if b.n > maxArray {
+		return b
+	}
+	a := make(array, 0, b.n)
+	b.each(func(x uint16) bool {
+		a = append(a, x)
+		return true
+	})
+	return a
+}
+
+// A runs container is a sorted slice of runs of consecutive
+// elements, separated by gaps. Runs containers are made only
+// by Optimize, but they may then be changed like any other.
+type runs []run
+
+type run struct {
+	start, last uint16 // inclusive
+}
+
+func (r run) len() int { return int(r.last) - int(r.start) + 1 }
+
+// search returns the index of the first run that ends at or after x.
+func (rs runs) search(x uint16) int {
+	return sort.Search(len(rs), func(i int) bool { return rs[i].last >= x })
+}
+
+func (rs runs) has(x uint16) bool {
+	i := rs.search(x)
+	return i < len(rs) && rs[i].start <= x
+}
+
+func (rs runs) add(x uint16) container {
+	i := rs.search(x)
+	if i < len(rs) && rs[i].start <= x {
+		return rs // already present
+	}
+	// x lies in the gap between rs[i-1] and rs[i].
+	joinPrev := i > 0 && int(rs[i-1].last)+1 == int(x)
+	joinNext := i < len(rs) && int(x)+1 == int(rs[i].start)
+	switch {
+	case joinPrev && joinNext:
+		rs[i-1].last = rs[i].last
+		return append(rs[:i], rs[i+1:]...)
+	case joinPrev:
+		rs[i-1].last = x
+	case joinNext:
+		rs[i].start = x
+	default:
+		rs = append(rs, run{})
+		copy(rs[i+1:], rs[i:])
+		rs[i] = run{x, x}
+	}
+	return rs
+}
+
+func (rs runs) remove(x uint16) container {
+	i := rs.search(x)
+	if i == len(rs) || rs[i].start > x {
+		return rs // absent
+	}
+	r := rs[i]
+	switch {
+	case r.start == r.last:
+		return append(rs[:i], rs[i+1:]...)
+	case x == r.start:
+		rs[i].start++
+	case x == r.last:
+		rs[i].last--
+	default: // split
+		rs = append(rs, run{})
+		copy(rs[i+1:], rs[i:])
+		rs[i] = run{r.start, x - 1}
+		rs[i+1] = run{x + 1, r.last}
+	}
+	return rs
+}
+
+func (rs runs) card() int {
+	n := 0
+	for _, r := range rs {
+		n += r.len()
+	}
+	return n
+}
+
+func (rs runs) min() uint16 { return rs[0].start }
+func (rs runs) max() uint16 { return rs[len(rs)-1].last }
+
+func (rs runs) each(yield func(uint16) bool) bool {
+	for _, r := range rs {
+		for x := int(r.start); x <= int(r.last); x++ {
+			if !yield(uint16(x)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (rs runs) clone() container { return append(runs(nil), rs...) }
+
+// optimize returns the smallest representation of c.
+func optimize(c container) container {
+	var rs runs
+	c.each(func(x uint16) bool {
+		if n := len(rs); n > 0 && int(rs[n-1].last)+1 == int(x) {
+			rs[n-1].last = x
+		} else {
+			rs = append(rs, run{x, x})
+		}
+		return true
+	})
+	n := c.card()
+	runSize, arraySize, bitmapSize := 4*len(rs), 2*n, 8*len(bitmap{}.words)
+	switch {
+	case runSize < arraySize && runSize < bitmapSize:
+		return rs
+	case n <= maxArray:
+		if a, ok := c.(array); ok {
+			return a
+		}
+		return shrink(toBitmap(c))
+	default:
+		return toBitmap(c)
+	}
+}
+
+// union returns the union of a and b. It may change a but not b.
+func union(a, b container) container {
+	if x, ok := a.(array); ok {
+		if y, ok := b.(array); ok && len(x)+len(y) <= maxArray {
+			return merge(x, y)
+		}
+	}
+	r := toBitmap(a)
+	switch b := b.(type) {
+	case *bitmap:
+		for i := range r.words {
+			r.words[i] |= b.words[i]
+		}
+		r.count()
+	default:
+		b.each(func(x uint16) bool {
+			r.add(x)
+			return true
+		})
+	}
+	return shrink(r)
+}
+
+// merge returns the union of two arrays.
+func merge(x, y array) array {
+	z := make(array, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] < y[j]:
+			z = append(z, x[i])
+			i++
+		case x[i] > y[j]:
+			z = append(z, y[j])
+			j++
+		default:
+			z = append(z, x[i])
+			i++
+			j++
+		}
+	}
+	z = append(z, x[i:]...)
+	return append(z, y[j:]...)
+}
+
+// intersect returns the intersection of a and b.
+// It may change a but not b.
+func intersect(a, b container) container {
+	if _, ok := b.(array); ok {
+		a, b = b.clone(), a
+	}
+	if x, ok := a.(array); ok {
+		return filter(x, func(v uint16) bool { return b.has(v) })
+	}
+	r, s := toBitmap(a), toBitmap(b)
+	for i := range r.words {
+		r.words[i] &= s.words[i]
+	}
+	r.count()
+	return shrink(r)
+}
+
+// difference returns the elements of a that are not in b.
+// It may change a but not b.
+func difference(a, b container) container {
+	if x, ok := a.(array); ok {
+		return filter(x, func(v uint16) bool { return !b.has(v) })
+	}
+	r, s := toBitmap(a), toBitmap(b)
+	for i := range r.words {
+		r.words[i] &^= s.words[i]
+	}
+	r.count()
+	return shrink(r)
+}
+
+// filter returns, in place, the elements of a for which keep is true.
+func filter(a array, keep func(uint16) bool) array {
+	out := a[:0]
+	for _, x := range a {
+		if keep(x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package roaring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The binary encoding of a Bitmap, which is the same on all machines,
+// is the magic string "RBM1" followed by the number of containers as
+// a uvarint (see encoding/binary), and then each container in order:
+//
+//	key     uvarint; the difference from the previous key, if any
+//	kind    byte; 0 for array, 1 for bitmap, 2 for runs
+//	array   uvarint count n, then n uint16 elements, increasing
+//	bitmap  1024 uint64 words; bit i of word j is element 64*j+i
+//	runs    uvarint count n, then n pairs of uint16: first, last
+//
+// Fixed-size integers are little-endian.
+const magic = "RBM1"
+
+const (
+	kindArray byte = iota
+	kindBitmap
+	kindRuns
+)
+
+// MarshalBinary returns the binary encoding of b.
+func (b *Bitmap) MarshalBinary() ([]byte, error) {
+	data := []byte(magic)
+	data = binary.AppendUvarint(data, uint64(len(b.keys)))
+	var prev uint64
+	for i, c := range b.conts {
+		data = binary.AppendUvarint(data, b.keys[i]-prev)
+		prev = b.keys[i]
+		switch c := c.(type) {
+		case array:
+			data = append(data, kindArray)
+			data = binary.AppendUvarint(data, uint64(len(c)))
+			for _, x := range c {
+				data = binary.LittleEndian.AppendUint16(data, x)
+			}
+		case *bitmap:
+			data = append(data, kindBitmap)
+			for _, w := range c.words {
+				data = binary.LittleEndian.AppendUint64(data, w)
+			}
+		case runs:
+			data = append(data, kindRuns)
+			data = binary.AppendUvarint(data, uint64(len(c)))
+			for _, r := range c {
+				data = binary.LittleEndian.AppendUint16(data, r.start)
+				data = binary.LittleEndian.AppendUint16(data, r.last)
+			}
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary sets b to the set encoded in data,
+// which must be as produced by MarshalBinary.
+func (b *Bitmap) UnmarshalBinary(data []byte) error {
+	if len(data) < len(magic) || string(data[:len(magic)]) != magic {
+		return errors.New("roaring: not an encoded Bitmap")
+	}
+	d := decoder{data: data[len(magic):]}
+	var keys []uint64
+	var conts []container
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		delta := d.uvarint()
+		key := delta
+		if i > 0 {
+			key = keys[i-1] + delta
+			if delta == 0 || key < delta {
+				d.fail("keys out of order")
+			}
+		}
+		if key >= 1<<48 {
+			d.fail("key out of range")
+		}
+		c := d.container()
+		if d.err == nil {
+			keys, conts = append(keys, key), append(conts, c)
+		}
+	}
+	if d.err == nil && len(d.data) > 0 {
+		d.fail("trailing data")
+	}
+	if d.err != nil {
+		return d.err
+	}
+	b.keys, b.conts = keys, conts
+	return nil
+}
+
+// A decoder reads the parts of an encoded Bitmap,
+// recording the first error.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("roaring: invalid encoding: "+format, args...)
+	}
+	d.data = nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("bad uvarint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return x
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if n < 0 || len(d.data) < n {
+		d.fail("truncated")
+		return nil
+	}
+	p := d.data[:n]
+	d.data = d.data[n:]
+	return p
+}
+
+// count reads a uvarint count of items of the given size, no more than max.
+func (d *decoder) count(size, max int) int {
+	n := d.uvarint()
+	if n == 0 || n > uint64(max) || n*uint64(size) > uint64(len(d.data)) {
+		d.fail("bad count %d", n)
+		return 0
+	}
+	return int(n)
+}
+
+// container reads a container, checking that it is well formed.
+func (d *decoder) container() container {
+	kind := d.bytes(1)
+	if d.err != nil {
+		return nil
+	}
+	switch kind[0] {
+	case kindArray:
+		n := d.count(2, maxArray)
+		p := d.bytes(2 * n)
+		a := make(array, n)
+		for i := range a {
+			a[i] = binary.LittleEndian.Uint16(p[2*i:])
+			if i > 0 && a[i] <= a[i-1] {
+				d.fail("array out of order")
+			}
+		}
+		return a
+	case kindBitmap:
+		p := d.bytes(8 * len(bitmap{}.words))
+		if d.err != nil {
+			return nil
+		}
+		b := new(bitmap)
+		for i := range b.words {
+			b.words[i] = binary.LittleEndian.Uint64(p[8*i:])
+		}
+		b.count()
+		if b.n == 0 {
+			d.fail("empty bitmap")
+		}
+		return shrink(b)
+	case kindRuns:
+		n := d.count(4, 1<<15)
+		p := d.bytes(4 * n)
+		rs := make(runs, n)
+		for i := range rs {
+			rs[i].start = binary.LittleEndian.Uint16(p[4*i:])
+			rs[i].last = binary.LittleEndian.Uint16(p[4*i+2:])
+			if rs[i].last < rs[i].start || i > 0 && int(rs[i].start) <= int(rs[i-1].last)+1 {
+				d.fail("runs out of order")
+			}
+		}
+		return rs
+	}
+	d.fail("unknown container kind %d", kind[0])
+	return nil
+}
@@ -0,0 +1,273 @@
+// Package roaring provides a compressed set of integers,
+// after the Roaring bitmaps of Chambi, Lemire, Kaser and Godin.
+//
+// A Bitmap splits each element into its high 48 bits, the key, and its
+// low 16 bits, which are stored in a container for that key. A
+// container is a sorted array if it has at most 4096 elements, and a
+// bit vector of 1<<16 bits otherwise. Optimize replaces containers by
+// runs of consecutive elements where that saves space. So, unlike a
+// gopl.io/ch6/intset.IntSet, a Bitmap uses memory in proportion to the
+// number of its elements, not the size of the largest: a set of
+// {1, 1<<40} needs a few bytes, not a terabit.
+//
+// The methods whose names end in 64 accept any uint64 value, and so
+// any uint32 value too. The others satisfy gopl.io/ch6/intset.Set;
+// they ignore negative values, and do not see values beyond math.MaxInt.
+package roaring
+
+import (
+	"bytes"
+	"fmt"
+	"iter"
+	"math"
+	"sort"
+)
+
+// A Bitmap is a set of uint64 values.
+// Its zero value represents the empty set.
+type Bitmap struct {
+	keys  []uint64    // high 48 bits, in increasing order
+	conts []container // conts[i] holds the low 16 bits of keys[i]; never empty
+}
+
+func split(x uint64) (key uint64, low uint16) { return x >> 16, uint16(x) }
+
+// find returns the index of key in b.keys,
+// or where it would be inserted, and whether it is there.
+func (b *Bitmap) find(key uint64) (int, bool) {
+	i := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= key })
+	return i, i < len(b.keys) && b.keys[i] == key
+}
+
+// Has64 reports whether the set contains x.
+func (b *Bitmap) Has64(x uint64) bool {
+	key, low := split(x)
+	i, ok := b.find(key)
+	return ok && b.conts[i].has(low)
+}
+
+// Add64 adds x to the set.
+func (b *Bitmap) Add64(x uint64) {
+	key, low := split(x)
+	i, ok := b.find(key)
+	if ok {
+		b.conts[i] = b.conts[i].add(low)
+		return
+	}
+	b.keys = append(b.keys, 0)
+	copy(b.keys[i+1:], b.keys[i:])
+	b.keys[i] = key
+	b.conts = append(b.conts, nil)
+	copy(b.conts[i+1:], b.conts[i:])
+	b.conts[i] = array{low}
+}
+
+// Remove64 removes x from the set.
+func (b *Bitmap) Remove64(x uint64) {
+	key, low := split(x)
+	i, ok := b.find(key)
+	if !ok {
+		return
+	}
+	b.conts[i] = b.conts[i].remove(low)
+	if b.conts[i].card() == 0 {
+		b.delete(i)
+	}
+}
+
+// delete removes the ith container.
+func (b *Bitmap) delete(i int) {
+	b.keys = append(b.keys[:i], b.keys[i+1:]...)
+	b.conts = append(b.conts[:i], b.conts[i+1:]...)
+}
+
+// Min64 returns the smallest element of the set,
+// or false if it is empty.
+func (b *Bitmap) Min64() (uint64, bool) {
+	if len(b.keys) == 0 {
+		return 0, false
+	}
+	return b.keys[0]<<16 | uint64(b.conts[0].min()), true
+}
+
+// Max64 returns the largest element of the set,
+// or false if it is empty.
+func (b *Bitmap) Max64() (uint64, bool) {
+	n := len(b.keys)
+	if n == 0 {
+		return 0, false
+	}
+	return b.keys[n-1]<<16 | uint64(b.conts[n-1].max()), true
+}
+
+// All64 returns an iterator over the elements of the set
+// in increasing order.
+func (b *Bitmap) All64() iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		for i, c := range b.conts {
+			high := b.keys[i] << 16
+			if !c.each(func(low uint16) bool { return yield(high | uint64(low)) }) {
+				return
+			}
+		}
+	}
+}
+
+// Has reports whether the set contains the non-negative value x.
+func (b *Bitmap) Has(x int) bool { return x >= 0 && b.Has64(uint64(x)) }
+
+// Add adds the non-negative value x to the set.
+func (b *Bitmap) Add(x int) {
+	if x >= 0 {
+		b.Add64(uint64(x))
+	}
+}
+
+// Remove removes x from the set.
+func (b *Bitmap) Remove(x int) {
+	if x >= 0 {
+		b.Remove64(uint64(x))
+	}
+}
+
+// Min returns the smallest element of the set, or false if
+// it is empty or its elements are all beyond math.MaxInt.
+func (b *Bitmap) Min() (int, bool) {
+	x, ok := b.Min64()
+	if !ok || x > math.MaxInt {
+		return 0, false
+	}
+	return int(x), true
+}
+
+// Max returns the largest element of the set no greater
+// than math.MaxInt, or false if there is none.
+func (b *Bitmap) Max() (int, bool) {
+	x, ok := b.Max64()
+	if ok && x > math.MaxInt {
+		x, ok = 0, false
+		for y := range b.All() {
+			x, ok = uint64(y), true
+		}
+	}
+	return int(x), ok
+}
+
+// All returns an iterator over the elements of the set
+// no greater than math.MaxInt, in increasing order.
+func (b *Bitmap) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for x := range b.All64() {
+			if x > math.MaxInt || !yield(int(x)) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of elements in the set.
+func (b *Bitmap) Len() int {
+	n := 0
+	for _, c := range b.conts {
+		n += c.card()
+	}
+	return n
+}
+
+// Clear removes all elements from the set.
+func (b *Bitmap) Clear() {
+	b.keys, b.conts = nil, nil
+}
+
+// Clone returns a copy of the set.
+func (b *Bitmap) Clone() *Bitmap {
+	c := &Bitmap{
+		keys:  append([]uint64(nil), b.keys...),
+		conts: make([]container, len(b.conts)),
+	}
+	for i, cont := range b.conts {
+		c.conts[i] = cont.clone()
+	}
+	return c
+}
+
+// Optimize converts each container to its most compact form,
+// which for sets with long runs of consecutive elements may be
+// much smaller. It is worth calling after many additions.
+func (b *Bitmap) Optimize() {
+	for i, c := range b.conts {
+		b.conts[i] = optimize(c)
+	}
+}
+
+// UnionWith sets b to the union of b and t.
+func (b *Bitmap) UnionWith(t *Bitmap) {
+	var keys []uint64
+	var conts []container
+	i, j := 0, 0
+	for i < len(b.keys) || j < len(t.keys) {
+		switch {
+		case j == len(t.keys) || i < len(b.keys) && b.keys[i] < t.keys[j]:
+			keys, conts = append(keys, b.keys[i]), append(conts, b.conts[i])
+			i++
+		case i == len(b.keys) || t.keys[j] < b.keys[i]:
+			keys, conts = append(keys, t.keys[j]), append(conts, t.conts[j].clone())
+			j++
+		default:
+			keys, conts = append(keys, b.keys[i]), append(conts, union(b.conts[i], t.conts[j]))
+			i++
+			j++
+		}
+	}
+	b.keys, b.conts = keys, conts
+}
+
+// IntersectWith sets b to the intersection of b and t.
+func (b *Bitmap) IntersectWith(t *Bitmap) {
+	b.combine(t, intersect, false)
+}
+
+// DifferenceWith sets b to the difference of b and t,
+// the elements of b that are not in t.
+func (b *Bitmap) DifferenceWith(t *Bitmap) {
+	b.combine(t, difference, true)
+}
+
+// combine sets each container of b to op applied to it and the
+// container of t with the same key. Containers of b for which t
+// has no counterpart are kept if keep is true.
+func (b *Bitmap) combine(t *Bitmap, op func(a, b container) container, keep bool) {
+	if t == b {
+		t = b.Clone() // op may change its first operand in place
+	}
+	keys, conts := b.keys[:0], b.conts[:0]
+	for i, key := range b.keys {
+		c := b.conts[i]
+		if j, ok := t.find(key); ok {
+			c = op(c, t.conts[j])
+		} else if !keep {
+			continue
+		}
+		if c.card() > 0 {
+			keys, conts = append(keys, key), append(conts, c)
+		}
+	}
+	for i := len(conts); i < len(b.conts); i++ {
+		b.conts[i] = nil // allow garbage collection
+	}
+	b.keys, b.conts = keys, conts
+}
+
+// String returns the set as a string of the form "{1 2 3}".
+func (b *Bitmap) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for x := range b.All64() {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
@@ -0,0 +1,314 @@
+package roaring
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"gopl.io/ch6/intset"
+)
+
+var _ intset.Set = (*Bitmap)(nil)
+
+func Example() {
+	var b Bitmap
+	b.Add64(1)
+	b.Add64(1 << 40)
+	b.Add64(math.MaxUint64)
+	fmt.Println(&b, b.Len())
+	fmt.Println(b.Has64(1<<40), b.Has(2))
+	max, _ := b.Max()
+	fmt.Println(max) // the largest int-sized element
+
+	// Output:
+	// {1 1099511627776 18446744073709551615} 3
+	// true false
+	// 1099511627776
+}
+
+// check reports whether b is well formed and has the elements of s.
+func check(t *testing.T, what string, b *Bitmap, s *intset.IntSet) {
+	t.Helper()
+	if got, want := slices.Collect(b.All()), s.Elems(); !slices.Equal(got, want) {
+		t.Fatalf("%s: got %v, want %v", what, got, want)
+	}
+	if b.Len() != s.Len() {
+		t.Fatalf("%s: Len = %d, want %d", what, b.Len(), s.Len())
+	}
+	min, ok1 := b.Min()
+	wantMin, ok2 := s.Min()
+	max, ok3 := b.Max()
+	wantMax, ok4 := s.Max()
+	if min != wantMin || max != wantMax || ok1 != ok2 || ok3 != ok4 {
+		t.Fatalf("%s: Min, Max = %d, %d, want %d, %d", what, min, max, wantMin, wantMax)
+	}
+	for i, key := range b.keys {
+		if i > 0 && key <= b.keys[i-1] {
+			t.Fatalf("%s: keys out of order: %v", what, b.keys)
+		}
+		switch c := b.conts[i].(type) {
+		case array:
+			if len(c) == 0 || len(c) > maxArray || !slices.IsSorted(c) || len(slices.Compact(slices.Clone(c))) != len(c) {
+				t.Fatalf("%s: bad array container of %d elements", what, len(c))
+			}
+		case *bitmap:
+			n := c.n
+			c.count()
+			if n != c.n || n <= maxArray {
+				t.Fatalf("%s: bitmap container of %d elements says %d", what, c.n, n)
+			}
+		case runs:
+			for j, r := range c {
+				if r.last < r.start || j > 0 && int(r.start) <= int(c[j-1].last)+1 {
+					t.Fatalf("%s: bad runs %v", what, c)
+				}
+			}
+		}
+	}
+}
+
+// A pair is a Bitmap and an IntSet with the same elements.
+type pair struct {
+	b *Bitmap
+	s *intset.IntSet
+}
+
+// randValue returns a value that is likely to share containers,
+// and sometimes to fill one densely.
+func randValue(rng *rand.Rand) int {
+	key := rng.Intn(4)
+	if rng.Intn(2) == 0 {
+		return key<<16 | rng.Intn(1<<16)
+	}
+	return key<<16 | rng.Intn(6000)
+}
+
+func randPair(rng *rand.Rand, n int) pair {
+	p := pair{new(Bitmap), new(intset.IntSet)}
+	for i := 0; i < n; i++ {
+		x := randValue(rng)
+		if rng.Intn(4) == 0 {
+			// Add a run.
+			for j := 0; j < rng.Intn(5000) && x+j < 4<<16; j++ {
+				p.b.Add(x + j)
+				p.s.Add(x + j)
+			}
+		} else {
+			p.b.Add(x)
+			p.s.Add(x)
+		}
+	}
+	return p
+}
+
+// TestRandomOps applies random sequences of operations to Bitmaps and
+// to IntSets, and checks that they always have the same elements.
+func TestRandomOps(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		p := randPair(rng, rng.Intn(3000))
+		for step := 0; step < 25; step++ {
+			var what string
+			switch op := rng.Intn(10); op {
+			case 0, 1:
+				what = "Add"
+				for i := 0; i < 500; i++ {
+					x := randValue(rng)
+					p.b.Add(x)
+					p.s.Add(x)
+				}
+			case 2, 3:
+				what = "Remove"
+				for i := 0; i < 2000; i++ {
+					x := randValue(rng)
+					p.b.Remove(x)
+					p.s.Remove(x)
+				}
+			case 4:
+				what = "UnionWith"
+				q := randPair(rng, rng.Intn(3000))
+				q.b.Optimize()
+				p.b.UnionWith(q.b)
+				p.s.UnionWith(q.s)
+				check(t, what+" operand", q.b, q.s)
+			case 5:
+				what = "IntersectWith"
+				q := randPair(rng, 1000+rng.Intn(5000))
+				p.b.IntersectWith(q.b)
+				p.s.IntersectWith(q.s)
+			case 6:
+				what = "DifferenceWith"
+				q := randPair(rng, rng.Intn(1000))
+				p.b.DifferenceWith(q.b)
+				p.s.DifferenceWith(q.s)
+			case 7:
+				what = "Optimize"
+				p.b.Optimize()
+			case 8:
+				what = "Marshal"
+				data, err := p.b.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+				p.b = new(Bitmap)
+				if err := p.b.UnmarshalBinary(data); err != nil {
+					t.Fatal(err)
+				}
+			case 9:
+				what = "Clone"
+				c := p.b.Clone()
+				for x := range p.b.All() {
+					c.Remove(x)
+				}
+				c.Add(1 << 20)
+				check(t, what+" original", p.b, p.s)
+				p.b.UnionWith(p.b)
+				p.b.IntersectWith(p.b)
+				check(t, what+" self-union and intersection", p.b, p.s)
+				p.b.DifferenceWith(p.b)
+				p.s.Clear()
+			}
+			check(t, fmt.Sprintf("trial %d, step %d: %s", trial, step, what), p.b, p.s)
+			for i := 0; i < 100; i++ {
+				x := randValue(rng)
+				if p.b.Has(x) != p.s.Has(x) {
+					t.Fatalf("trial %d, step %d: Has(%d) = %t", trial, step, x, p.b.Has(x))
+				}
+			}
+		}
+	}
+}
+
+func TestWideValues(t *testing.T) {
+	values := []uint64{0, 1, 1 << 16, 1 << 32, 1<<32 + 1, 1 << 40, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64}
+	var b Bitmap
+	for _, x := range values {
+		b.Add64(x)
+	}
+	if got := slices.Collect(b.All64()); !slices.Equal(got, values) {
+		t.Errorf("All64 = %v, want %v", got, values)
+	}
+	if got, want := slices.Collect(b.All()), []int{0, 1, 1 << 16, 1 << 32, 1<<32 + 1, 1 << 40, math.MaxInt64}; !slices.Equal(got, want) {
+		t.Errorf("All = %v, want %v", got, want)
+	}
+	if max, _ := b.Max64(); max != math.MaxUint64 {
+		t.Errorf("Max64 = %d", max)
+	}
+	if max, _ := b.Max(); max != math.MaxInt64 {
+		t.Errorf("Max = %d", max)
+	}
+	if len(b.keys) != 7 {
+		t.Errorf("%d containers, want 7", len(b.keys))
+	}
+	b.Add(-1)
+	if b.Has(-1) || b.Len() != len(values) {
+		t.Errorf("Add(-1) changed the set")
+	}
+
+	var c Bitmap
+	for x := uint32(math.MaxUint32 - 10); x != 0; x++ {
+		c.Add64(uint64(x))
+	}
+	if c.Len() != 11 || !c.Has64(math.MaxUint32) || c.Has64(math.MaxUint32+1) {
+		t.Errorf("uint32 values: %v", &c)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	var b Bitmap
+	for x := 0; x < 100000; x++ {
+		b.Add(x)
+	}
+	b.Optimize()
+	for _, c := range b.conts {
+		if _, ok := c.(runs); !ok {
+			t.Fatalf("container of a full range is %T, want runs", c)
+		}
+	}
+	data, _ := b.MarshalBinary()
+	if len(data) > 32 {
+		t.Errorf("encoding of a range takes %d bytes", len(data))
+	}
+	// Punching holes in the runs splits them.
+	for x := 1; x < 100000; x += 1000 {
+		b.Remove(x)
+	}
+	if b.Has(1001) || !b.Has(1000) || !b.Has(1002) || b.Len() != 100000-100 {
+		t.Errorf("after removals, Len = %d", b.Len())
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var b Bitmap
+	b.Add64(1 << 40)
+	good, _ := b.MarshalBinary()
+	for _, data := range []string{
+		"",
+		"RBM2\x00",
+		"RBM1",
+		"RBM1\x01",
+		"RBM1\x01\x00\x03",                     // unknown kind
+		"RBM1\x01\x00\x00\x00",                 // empty array
+		"RBM1\x01\x00\x00\x02\x05\x00\x05\x00", // array out of order
+		"RBM1\x01\x00\x01\x00",                 // truncated bitmap
+		"RBM1\x01\x00\x02\x01\x05\x00\x04\x00", // run ends before it starts
+		"RBM1\x02\x00\x00\x01\x00\x00\x00\x00\x01\x00\x00", // repeated key
+		string(good) + "x",
+	} {
+		if err := b.UnmarshalBinary([]byte(data)); err == nil {
+			t.Errorf("UnmarshalBinary(%q) succeeded", data)
+		}
+	}
+	if b.Len() != 1 || !b.Has64(1<<40) {
+		t.Errorf("failed UnmarshalBinary changed the set to %v", &b)
+	}
+}
+
+// Benchmarks compare Bitmap with IntSet on sets of
+// 1<<14 random elements below 1<<24.
+
+func benchSets(newSet func() intset.Set) (intset.Set, intset.Set) {
+	rng := rand.New(rand.NewSource(1))
+	s, t := newSet(), newSet()
+	for i := 0; i < 1<<14; i++ {
+		s.Add(rng.Intn(1 << 24))
+		t.Add(rng.Intn(1 << 24))
+	}
+	return s, t
+}
+
+func BenchmarkUnion(b *testing.B) {
+	b.Run("Bitmap", func(b *testing.B) {
+		s, t := benchSets(func() intset.Set { return new(Bitmap) })
+		for i := 0; i < b.N; i++ {
+			u := s.(*Bitmap).Clone()
+			u.UnionWith(t.(*Bitmap))
+		}
+	})
+	b.Run("IntSet", func(b *testing.B) {
+		s, t := benchSets(func() intset.Set { return new(intset.IntSet) })
+		for i := 0; i < b.N; i++ {
+			u := s.(*intset.IntSet).Copy()
+			u.UnionWith(t.(*intset.IntSet))
+		}
+	})
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	b.Run("Bitmap", func(b *testing.B) {
+		s, t := benchSets(func() intset.Set { return new(Bitmap) })
+		for i := 0; i < b.N; i++ {
+			u := s.(*Bitmap).Clone()
+			u.IntersectWith(t.(*Bitmap))
+		}
+	})
+	b.Run("IntSet", func(b *testing.B) {
+		s, t := benchSets(func() intset.Set { return new(intset.IntSet) })
+		for i := 0; i < b.N; i++ {
+			u := s.(*intset.IntSet).Copy()
+			u.IntersectWith(t.(*intset.IntSet))
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package selector
+
+import "strings"
+
+// An Element is an element of a document tree, as seen by a selector.
+// How names compare, for example with or without regard to case, and
+// how namespace prefixes are resolved is up to the implementation.
+type Element interface {
+	// HasName reports whether the element is named local, or has
+	// any name if local is "*", in the namespace with prefix space,
+	// or in any namespace if space is "".
+	HasName(space, local string) bool
+
+	// Attr returns the value of the element's attribute named local in
+	// the namespace with prefix space, or in any namespace if space is
+	// "", and whether there is one.
+	Attr(space, local string) (value string, ok bool)
+
+	// Parent returns the parent element, or nil for the root.
+	Parent() Element
+}
+
+// Match reports whether e matches s.
+func (s Selector) Match(e Element) bool {
+	return s.match(len(s)-1, e)
+}
+
+// match reports whether s[:i+1] matches e and its ancestors,
+// with s[i] matching e.
+func (s Selector) match(i int, e Element) bool {
+	if !s[i].match(e) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if s[i].Comb == '>' {
+		p := e.Parent()
+		return p != nil && s.match(i-1, p)
+	}
+	for p := e.Parent(); p != nil; p = p.Parent() {
+		if s.match(i-1, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compound) match(e Element) bool {
+	if !e.HasName(c.Space, c.Local) {
+		return false
+	}
+	for _, a := range c.Attrs {
+		v, ok := e.Attr(a.Space, a.Local)
+		if !ok || !a.test(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// test reports whether the attribute value v passes a.
+func (a *Attr) test(v string) bool {
+	switch a.Op {
+	case "":
+		return true
+	case "=":
+		return v == a.Value
+	case "~=":
+		for _, word := range strings.Fields(v) {
+			if word == a.Value {
+				return true
+			}
+		}
+		return false
+	case "^=":
+		return strings.HasPrefix(v, a.Value)
+	case "$=":
+		return strings.HasSuffix(v, a.Value)
+	case "*=":
+		return strings.Contains(v, a.Value)
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+// Package selector parses and matches selectors resembling those of
+// CSS, such as "div#main > ul li.item a[href^=http]", against the
+// elements of any document tree that satisfies the Element interface.
+// It is used by gopl.io/ch7/xmlselect2 for XML and gopl.io/ch5/htmlq
+// for HTML.
+//
+// A selector is a sequence of compound selectors separated by white
+// space, meaning "descendant of", or by >, meaning "child of". A
+// compound selector is an optional element name, prefix:name or *,
+// followed by any number of #id, .class, [attr], and [attr op value]
+// tests, where op is = (equals), ~= (contains word), ^= (prefix),
+// $= (suffix), or *= (substring). A value may be quoted with ' or ",
+// and must be if it contains white space, ], or a comma. Pseudo-classes
+// such as :hover are not supported.
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Selector is a sequence of compound selectors, in which each
+// compound after the first is related to its predecessor by a
+// combinator.
+type Selector []Compound
+
+// A Compound selector tests one element.
+type Compound struct {
+	Comb  byte   // ' ' (descendant of) or '>' (child of) the previous compound
+	Space string // namespace prefix; "" => any namespace
+	Local string // "*" => any element
+	Attrs []Attr
+}
+
+// An Attr is an attribute test such as [lang=en] or .note,
+// which is short for [class~=note].
+type Attr struct {
+	Space, Local string // attribute name; Space is a prefix, as above
+	Op           string // "" (present), "=", "~=", "^=", "$=", "*="
+	Value        string
+}
+
+func (s Selector) String() string {
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 {
+			if c.Comb == '>' {
+				b.WriteString(" > ")
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		if c.Space != "" {
+			b.WriteString(c.Space + ":")
+		}
+		b.WriteString(c.Local)
+		for _, a := range c.Attrs {
+			b.WriteByte('[')
+			if a.Space != "" {
+				b.WriteString(a.Space + ":")
+			}
+			b.WriteString(a.Local)
+			if a.Op != "" {
+				fmt.Fprintf(&b, "%s%q", a.Op, a.Value)
+			}
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+// Parse parses a selector, such as "ns:book > title[lang=en]".
+func Parse(s string) (Selector, error) {
+	p := &parser{s: s}
+	sel, err := p.selector()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return sel, nil
+}
+
+// ParseList parses a comma-separated list of selectors.
+// Commas within quoted attribute values do not separate selectors.
+func ParseList(s string) ([]Selector, error) {
+	p := &parser{s: s}
+	var sels []Selector
+	for {
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		if p.eof() {
+			return sels, nil
+		}
+		p.i++ // skip ','
+	}
+}
+
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) eof() bool  { return p.i >= len(p.s) }
+func (p *parser) peek() byte { return p.s[p.i] }
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("selector %q, offset %d: %s", p.s, p.i, fmt.Sprintf(format, args...))
+}
+
+func isSpace(c byte) bool { return strings.IndexByte(" \t\n\r\f", c) >= 0 }
+
+func (p *parser) skipSpace() {
+	for !p.eof() && isSpace(p.peek()) {
+		p.i++
+	}
+}
+
+func isNameByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c >= 0x80
+}
+
+// selector parses a selector that ends at the end of the input
+// or at a comma.
+func (p *parser) selector() (Selector, error) {
+	var sel Selector
+	comb := byte(' ')
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ',' {
+			break
+		}
+		if p.peek() == '>' {
+			if len(sel) == 0 || comb == '>' {
+				return nil, p.errorf("misplaced >")
+			}
+			p.i++
+			comb = '>'
+			continue
+		}
+		c, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		c.Comb = comb
+		sel = append(sel, c)
+		comb = ' '
+	}
+	if len(sel) == 0 {
+		return nil, p.errorf("empty selector")
+	}
+	if comb == '>' {
+		return nil, p.errorf("selector ends with >")
+	}
+	return sel, nil
+}
+
+// ident parses a name, which may be empty.
+func (p *parser) ident() string {
+	start := p.i
+	for !p.eof() && isNameByte(p.peek()) {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// qname parses [prefix:]name, where name may be * if star is true.
+func (p *parser) qname(star bool) (space, local string) {
+	if star && !p.eof() && p.peek() == '*' {
+		p.i++
+		return "", "*"
+	}
+	local = p.ident()
+	if local != "" && !p.eof() && p.peek() == ':' {
+		p.i++
+		space = local
+		if star && !p.eof() && p.peek() == '*' {
+			p.i++
+			return space, "*"
+		}
+		local = p.ident()
+	}
+	return space, local
+}
+
+func (p *parser) compound() (Compound, error) {
+	var c Compound
+	c.Space, c.Local = p.qname(true)
+	if c.Space != "" && c.Local == "" {
+		return c, p.errorf("missing name after %s:", c.Space)
+	}
+	for !p.eof() {
+		switch p.peek() {
+		case '#', '.':
+			kind := p.peek()
+			p.i++
+			name := p.ident()
+			if name == "" {
+				return c, p.errorf("missing name after %c", kind)
+			}
+			if kind == '#' {
+				c.Attrs = append(c.Attrs, Attr{Local: "id", Op: "=", Value: name})
+			} else {
+				c.Attrs = append(c.Attrs, Attr{Local: "class", Op: "~=", Value: name})
+			}
+		case '[':
+			p.i++
+			a, err := p.attr()
+			if err != nil {
+				return c, err
+			}
+			c.Attrs = append(c.Attrs, a)
+		default:
+			if !isSpace(p.peek()) && strings.IndexByte(">,", p.peek()) < 0 {
+				return c, p.errorf("unexpected %q", p.peek())
+			}
+			if c.Local == "" {
+				c.Local = "*"
+			}
+			return c, nil
+		}
+	}
+	if c.Local == "" {
+		c.Local = "*"
+	}
+	return c, nil
+}
+
+// attr parses the rest of "[name op value]" after the "[".
+func (p *parser) attr() (Attr, error) {
+	var a Attr
+	p.skipSpace()
+	a.Space, a.Local = p.qname(false)
+	if a.Local == "" {
+		return a, p.errorf("missing attribute name")
+	}
+	p.skipSpace()
+	if p.eof() {
+		return a, p.errorf("missing ]")
+	}
+	if p.peek() == ']' {
+		p.i++
+		return a, nil
+	}
+	for _, op := range []string{"=", "~=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.i:], op) {
+			a.Op = op
+			p.i += len(op)
+			break
+		}
+	}
+	if a.Op == "" {
+		return a, p.errorf("unknown attribute operator")
+	}
+	p.skipSpace()
+	if !p.eof() && (p.peek() == '"' || p.peek() == '\'') {
+		quote := p.peek()
+		end := strings.IndexByte(p.s[p.i+1:], quote)
+		if end < 0 {
+			return a, p.errorf("unterminated string")
+		}
+		a.Value = p.s[p.i+1 : p.i+1+end]
+		p.i += end + 2
+	} else {
+		start := p.i
+		for !p.eof() && p.peek() != ']' && p.peek() != ',' && !isSpace(p.peek()) {
+			p.i++
+		}
+		a.Value = p.s[start:p.i]
+	}
+	p.skipSpace()
+	if p.eof() || p.peek() != ']' {
+		return a, p.errorf("missing ]")
+	}
+	p.i++
+	return a, nil
+}
@@ -0,0 +1,132 @@
+package selector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"div div h2", "div div h2"},
+		{"div\tdiv\nh2", "div div h2"},
+		{"div[id=page] p.note", `div[id="page"] p[class~="note"]`},
+		{"div>p", "div > p"},
+		{"#main > *", `*[id="main"] > *`},
+		{"a:entry > a:*", "a:entry > a:*"},
+		{`item[ x:lang ^= 'en' ][draft]`, `item[x:lang^="en"][draft]`},
+		{"item[\tlang\t=\ten\t]", `item[lang="en"]`},
+		{"[href$=.pdf]", `*[href$=".pdf"]`},
+		{`a[title="a, b"]`, `a[title="a, b"]`},
+		{`a[title='[x]'] b`, `a[title="[x]"] b`},
+	} {
+		sel, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.in, err)
+			continue
+		}
+		if got := sel.String(); got != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.in, got, test.want)
+		}
+	}
+	for _, bad := range []string{"", "> p", "div >", "div > > p", "p[id", "p[id=", "p[id!=x]", "p.",
+		"a:", "p[x='y]", "p, q", "a[title=a,b]", "p+q"} {
+		if sel, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) = %s, want error", bad, sel)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"p", "p"},
+		{"p, q > r,s", "p | q > r | s"},
+		{`a[title="a,b"], b[x='1,2']`, `a[title="a,b"] | b[x="1,2"]`},
+		{"a[title=x],b", `a[title="x"] | b`},
+	} {
+		sels, err := ParseList(test.in)
+		if err != nil {
+			t.Errorf("ParseList(%q): %v", test.in, err)
+			continue
+		}
+		var got []string
+		for _, sel := range sels {
+			got = append(got, sel.String())
+		}
+		if strings.Join(got, " | ") != test.want {
+			t.Errorf("ParseList(%q) = %q, want %s", test.in, got, test.want)
+		}
+	}
+	for _, bad := range []string{"", ",", "p,", ", p", "p,,q", `a[title="a,b]`} {
+		if sels, err := ParseList(bad); err == nil {
+			t.Errorf("ParseList(%q) = %v, want error", bad, sels)
+		}
+	}
+}
+
+// A node is an Element for testing, with case-sensitive names
+// and no namespaces.
+type node struct {
+	name   string
+	attr   map[string]string
+	parent *node
+}
+
+func (n *node) HasName(space, local string) bool {
+	return space == "" && (local == "*" || local == n.name)
+}
+
+func (n *node) Attr(space, local string) (string, bool) {
+	v, ok := n.attr[local]
+	return v, ok && space == ""
+}
+
+func (n *node) Parent() Element {
+	if n.parent == nil {
+		return nil // not a nil *node
+	}
+	return n.parent
+}
+
+func TestMatch(t *testing.T) {
+	html := &node{name: "html"}
+	div := &node{name: "div", attr: map[string]string{"id": "main", "class": "page wide"}, parent: html}
+	ul := &node{name: "ul", parent: div}
+	li := &node{name: "li", parent: ul}
+	a := &node{name: "a", attr: map[string]string{"href": "https://example.com/x.pdf", "title": "a, b"}, parent: li}
+
+	for _, test := range []struct {
+		sel  string
+		want bool
+	}{
+		{"a", true},
+		{"*", true},
+		{"html a", true},
+		{"div > ul > li > a", true},
+		{"div > a", false},
+		{"#main a", true},
+		{".wide a", true},
+		{".wid a", false},
+		{"div.page.wide li a", true},
+		{"div.page.narrow a", false},
+		{"a[href]", true},
+		{"a[lang]", false},
+		{"a[href^=https]", true},
+		{"a[href$=.pdf]", true},
+		{"a[href*=example]", true},
+		{"a[href=x]", false},
+		{`a[title="a, b"]`, true},
+		{"ul a", true},
+		{"a ul", false},
+		{"li > li > a", false},
+		{"x:a", false},
+	} {
+		sel, err := Parse(test.sel)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.sel, err)
+			continue
+		}
+		if got := sel.Match(a); got != test.want {
+			t.Errorf("%s matches a = %t, want %t", test.sel, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Sorting2 sorts the music playlist of gopl.io/ch7/sorting by a
+// sequence of column clicks, or serves it as an HTML table that
+// is sorted again when a column heading is clicked.
+//
+// Usage:
+//
+//	sorting2 [column ...]      # print, clicking each column in turn
+//	sorting2 -http :8000       # serve the playlist
+//
+// For example, "sorting2 Year Title" sorts by title, then by year.
+// Clicking the same column twice in a row reverses its order.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"gopl.io/ch7/table"
+)
+
+type Track struct {
+	Title  string
+	Artist string
+	Album  string
+	Year   int
+	Length time.Duration
+}
+
+var tracks = []*Track{
+	{"Go", "Delilah", "From the Roots Up", 2012, length("3m38s")},
+	{"Go", "Moby", "Moby", 1992, length("3m37s")},
+	{"Go Ahead", "Alicia Keys", "As I Am", 2007, length("4m36s")},
+	{"Ready 2 Go", "Martin Solveig", "Smash", 2011, length("4m24s")},
+}
+
+func length(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		panic(s)
+	}
+	return d
+}
+
+func main() {
+	addr := flag.String("http", "", "serve the playlist at `address`")
+	flag.Parse()
+	t, err := table.FromStruct(tracks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *addr != "" {
+		log.Fatal(http.ListenAndServe(*addr, t))
+	}
+	if err := run(t, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "sorting2: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run clicks each of the columns in turn, then prints the table.
+func run(t *table.Table[*Track], clicks []string, out io.Writer) error {
+	for _, col := range clicks {
+		if err := t.Click(col); err != nil {
+			return err
+		}
+	}
+	return t.WriteText(out)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"gopl.io/ch7/table"
+)
+
+func TestRun(t *testing.T) {
+	tab, err := table.FromStruct(tracks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run(tab, []string{"Year", "Title"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `Title       Artist          Album              Year  Length
+-----       ------          -----              ----  ------
+Go          Moby            Moby               1992  3m37s
+Go          Delilah         From the Roots Up  2012  3m38s
+Go Ahead    Alicia Keys     As I Am            2007  4m36s
+Ready 2 Go  Martin Solveig  Smash              2011  4m24s
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if err := run(tab, []string{"Genre"}, &out); err == nil {
+		t.Error("run succeeded with an unknown column")
+	}
+}
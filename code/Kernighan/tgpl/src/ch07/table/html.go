@@ -0,0 +1,132 @@
+package table
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ParseKeys parses a click history such as "Year,-Title",
+// most recent first, in which a "-" marks a descending key.
+func ParseKeys(s string) []Key {
+	var keys []Key
+	for _, f := range strings.Split(s, ",") {
+		if f == "" {
+			continue
+		}
+		k := Key{Column: f}
+		if strings.HasPrefix(f, "-") {
+			k = Key{Column: f[1:], Desc: true}
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// FormatKeys formats a click history in the form read by ParseKeys.
+func FormatKeys(keys []Key) string {
+	var fields []string
+	for _, k := range keys {
+		fields = append(fields, k.String())
+	}
+	return strings.Join(fields, ",")
+}
+
+var page = template.Must(template.New("table").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.8em; text-align: left; }
+th { border-bottom: 1px solid; }
+th a { color: inherit; text-decoration: none; }
+tr:nth-child(even) td { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<table>
+<tr>
+{{- range .Headers}}
+  <th><a href="?{{.Query}}">{{.Name}}</a>{{.Arrow}}</th>
+{{- end}}
+</tr>
+{{- range .Rows}}
+<tr>
+{{- range .}}
+  <td>{{.}}</td>
+{{- end}}
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+type header struct {
+	Name  string
+	Query template.URL // the query after a click on this column
+	Arrow string       // the column's order if it is a key
+}
+
+// ServeHTTP serves the rows as an HTML table.
+// The query parameter sort holds the click history, in the form read
+// by ParseKeys; each column heading is a link that adds a click on
+// that column to it. Without the parameter, the rows are served in
+// their current order. Each request sorts a copy of the rows, so it
+// does not change the table.
+func (t *Table[T]) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	keys := t.Keys()
+	rows := t.Rows()
+	if q := req.URL.Query(); q.Has("sort") {
+		keys = ParseKeys(q.Get("sort"))
+		for _, k := range keys {
+			if _, ok := t.byName[k.Column]; !ok {
+				http.Error(w, fmt.Sprintf("no column %q", k.Column), http.StatusBadRequest)
+				return
+			}
+		}
+		t.sort(rows, keys)
+	}
+
+	var data struct {
+		Title   string
+		Headers []header
+		Rows    [][]any
+	}
+	data.Title = FormatKeys(keys)
+	if data.Title == "" {
+		data.Title = "table"
+	}
+	for _, c := range t.cols {
+		h := header{
+			Name:  c.Name,
+			Query: template.URL("sort=" + url.QueryEscape(FormatKeys(click(keys, c.Name)))),
+		}
+		if i := slices.IndexFunc(keys, func(k Key) bool { return k.Column == c.Name }); i >= 0 {
+			h.Arrow = " ▲"
+			if keys[i].Desc {
+				h.Arrow = " ▼"
+			}
+			if i > 0 {
+				h.Arrow += fmt.Sprint(i + 1) // position among the keys
+			}
+		}
+		data.Headers = append(data.Headers, h)
+	}
+	for _, row := range rows {
+		var cells []any
+		for _, c := range t.cols {
+			cells = append(cells, c.Value(row))
+		}
+		data.Rows = append(data.Rows, cells)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
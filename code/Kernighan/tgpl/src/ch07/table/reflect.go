@@ -0,0 +1,103 @@
+package table
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// FromStruct returns a table of rows, which must be structs or
+// pointers to structs, with a column for each exported field.
+// A field's tag `table:"name"` renames its column, and `table:"-"`
+// omits it. Fields of numeric, string and boolean kinds, including
+// named types such as time.Duration, compare by value; time.Time
+// fields compare chronologically; other fields compare as formatted
+// by fmt.Sprint. A nil pointer comes before every struct.
+func FromStruct[T any](rows []T) (*Table[T], error) {
+	typ := reflect.TypeFor[T]()
+	ptr := typ.Kind() == reflect.Pointer
+	if ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("table: %s is not a struct type", reflect.TypeFor[T]())
+	}
+	var cols []Column[T]
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("table"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		index := f.Index
+		field := func(row T) (reflect.Value, bool) {
+			v := reflect.ValueOf(&row).Elem()
+			if ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+			v, err := v.FieldByIndexErr(index)
+			return v, err == nil
+		}
+		compare := compareFunc(f.Type)
+		cols = append(cols, Column[T]{
+			Name: name,
+			Value: func(row T) any {
+				if v, ok := field(row); ok {
+					return v.Interface()
+				}
+				return nil
+			},
+			Compare: func(x, y T) int {
+				vx, okx := field(x)
+				vy, oky := field(y)
+				if !okx || !oky {
+					return cmp.Compare(b2i(okx), b2i(oky))
+				}
+				return compare(vx, vy)
+			},
+		})
+	}
+	return New(rows, cols...), nil
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// compareFunc returns a function that compares values of type t.
+func compareFunc(t reflect.Type) func(x, y reflect.Value) int {
+	if t == timeType {
+		return func(x, y reflect.Value) int {
+			return x.Interface().(time.Time).Compare(y.Interface().(time.Time))
+		}
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(x, y reflect.Value) int { return cmp.Compare(x.Int(), y.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(x, y reflect.Value) int { return cmp.Compare(x.Uint(), y.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		return func(x, y reflect.Value) int { return cmp.Compare(x.Float(), y.Float()) }
+	case reflect.String:
+		return func(x, y reflect.Value) int { return cmp.Compare(x.String(), y.String()) }
+	case reflect.Bool:
+		return func(x, y reflect.Value) int { return cmp.Compare(b2i(x.Bool()), b2i(y.Bool())) }
+	}
+	return func(x, y reflect.Value) int {
+		return cmp.Compare(fmt.Sprint(x.Interface()), fmt.Sprint(y.Interface()))
+	}
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
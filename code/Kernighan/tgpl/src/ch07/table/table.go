@@ -0,0 +1,182 @@
+// Package table sorts slices of records by several columns at once,
+// as a user does by clicking on column headings (exercises 7.8 and 7.9).
+//
+// A Table remembers which columns were clicked, most recent first:
+// the most recent is the primary sort key, the one before it breaks
+// ties, and so on. Clicking the primary column again reverses it.
+// The columns are defined by accessor functions, or found by
+// reflection on the fields of a struct type:
+//
+//	t, err := table.FromStruct(tracks)
+//	t.Click("Year")
+//	t.Click("Title") // by title, then year
+//	t.WriteText(os.Stdout)
+//
+// A Table is also an http.Handler that serves the records as an HTML
+// table whose column headings are links that sort it again on the server.
+package table
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// A Column is a named column of a table of records of type T.
+type Column[T any] struct {
+	Name    string
+	Value   func(T) any      // the value shown in the column
+	Compare func(x, y T) int // orders records by the column
+}
+
+// A Key is a column by which records are sorted.
+type Key struct {
+	Column string
+	Desc   bool // descending
+}
+
+func (k Key) String() string {
+	if k.Desc {
+		return "-" + k.Column
+	}
+	return k.Column
+}
+
+// A Table is a sorted slice of records of type T.
+// It is safe for concurrent use.
+type Table[T any] struct {
+	cols   []Column[T]
+	byName map[string]int
+
+	mu   sync.Mutex // guards the following
+	rows []T
+	keys []Key // click history, most recent first
+}
+
+// New returns a table of the specified rows and columns.
+// It sorts rows in place as columns are clicked.
+func New[T any](rows []T, cols ...Column[T]) *Table[T] {
+	t := &Table[T]{cols: cols, byName: make(map[string]int), rows: rows}
+	for i, c := range cols {
+		t.byName[c.Name] = i
+	}
+	return t
+}
+
+// Columns returns the names of the columns.
+func (t *Table[T]) Columns() []string {
+	var names []string
+	for _, c := range t.cols {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// Click makes the named column the primary sort key and re-sorts the
+// rows. If it is already the primary key, Click reverses its order.
+func (t *Table[T]) Click(name string) error {
+	if _, ok := t.byName[name]; !ok {
+		return fmt.Errorf("table: no column %q", name)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = click(t.keys, name)
+	t.sort(t.rows, t.keys)
+	return nil
+}
+
+// click returns the key history after a click on the named column.
+func click(keys []Key, name string) []Key {
+	if len(keys) > 0 && keys[0].Column == name {
+		keys = slices.Clone(keys)
+		keys[0].Desc = !keys[0].Desc
+		return keys
+	}
+	result := []Key{{Column: name}}
+	for _, k := range keys {
+		if k.Column != name {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// SetKeys sets the click history and re-sorts the rows.
+func (t *Table[T]) SetKeys(keys []Key) error {
+	for _, k := range keys {
+		if _, ok := t.byName[k.Column]; !ok {
+			return fmt.Errorf("table: no column %q", k.Column)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = slices.Clone(keys)
+	t.sort(t.rows, t.keys)
+	return nil
+}
+
+// Keys returns the click history, most recent first.
+func (t *Table[T]) Keys() []Key {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.keys)
+}
+
+// Rows returns a copy of the rows in their current order.
+func (t *Table[T]) Rows() []T {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.rows)
+}
+
+// sort sorts rows by keys. The sort is stable, so rows equal in
+// every key stay in their previous order.
+func (t *Table[T]) sort(rows []T, keys []Key) {
+	slices.SortStableFunc(rows, func(x, y T) int {
+		for _, k := range keys {
+			c := t.cols[t.byName[k.Column]].Compare(x, y)
+			if k.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// WriteText writes the rows as a table aligned with tabwriter,
+// as printTracks does in gopl.io/ch7/sorting.
+func (t *Table[T]) WriteText(w io.Writer) error {
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 2, ' ', 0)
+	var names, rules []string
+	for _, c := range t.cols {
+		names = append(names, c.Name)
+		rules = append(rules, strings.Repeat("-", len(c.Name)))
+	}
+	fmt.Fprintln(tw, strings.Join(names, "\t"))
+	fmt.Fprintln(tw, strings.Join(rules, "\t"))
+	for _, row := range t.Rows() {
+		var cells []string
+		for _, c := range t.cols {
+			cells = append(cells, fmt.Sprint(c.Value(row)))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Field returns a column that shows and compares the values
+// returned by field, which must be ordered.
+func Field[T any, V cmp.Ordered](name string, field func(T) V) Column[T] {
+	return Column[T]{
+		Name:    name,
+		Value:   func(x T) any { return field(x) },
+		Compare: func(x, y T) int { return cmp.Compare(field(x), field(y)) },
+	}
+}
@@ -0,0 +1,220 @@
+package table_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopl.io/ch7/table"
+)
+
+type track struct {
+	Title  string
+	Artist string
+	Year   int
+	Length time.Duration
+	Notes  string    `table:"-"`
+	Added  time.Time `table:"Date added"`
+}
+
+func tracks() []*track {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	return []*track{
+		{"Go", "Delilah", 2012, 218 * time.Second, "", day(3)},
+		{"Go", "Moby", 1992, 217 * time.Second, "", day(1)},
+		{"Go Ahead", "Alicia Keys", 2007, 276 * time.Second, "", day(4)},
+		{"Ready 2 Go", "Martin Solveig", 2011, 264 * time.Second, "", day(2)},
+	}
+}
+
+func artists(rows []*track) []string {
+	var names []string
+	for _, r := range rows {
+		names = append(names, r.Artist)
+	}
+	return names
+}
+
+func TestClick(t *testing.T) {
+	for _, test := range []struct {
+		clicks []string
+		want   []string // artists
+		keys   string
+	}{
+		{nil, []string{"Delilah", "Moby", "Alicia Keys", "Martin Solveig"}, ""},
+		{[]string{"Year"}, []string{"Moby", "Alicia Keys", "Martin Solveig", "Delilah"}, "Year"},
+		{[]string{"Year", "Year"}, []string{"Delilah", "Martin Solveig", "Alicia Keys", "Moby"}, "-Year"},
+		{[]string{"Year", "Title"}, []string{"Moby", "Delilah", "Alicia Keys", "Martin Solveig"}, "Title,Year"},
+		{[]string{"Year", "Year", "Title"}, []string{"Delilah", "Moby", "Alicia Keys", "Martin Solveig"}, "Title,-Year"},
+		{[]string{"Year", "Title", "Title"}, []string{"Martin Solveig", "Alicia Keys", "Moby", "Delilah"}, "-Title,Year"},
+		{[]string{"Year", "Title", "Year"}, []string{"Moby", "Alicia Keys", "Martin Solveig", "Delilah"}, "Year,Title"},
+		{[]string{"Length", "Date added"}, []string{"Moby", "Martin Solveig", "Delilah", "Alicia Keys"}, "Date added,Length"},
+	} {
+		tab, err := table.FromStruct(tracks())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range test.clicks {
+			if err := tab.Click(c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := artists(tab.Rows()); !slices.Equal(got, test.want) {
+			t.Errorf("clicks %q: got %q, want %q", test.clicks, got, test.want)
+		}
+		if got := table.FormatKeys(tab.Keys()); got != test.keys {
+			t.Errorf("clicks %q: keys %q, want %q", test.clicks, got, test.keys)
+		}
+	}
+}
+
+func TestClickUnknown(t *testing.T) {
+	tab, _ := table.FromStruct(tracks())
+	if err := tab.Click("Notes"); err == nil {
+		t.Error(`Click("Notes") succeeded for an omitted field`)
+	}
+}
+
+// TestStable checks that the order of rows equal in every key
+// is the order before the click.
+func TestStable(t *testing.T) {
+	rows := tracks()
+	tab := table.New(rows, table.Field("Title", func(t *track) string { return t.Title }))
+	tab.Click("Title")
+	if got, want := artists(tab.Rows()), []string{"Delilah", "Moby", "Alicia Keys", "Martin Solveig"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	tab.Click("Title")
+	if got, want := artists(tab.Rows()), []string{"Martin Solveig", "Alicia Keys", "Delilah", "Moby"}; !slices.Equal(got, want) {
+		t.Errorf("reversed: got %q, want %q", got, want)
+	}
+}
+
+func TestFromStruct(t *testing.T) {
+	type inner struct{ Rank int }
+	type row struct {
+		*inner
+		Name    string
+		private int
+		OK      bool
+		Tags    []string
+	}
+	rows := []row{
+		{&inner{2}, "b", 0, true, []string{"y"}},
+		{nil, "a", 0, false, []string{"z"}},
+		{&inner{1}, "c", 0, true, []string{"x"}},
+	}
+	tab, err := table.FromStruct(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tab.Columns(), []string{"Rank", "Name", "OK", "Tags"}; !slices.Equal(got, want) {
+		t.Fatalf("Columns() = %q, want %q", got, want)
+	}
+	names := func() string {
+		var s string
+		for _, r := range tab.Rows() {
+			s += r.Name
+		}
+		return s
+	}
+	for _, test := range []struct {
+		keys string
+		want string
+	}{
+		{"Rank", "acb"}, // the nil embedded pointer comes first
+		{"-Rank", "bca"},
+		{"OK,-Name", "acb"},
+		{"Tags", "cba"},
+	} {
+		if err := tab.SetKeys(table.ParseKeys(test.keys)); err != nil {
+			t.Fatal(err)
+		}
+		if got := names(); got != test.want {
+			t.Errorf("keys %q: got %s, want %s", test.keys, got, test.want)
+		}
+	}
+
+	if _, err := table.FromStruct([]int{1, 2}); err == nil {
+		t.Error("FromStruct([]int) succeeded")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got := table.ParseKeys("Year,-Title,,Artist")
+	want := []table.Key{{"Year", false}, {"Title", true}, {"Artist", false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseKeys = %v, want %v", got, want)
+	}
+	if s := table.FormatKeys(got); s != "Year,-Title,Artist" {
+		t.Errorf("FormatKeys = %q", s)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	tab, _ := table.FromStruct(tracks())
+	get := func(query string) (int, string) {
+		rec := httptest.NewRecorder()
+		tab.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+query, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := get("sort=Year,-Title")
+	if code != 200 {
+		t.Fatalf("status %d", code)
+	}
+	// Rows in order of year.
+	if i, j := strings.Index(body, "Moby"), strings.Index(body, "Delilah"); i < 0 || j < 0 || i > j {
+		t.Errorf("Moby (1992) not before Delilah (2012)")
+	}
+	// Clicking Year again reverses it; clicking Title makes it primary.
+	for _, link := range []string{
+		`href="?sort=-Year%2C-Title"`,
+		`href="?sort=Title%2CYear"`,
+		`href="?sort=Artist%2CYear%2C-Title"`,
+	} {
+		if !strings.Contains(body, link) {
+			t.Errorf("no link %s in\n%s", link, body)
+		}
+	}
+	if !strings.Contains(body, "Title</a> ▼2") {
+		t.Errorf("Title heading not marked as secondary descending key")
+	}
+	// Serving does not change the table.
+	if keys := tab.Keys(); len(keys) != 0 {
+		t.Errorf("ServeHTTP changed keys to %v", keys)
+	}
+
+	if code, _ := get("sort=Nope"); code != 400 {
+		t.Errorf("unknown column: status %d, want 400", code)
+	}
+}
+
+func Example() {
+	type city struct {
+		Name       string
+		Country    string
+		Population int `table:"Pop."`
+	}
+	t, _ := table.FromStruct([]city{
+		{"Lyon", "France", 522},
+		{"Porto", "Portugal", 232},
+		{"Paris", "France", 2103},
+		{"Lisbon", "Portugal", 545},
+	})
+	t.Click("Pop.")
+	t.Click("Pop.")
+	t.Click("Country") // by country, then by descending population
+	t.WriteText(os.Stdout)
+	// Output:
+	// Name    Country   Pop.
+	// ----    -------   ----
+	// Paris   France    2103
+	// Lyon    France    522
+	// Lisbon  Portugal  545
+	// Porto   Portugal  232
+}
@@ -0,0 +1,99 @@
+package units
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Quantities of fixed dimensions, in SI base units or bytes.
+type (
+	Temperature float64 // kelvins
+	Length      float64 // meters
+	Mass        float64 // kilograms
+	Volume      float64 // cubic meters
+	Speed       float64 // meters per second
+	Pressure    float64 // pascals
+	DataSize    float64 // bytes
+)
+
+func (Temperature) Dim() Dim { return TempDim }
+func (Length) Dim() Dim      { return LengthDim }
+func (Mass) Dim() Dim        { return MassDim }
+func (Volume) Dim() Dim      { return VolumeDim }
+func (Speed) Dim() Dim       { return SpeedDim }
+func (Pressure) Dim() Dim    { return PressureDim }
+func (DataSize) Dim() Dim    { return DataDim }
+
+func (x Temperature) String() string { return Of(x).String() }
+func (x Length) String() string      { return Of(x).String() }
+func (x Mass) String() string        { return Of(x).String() }
+func (x Volume) String() string      { return Of(x).String() }
+func (x Speed) String() string       { return Of(x).String() }
+func (x Pressure) String() string    { return Of(x).String() }
+func (x DataSize) String() string    { return Of(x).String() }
+
+// A Measure is a quantity of a fixed dimension.
+type Measure interface {
+	~float64
+	Dim() Dim
+}
+
+// Of returns x as a Quantity.
+func Of[T Measure](x T) Quantity {
+	return Quantity{float64(x), x.Dim()}
+}
+
+// As returns q as a T, if it has the dimension of a T.
+func As[T Measure](q Quantity) (T, error) {
+	var x T
+	if q.Dim != x.Dim() {
+		return 0, &DimensionError{"convert", q.Dim, x.Dim()}
+	}
+	return T(q.Value), nil
+}
+
+// ParseAs parses s, as Parse does, as a T.
+func ParseAs[T Measure](s string) (T, error) {
+	q, err := Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	return As[T](q)
+}
+
+// *measureFlag satisfies the flag.Value interface.
+type measureFlag[T Measure] struct{ p *T }
+
+func (f measureFlag[T]) String() string {
+	if f.p == nil {
+		return ""
+	}
+	return Of(*f.p).String()
+}
+
+func (f measureFlag[T]) Set(s string) error {
+	x, err := ParseAs[T](s)
+	if err != nil {
+		var zero T
+		return fmt.Errorf("invalid %s quantity %q: %v", zero.Dim(), s, err)
+	}
+	*f.p = x
+	return nil
+}
+
+// FlagVar defines a flag of type T in fs with the specified name,
+// default value, and usage, stored in *p. The flag argument must have
+// a quantity and a unit of the right dimension, e.g., "20.5km/h" for
+// a Speed.
+func FlagVar[T Measure](fs *flag.FlagSet, p *T, name string, value T, usage string) {
+	*p = value
+	fs.Var(measureFlag[T]{p}, name, usage)
+}
+
+// Flag defines a command-line flag of type T, as FlagVar does, and
+// returns the address of the flag variable.
+func Flag[T Measure](name string, value T, usage string) *T {
+	p := new(T)
+	FlagVar(flag.CommandLine, p, name, value, usage)
+	return p
+}
@@ -0,0 +1,177 @@
+package units
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A unit converts values v in it to SI base units as (v + offset) * scale.
+type unit struct {
+	scale, offset float64
+	dim           Dim
+}
+
+const (
+	inch  = 0.0254
+	pound = 0.45359237
+	usGal = 231 * inch * inch * inch
+)
+
+var unitTable = map[string]unit{
+	// length
+	"m": {1, 0, LengthDim}, "km": {1e3, 0, LengthDim}, "cm": {1e-2, 0, LengthDim},
+	"mm": {1e-3, 0, LengthDim}, "µm": {1e-6, 0, LengthDim}, "um": {1e-6, 0, LengthDim},
+	"nm": {1e-9, 0, LengthDim}, "in": {inch, 0, LengthDim}, "ft": {12 * inch, 0, LengthDim},
+	"yd": {36 * inch, 0, LengthDim}, "mi": {63360 * inch, 0, LengthDim}, "nmi": {1852, 0, LengthDim},
+
+	// mass
+	"kg": {1, 0, MassDim}, "g": {1e-3, 0, MassDim}, "mg": {1e-6, 0, MassDim},
+	"t": {1e3, 0, MassDim}, "lb": {pound, 0, MassDim}, "oz": {pound / 16, 0, MassDim},
+
+	// time
+	"s": {1, 0, TimeDim}, "ms": {1e-3, 0, TimeDim}, "µs": {1e-6, 0, TimeDim},
+	"us": {1e-6, 0, TimeDim}, "ns": {1e-9, 0, TimeDim}, "min": {60, 0, TimeDim},
+	"h": {3600, 0, TimeDim}, "d": {86400, 0, TimeDim},
+
+	// temperature
+	"K": {1, 0, TempDim}, "C": {1, 273.15, TempDim}, "°C": {1, 273.15, TempDim},
+	"F": {5.0 / 9, 459.67, TempDim}, "°F": {5.0 / 9, 459.67, TempDim},
+
+	// volume
+	"L": {1e-3, 0, VolumeDim}, "l": {1e-3, 0, VolumeDim}, "mL": {1e-6, 0, VolumeDim},
+	"ml": {1e-6, 0, VolumeDim}, "gal": {usGal, 0, VolumeDim}, "qt": {usGal / 4, 0, VolumeDim},
+	"pt": {usGal / 8, 0, VolumeDim}, "floz": {usGal / 128, 0, VolumeDim},
+
+	// speed
+	"mph": {63360 * inch / 3600, 0, SpeedDim}, "kn": {1852.0 / 3600, 0, SpeedDim},
+
+	// pressure
+	"Pa": {1, 0, PressureDim}, "hPa": {1e2, 0, PressureDim}, "kPa": {1e3, 0, PressureDim},
+	"MPa": {1e6, 0, PressureDim}, "bar": {1e5, 0, PressureDim}, "mbar": {1e2, 0, PressureDim},
+	"atm": {101325, 0, PressureDim}, "psi": {pound * 9.80665 / (inch * inch), 0, PressureDim},
+	"mmHg": {133.322387415, 0, PressureDim},
+
+	// data size
+	"B": {1, 0, DataDim}, "bit": {1.0 / 8, 0, DataDim},
+	"kB": {1e3, 0, DataDim}, "KB": {1e3, 0, DataDim}, "MB": {1e6, 0, DataDim},
+	"GB": {1e9, 0, DataDim}, "TB": {1e12, 0, DataDim}, "PB": {1e15, 0, DataDim},
+	"KiB": {1 << 10, 0, DataDim}, "MiB": {1 << 20, 0, DataDim}, "GiB": {1 << 30, 0, DataDim},
+	"TiB": {1 << 40, 0, DataDim}, "PiB": {1 << 50, 0, DataDim},
+}
+
+// Parse parses a number followed by an optional unit, such as
+// "20.5km/h", "-40 °F", "9.81m/s^2", "2 m³" or "3GiB". Units may be
+// combined with *, · and /, and raised to powers with ^, ² and ³, but
+// Celsius and Fahrenheit, which do not start from zero, stand alone.
+func Parse(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	n := numberLen(s)
+	v, err := strconv.ParseFloat(s[:n], 64)
+	if err != nil {
+		return Quantity{}, fmt.Errorf("units: bad number in %q", s)
+	}
+	u, err := parseUnit(s[n:])
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{(v + u.offset) * u.scale, u.dim}, nil
+}
+
+// numberLen returns the length of the floating-point number
+// at the start of s.
+func numberLen(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for i < len(s) && ('0' <= s[i] && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	// An exponent must have digits, to allow units that start with e.
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && '0' <= s[j] && s[j] <= '9' {
+			for i = j; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			}
+		}
+	}
+	return i
+}
+
+// maxExp is the largest exponent, positive or negative, allowed in
+// a unit, both after ^ and in the dimension of the whole unit.
+// It keeps exponents well within the range of Dim's int8s.
+const maxExp = 9
+
+// parseUnit parses a unit expression such as "kg*m/s^2".
+// The empty string is the unit of dimensionless numbers.
+func parseUnit(s string) (unit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return unit{1, 0, None}, nil
+	}
+	if u, ok := unitTable[s]; ok {
+		return u, nil
+	}
+	result := unit{1, 0, None}
+	for i, term := range strings.Split(s, "/") {
+		for _, factor := range strings.FieldsFunc(term, func(r rune) bool { return r == '*' || r == '·' }) {
+			name, exp, err := splitPower(strings.TrimSpace(factor))
+			if err != nil {
+				return unit{}, fmt.Errorf("units: %v in %q", err, s)
+			}
+			u, ok := unitTable[name]
+			if !ok {
+				return unit{}, fmt.Errorf("units: unknown unit %q in %q", name, s)
+			}
+			if u.offset != 0 {
+				return unit{}, fmt.Errorf("units: %s cannot be combined with other units in %q", name, s)
+			}
+			if i > 0 {
+				exp = -exp
+			}
+			for ; exp > 0; exp-- {
+				result.scale *= u.scale
+				result.dim = result.dim.mul(u.dim)
+			}
+			for ; exp < 0; exp++ {
+				result.scale /= u.scale
+				result.dim = result.dim.mul(u.dim.pow(-1))
+			}
+			for _, e := range result.dim {
+				if e > maxExp || e < -maxExp {
+					return unit{}, fmt.Errorf("units: exponent out of range in %q", s)
+				}
+			}
+		}
+		if strings.TrimSpace(term) == "" {
+			return unit{}, fmt.Errorf("units: missing unit in %q", s)
+		}
+	}
+	return result, nil
+}
+
+// splitPower splits a factor such as "m^2" or "m³" into a
+// unit name and an exponent, which must be nonzero and at most
+// maxExp in magnitude.
+func splitPower(s string) (string, int, error) {
+	if i := strings.IndexByte(s, '^'); i >= 0 {
+		exp, err := strconv.Atoi(s[i+1:])
+		if err != nil || exp == 0 || exp > maxExp || exp < -maxExp {
+			return "", 0, fmt.Errorf("bad exponent %q", s[i+1:])
+		}
+		return s[:i], exp, nil
+	}
+	switch r, size := utf8.DecodeLastRuneInString(s); r {
+	case '²':
+		return s[:len(s)-size], 2, nil
+	case '³':
+		return s[:len(s)-size], 3, nil
+	}
+	return s, 1, nil
+}
@@ -0,0 +1,195 @@
+// Package units performs computations on physical quantities
+// such as lengths, speeds and data sizes, checking their dimensions.
+//
+// A Quantity is a value in SI base units (meters, kilograms, seconds,
+// kelvins) or bytes, together with its dimension. Parse reads
+// quantities such as "20.5km/h", "-40°F" or "3GiB"; In converts
+// them to any unit of the same dimension. Sums of quantities of
+// different dimensions are errors, but products and quotients
+// have new dimensions:
+//
+//	d, _ := units.Parse("100km")
+//	t, _ := units.Parse("1.5h")
+//	v := units.Div(d, t)
+//	mph, _ := v.In("mph") // 41.42...
+//
+// The types Temperature, Length, Mass, Volume, Speed, Pressure and
+// DataSize are quantities of a fixed dimension. Any of them can be a
+// command-line flag, as Celsius can in gopl.io/ch7/tempconv.
+package units
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Dim is the dimension of a quantity: the exponents of its base units.
+type Dim [5]int8
+
+const (
+	lengthExp = iota
+	massExp
+	timeExp
+	tempExp
+	dataExp
+)
+
+// The dimensions of common quantities.
+var (
+	None        = Dim{}
+	LengthDim   = Dim{lengthExp: 1}
+	MassDim     = Dim{massExp: 1}
+	TimeDim     = Dim{timeExp: 1}
+	TempDim     = Dim{tempExp: 1}
+	DataDim     = Dim{dataExp: 1}
+	AreaDim     = Dim{lengthExp: 2}
+	VolumeDim   = Dim{lengthExp: 3}
+	SpeedDim    = Dim{lengthExp: 1, timeExp: -1}
+	PressureDim = Dim{lengthExp: -1, massExp: 1, timeExp: -2}
+)
+
+var baseSymbols = [len(Dim{})]string{"m", "kg", "s", "K", "B"}
+
+// named gives the symbols used for dimensions that have one.
+var named = map[Dim]string{
+	None:                                    "",
+	AreaDim:                                 "m²",
+	VolumeDim:                               "m³",
+	SpeedDim:                                "m/s",
+	PressureDim:                             "Pa",
+	{lengthExp: 1, timeExp: -2}:             "m/s²",
+	{lengthExp: 1, massExp: 1, timeExp: -2}: "N",
+	{lengthExp: 2, massExp: 1, timeExp: -2}: "J",
+	{lengthExp: 2, massExp: 1, timeExp: -3}: "W",
+	{dataExp: 1, timeExp: -1}:               "B/s",
+}
+
+// String returns the symbol of the SI unit of dimension d,
+// such as "m/s" or "kg·m^-3".
+func (d Dim) String() string {
+	if s, ok := named[d]; ok {
+		return s
+	}
+	var parts []string
+	for i, exp := range d {
+		switch exp {
+		case 0:
+		case 1:
+			parts = append(parts, baseSymbols[i])
+		default:
+			parts = append(parts, fmt.Sprintf("%s^%d", baseSymbols[i], exp))
+		}
+	}
+	return strings.Join(parts, "·")
+}
+
+func (d Dim) mul(e Dim) Dim {
+	for i := range d {
+		d[i] += e[i]
+	}
+	return d
+}
+
+func (d Dim) pow(n int8) Dim {
+	for i := range d {
+		d[i] *= n
+	}
+	return d
+}
+
+// A Quantity is a value in SI base units, or bytes, and its dimension.
+type Quantity struct {
+	Value float64
+	Dim   Dim
+}
+
+// Q returns a quantity of the specified value and dimension.
+func Q(value float64, dim Dim) Quantity { return Quantity{value, dim} }
+
+// String formats q in SI units, such as "12.5m/s".
+func (q Quantity) String() string { return fmt.Sprintf("%g%s", q.Value, q.Dim) }
+
+// A DimensionError reports an operation
+// on quantities of incompatible dimensions.
+type DimensionError struct {
+	Op   string // e.g., "add" or "convert"
+	X, Y Dim
+}
+
+func (e *DimensionError) Error() string {
+	name := func(d Dim) string {
+		if d == None {
+			return "a number"
+		}
+		return d.String()
+	}
+	return fmt.Sprintf("units: cannot %s %s and %s", e.Op, name(e.X), name(e.Y))
+}
+
+// Add returns x+y, which must have the same dimension.
+func Add(x, y Quantity) (Quantity, error) {
+	if x.Dim != y.Dim {
+		return Quantity{}, &DimensionError{"add", x.Dim, y.Dim}
+	}
+	return Quantity{x.Value + y.Value, x.Dim}, nil
+}
+
+// Sub returns x-y, which must have the same dimension.
+func Sub(x, y Quantity) (Quantity, error) {
+	if x.Dim != y.Dim {
+		return Quantity{}, &DimensionError{"subtract", x.Dim, y.Dim}
+	}
+	return Quantity{x.Value - y.Value, x.Dim}, nil
+}
+
+// Compare returns -1, 0 or +1 as x is less than, equal to,
+// or greater than y, which must have the same dimension.
+func Compare(x, y Quantity) (int, error) {
+	if x.Dim != y.Dim {
+		return 0, &DimensionError{"compare", x.Dim, y.Dim}
+	}
+	switch {
+	case x.Value < y.Value:
+		return -1, nil
+	case x.Value > y.Value:
+		return +1, nil
+	}
+	return 0, nil
+}
+
+// Mul returns x*y.
+func Mul(x, y Quantity) Quantity {
+	return Quantity{x.Value * y.Value, x.Dim.mul(y.Dim)}
+}
+
+// Div returns x/y.
+func Div(x, y Quantity) Quantity {
+	return Quantity{x.Value / y.Value, x.Dim.mul(y.Dim.pow(-1))}
+}
+
+// Scale returns q multiplied by the number k.
+func (q Quantity) Scale(k float64) Quantity {
+	return Quantity{q.Value * k, q.Dim}
+}
+
+// In returns the value of q in the specified unit, such as
+// "km/h" or "°F", which must be of the same dimension.
+func (q Quantity) In(unit string) (float64, error) {
+	u, err := parseUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	if u.dim != q.Dim {
+		return 0, &DimensionError{"convert", q.Dim, u.dim}
+	}
+	return q.Value/u.scale - u.offset, nil
+}
+
+// Format returns q formatted in the specified unit, such as "3.2GiB".
+func (q Quantity) Format(unit string) (string, error) {
+	v, err := q.In(unit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%g%s", v, unit), nil
+}
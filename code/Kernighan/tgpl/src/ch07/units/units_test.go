@@ -0,0 +1,184 @@
+package units
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func Example() {
+	d, _ := Parse("100km")
+	t, _ := Parse("1.5h")
+	v := Div(d, t)
+	mph, _ := v.In("mph")
+	fmt.Printf("%s = %.2fmph\n", v, mph)
+
+	f, _ := Parse("-40°F")
+	c, _ := f.In("°C")
+	fmt.Printf("%s = %.1f°C\n", f, c)
+
+	_, err := Add(d, t)
+	fmt.Println(err)
+
+	// Output:
+	// 18.51851851851852m/s = 41.42mph
+	// 233.15K = -40.0°C
+	// units: cannot add m and s
+}
+
+func ExampleFlag() {
+	fs := flag.NewFlagSet("example", flag.ContinueOnError)
+	var limit Speed
+	FlagVar(fs, &limit, "limit", 0, "the speed `limit`")
+	quota := new(DataSize)
+	FlagVar(fs, quota, "quota", 1<<30, "the disk `quota`")
+	fs.Parse([]string{"-limit", "50mph", "-quota", "3GiB"})
+	kmh, _ := Of(limit).In("km/h")
+	fmt.Printf("%.1f km/h, %s\n", kmh, quota)
+
+	// Output:
+	// 80.5 km/h, 3.221225472e+09B
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		value float64
+		dim   Dim
+	}{
+		{"20.5km/h", 20.5 / 3.6, SpeedDim},
+		{"3GiB", 3 << 30, DataDim},
+		{"3 GB", 3e9, DataDim},
+		{"8bit", 1, DataDim},
+		{"100 °C", 373.15, TempDim},
+		{"32F", 273.15, TempDim},
+		{"0K", 0, TempDim},
+		{"1e3m", 1000, LengthDim},
+		{"-2.5e-3 km", -2.5, LengthDim},
+		{"12in", 0.3048, LengthDim},
+		{"1mi", 1609.344, LengthDim},
+		{"2lb", 0.90718474, MassDim},
+		{"1L", 1e-3, VolumeDim},
+		{"2 m³", 2, VolumeDim},
+		{"2m^3", 2, VolumeDim},
+		{"1 gal", 0.003785411784, VolumeDim},
+		{"1atm", 101325, PressureDim},
+		{"1 bar", 1e5, PressureDim},
+		{"1 kg/m/s^2", 1, PressureDim},
+		{"1 kg*m/s²", 1, Dim{lengthExp: 1, massExp: 1, timeExp: -2}},
+		{"9.81 m/s^2", 9.81, Dim{lengthExp: 1, timeExp: -2}},
+		{"42", 42, None},
+	} {
+		q, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.input, err)
+			continue
+		}
+		if math.Abs(q.Value-test.value) > 1e-9*math.Abs(test.value) || q.Dim != test.dim {
+			t.Errorf("Parse(%q) = %v %v, want %v %v", test.input, q.Value, q.Dim, test.value, test.dim)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"", "km", "1 furlong", "1 N/m²", "1 °C/s", "1 m/", "1 m^x", "1 m^0", "1.2.3m", "--1m",
+		"1 m^10", "1 m^200", "1 m^1000000000", "1 s^-10", "1 m^5*m^5", "1 m/s^5/s^5"} {
+		if q, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) = %v, want error", input, q)
+		}
+	}
+	// The largest exponents are allowed.
+	for _, input := range []string{"1 m^9", "1 m^-9", "1 m^9/s^9"} {
+		if _, err := Parse(input); err != nil {
+			t.Errorf("Parse(%q): %v", input, err)
+		}
+	}
+}
+
+func TestIn(t *testing.T) {
+	for _, test := range []struct {
+		input, unit string
+		want        float64
+	}{
+		{"1mi", "km", 1.609344},
+		{"100km/h", "m/s", 27.77777777777778},
+		{"1kn", "km/h", 1.852},
+		{"37°C", "°F", 98.6},
+		{"0K", "C", -273.15},
+		{"1GiB", "MB", 1073.741824},
+		{"1MB", "bit", 8e6},
+		{"1atm", "psi", 14.695948775513},
+		{"760mmHg", "kPa", 101.325},
+		{"1gal", "L", 3.785411784},
+	} {
+		q, err := Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.In(test.unit)
+		if err != nil {
+			t.Errorf("%s in %s: %v", test.input, test.unit, err)
+		} else if math.Abs(got-test.want) > 1e-6*math.Abs(test.want)+1e-9 {
+			t.Errorf("%s in %s = %g, want %g", test.input, test.unit, got, test.want)
+		}
+	}
+}
+
+func TestDimensionErrors(t *testing.T) {
+	m, _ := Parse("1m")
+	s, _ := Parse("1s")
+	kg, _ := Parse("1kg")
+	var derr *DimensionError
+	if _, err := Add(m, s); !errors.As(err, &derr) || derr.Op != "add" {
+		t.Errorf("Add(m, s): err = %v", err)
+	}
+	if _, err := Sub(kg, Q(1, None)); err == nil || err.Error() != "units: cannot subtract kg and a number" {
+		t.Errorf("Sub(kg, 1): err = %v", err)
+	}
+	if _, err := Compare(m, kg); err == nil {
+		t.Errorf("Compare(m, kg): no error")
+	}
+	if _, err := Div(m, s).In("kg"); err == nil || err.Error() != "units: cannot convert m/s and kg" {
+		t.Errorf("m/s in kg: err = %v", err)
+	}
+	if _, err := As[Speed](m); err == nil {
+		t.Errorf("As[Speed](1m): no error")
+	}
+
+	area := Mul(m, m)
+	if area.Dim != AreaDim || area.String() != "1m²" {
+		t.Errorf("m*m = %s", area)
+	}
+	density := Div(kg, Mul(area, m))
+	if got := density.String(); got != "1m^-3·kg" {
+		t.Errorf("kg/m³ = %s", got)
+	}
+	if c, err := Compare(m, m.Scale(2)); err != nil || c != -1 {
+		t.Errorf("Compare(1m, 2m) = %d, %v", c, err)
+	}
+}
+
+func TestFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(nopWriter))
+	var temp Temperature
+	FlagVar(fs, &temp, "temp", 293.15, "temperature")
+	if err := fs.Parse([]string{"-temp", "5kg"}); err == nil {
+		t.Errorf("-temp 5kg: no error")
+	}
+	if err := fs.Parse([]string{"-temp", "212F"}); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(temp)-373.15) > 1e-9 {
+		t.Errorf("-temp 212F = %v", temp)
+	}
+	if got := fs.Lookup("temp").DefValue; got != "293.15K" {
+		t.Errorf("default = %q", got)
+	}
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
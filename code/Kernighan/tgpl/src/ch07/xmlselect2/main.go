@@ -0,0 +1,184 @@
+// Xmlselect2 prints selected elements of an XML document.
+//
+// It generalizes gopl.io/ch7/xmlselect, whose arguments are a sequence
+// of element names, to selectors resembling those of CSS:
+//
+//	xmlselect2 'div[id=page] > p.note' <doc.xml
+//	xmlselect2 -ns a=http://www.w3.org/2005/Atom 'a:entry > a:title' <feed.xml
+//
+// See gopl.io/ch7/selector for the syntax. A namespace prefix in a selector
+// refers to a -ns flag or, failing that, to a prefix declared in the
+// document. Several selectors may be separated by commas.
+//
+// Like xmlselect, it reads the document as a stream of tokens and
+// never holds more of it in memory than the open elements and the
+// current match. Matches nested within another match are part of
+// it and are not reported separately. The -o flag chooses the output:
+//
+//	path	the text within each match, preceded by its element path, as xmlselect does
+//	text	the text content of each match on a line, with white space collapsed
+//	xml	each matched subtree as XML (encoding/xml chooses the namespace prefixes)
+//	json	each matched subtree as a JSON object on a line
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopl.io/ch7/selector"
+)
+
+var (
+	output = flag.String("o", "path", "output `mode`: path, text, xml or json")
+	prefix resolver
+)
+
+func init() {
+	prefix = make(resolver)
+	flag.Var(prefix, "ns", "bind namespace `prefix=uri` for use in selectors (repeatable)")
+}
+
+func (r resolver) String() string { return fmt.Sprint(map[string]string(r)) }
+
+func (r resolver) Set(s string) error {
+	p, uri, ok := strings.Cut(s, "=")
+	if !ok || p == "" {
+		return fmt.Errorf("want prefix=uri, got %q", s)
+	}
+	r[p] = uri
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: xmlselect2 [-o path|text|xml|json] [-ns prefix=uri] selector... <doc.xml")
+		os.Exit(2)
+	}
+	// For compatibility with xmlselect, the arguments
+	// are joined to make a single selector.
+	sels, err := selector.ParseList(strings.Join(flag.Args(), " "))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xmlselect2: %v\n", err)
+		os.Exit(2)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if err := selectXML(os.Stdin, out, sels, prefix, *output); err != nil {
+		out.Flush()
+		fmt.Fprintf(os.Stderr, "xmlselect2: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// A printer writes matches in one of the output modes.
+// For each match, it receives a call to begin, a call to token
+// for each token within the matched element, and a call to end.
+type printer interface {
+	begin(stack []*frame, start xml.StartElement) error
+	token(stack []*frame, tok xml.Token) error
+	end(tok xml.EndElement) error
+}
+
+// selectXML reads an XML document from r and writes to w
+// the elements that match any of sels, in the named mode.
+func selectXML(r io.Reader, w io.Writer, sels []selector.Selector, res resolver, mode string) error {
+	var p printer
+	switch mode {
+	case "path":
+		p = &pathPrinter{w: w}
+	case "text":
+		p = &textPrinter{w: w}
+	case "xml":
+		p = &xmlPrinter{w: w}
+	case "json":
+		p = &jsonPrinter{w: w}
+	default:
+		return fmt.Errorf("unknown output mode %q", mode)
+	}
+
+	dec := xml.NewDecoder(r)
+	var stack []*frame
+	matched := -1 // depth of the current match, or -1
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			f := &frame{name: tok.Name, attr: tok.Attr}
+			f.scope = scope(stack, tok.Attr)
+			stack = append(stack, f) // push
+			if matched >= 0 {
+				err = p.token(stack, tok)
+			} else if anyMatch(sels, stack, res) {
+				matched = len(stack) - 1
+				err = p.begin(stack, tok)
+			}
+		case xml.EndElement:
+			if matched == len(stack)-1 {
+				err = p.end(tok)
+				matched = -1
+			} else if matched >= 0 {
+				err = p.token(stack, tok)
+			}
+			stack = stack[:len(stack)-1] // pop
+		default:
+			if matched >= 0 {
+				err = p.token(stack, xml.CopyToken(tok))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func anyMatch(sels []selector.Selector, stack []*frame, res resolver) bool {
+	for _, sel := range sels {
+		if sel.Match(openElement{stack, res}) {
+			return true
+		}
+	}
+	return false
+}
+
+// scope returns the namespace prefixes in scope for an element with the
+// given attributes, sharing its parent's map if it declares none.
+func scope(stack []*frame, attrs []xml.Attr) map[string]string {
+	var parent map[string]string
+	if len(stack) > 0 {
+		parent = stack[len(stack)-1].scope
+	}
+	m := parent
+	copied := false
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" {
+			if !copied {
+				m = make(map[string]string)
+				for k, v := range parent {
+					m[k] = v
+				}
+				copied = true
+			}
+			m[a.Name.Local] = a.Value
+		}
+	}
+	return m
+}
+
+func path(stack []*frame) string {
+	names := make([]string, len(stack))
+	for i, f := range stack {
+		names[i] = f.name.Local
+	}
+	return strings.Join(names, " ")
+}
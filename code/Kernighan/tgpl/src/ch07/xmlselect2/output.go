@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A pathPrinter prints each piece of text within a match,
+// preceded by the names of its enclosing elements.
+type pathPrinter struct{ w io.Writer }
+
+func (p *pathPrinter) begin(stack []*frame, start xml.StartElement) error { return nil }
+func (p *pathPrinter) end(tok xml.EndElement) error                       { return nil }
+
+func (p *pathPrinter) token(stack []*frame, tok xml.Token) error {
+	if text, ok := tok.(xml.CharData); ok {
+		_, err := fmt.Fprintf(p.w, "%s: %s\n", path(stack), text)
+		return err
+	}
+	return nil
+}
+
+// A textPrinter prints the text content of each match on one line.
+type textPrinter struct {
+	w    io.Writer
+	text strings.Builder
+}
+
+func (p *textPrinter) begin(stack []*frame, start xml.StartElement) error {
+	p.text.Reset()
+	return nil
+}
+
+func (p *textPrinter) token(stack []*frame, tok xml.Token) error {
+	if text, ok := tok.(xml.CharData); ok {
+		p.text.Write(text)
+		p.text.WriteByte(' ')
+	}
+	return nil
+}
+
+func (p *textPrinter) end(tok xml.EndElement) error {
+	_, err := fmt.Fprintln(p.w, strings.Join(strings.Fields(p.text.String()), " "))
+	return err
+}
+
+// An xmlPrinter prints each match as XML.
+type xmlPrinter struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+func (p *xmlPrinter) begin(stack []*frame, start xml.StartElement) error {
+	p.enc = xml.NewEncoder(p.w)
+	return p.enc.EncodeToken(start)
+}
+
+func (p *xmlPrinter) token(stack []*frame, tok xml.Token) error {
+	switch tok.(type) {
+	case xml.ProcInst, xml.Directive:
+		return nil // not allowed within an element
+	}
+	return p.enc.EncodeToken(tok)
+}
+
+func (p *xmlPrinter) end(tok xml.EndElement) error {
+	if err := p.enc.EncodeToken(tok); err != nil {
+		return err
+	}
+	if err := p.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(p.w, "\n")
+	return err
+}
+
+// A jsonPrinter prints each match as a JSON object on one line.
+// It holds only the current match in memory.
+type jsonPrinter struct {
+	w     io.Writer
+	stack []*element // open elements within the match
+}
+
+// An element is the JSON form of an XML element.
+type element struct {
+	Name     string            `json:"name"`
+	Space    string            `json:"ns,omitempty"`
+	Attr     map[string]string `json:"attr,omitempty"`
+	Children []interface{}     `json:"children,omitempty"` // strings and *elements
+}
+
+func newElement(start xml.StartElement) *element {
+	e := &element{Name: start.Name.Local, Space: start.Name.Space}
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue // namespace declarations are reflected in Space
+		}
+		if e.Attr == nil {
+			e.Attr = make(map[string]string)
+		}
+		name := a.Name.Local
+		if a.Name.Space != "" {
+			name = a.Name.Space + " " + name
+		}
+		e.Attr[name] = a.Value
+	}
+	return e
+}
+
+func (p *jsonPrinter) begin(stack []*frame, start xml.StartElement) error {
+	p.stack = []*element{newElement(start)}
+	return nil
+}
+
+func (p *jsonPrinter) token(stack []*frame, tok xml.Token) error {
+	top := p.stack[len(p.stack)-1]
+	switch tok := tok.(type) {
+	case xml.StartElement:
+		e := newElement(tok)
+		top.Children = append(top.Children, e)
+		p.stack = append(p.stack, e)
+	case xml.EndElement:
+		p.stack = p.stack[:len(p.stack)-1]
+	case xml.CharData:
+		if text := strings.TrimSpace(string(tok)); text != "" {
+			top.Children = append(top.Children, text)
+		}
+	}
+	return nil
+}
+
+func (p *jsonPrinter) end(tok xml.EndElement) error {
+	data, err := json.Marshal(p.stack[0])
+	if err != nil {
+		return err
+	}
+	p.stack = nil
+	_, err = fmt.Fprintf(p.w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/xml"
+
+	"gopl.io/ch7/selector"
+)
+
+// A frame describes an open element.
+type frame struct {
+	name  xml.Name
+	attr  []xml.Attr
+	scope map[string]string // in-scope namespace prefixes, from xmlns:p attributes
+}
+
+// A resolver maps a namespace prefix in a selector to a URI,
+// given the element being tested.
+type resolver map[string]string // prefixes from the command line
+
+func (r resolver) uri(prefix string, f *frame) string {
+	if uri, ok := r[prefix]; ok {
+		return uri
+	}
+	if uri, ok := f.scope[prefix]; ok {
+		return uri
+	}
+	return prefix // undeclared; the decoder leaves it as is
+}
+
+// An openElement is the innermost element of a stack,
+// as seen by a selector.
+type openElement struct {
+	stack []*frame
+	r     resolver
+}
+
+func (e openElement) top() *frame { return e.stack[len(e.stack)-1] }
+
+func (e openElement) HasName(space, local string) bool {
+	f := e.top()
+	return (local == "*" || local == f.name.Local) &&
+		(space == "" || e.r.uri(space, f) == f.name.Space)
+}
+
+func (e openElement) Attr(space, local string) (string, bool) {
+	f := e.top()
+	for _, attr := range f.attr {
+		if attr.Name.Local == local && (space == "" || e.r.uri(space, f) == attr.Name.Space) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e openElement) Parent() selector.Element {
+	if len(e.stack) == 1 {
+		return nil
+	}
+	return openElement{e.stack[:len(e.stack)-1], e.r}
+}
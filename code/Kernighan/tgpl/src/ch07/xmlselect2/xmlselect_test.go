@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopl.io/ch7/selector"
+)
+
+const doc = `<?xml version="1.0"?>
+<html xmlns:x="urn:example">
+  <body>
+    <div id="page">
+      <h2>Title</h2>
+      <p class="note big">First <b>note</b></p>
+      <div><p class="note">Nested note</p></div>
+      <p>Plain</p>
+    </div>
+    <x:item x:lang="en-GB">Namespaced</x:item>
+    <item lang="fr">Not namespaced</item>
+  </body>
+</html>`
+
+func run(t *testing.T, mode string, res resolver, selectors string) string {
+	t.Helper()
+	sels, err := selector.ParseList(selectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := selectXML(strings.NewReader(doc), &out, sels, res, mode); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestSelectText(t *testing.T) {
+	for _, test := range []struct {
+		sel  string
+		res  resolver
+		want string
+	}{
+		{"div h2", nil, "Title\n"},
+		{"div[id=page] p.note", nil, "First note\nNested note\n"},
+		{"div[id=page] > p", nil, "First note\nPlain\n"},
+		{"#page > div > *", nil, "Nested note\n"},
+		{"p.note.big", nil, "First note\n"},
+		{"item", nil, "Namespaced\nNot namespaced\n"},
+		{"x:item", nil, "Namespaced\n"},                                        // prefix declared in document
+		{"y:item", resolver{"y": "urn:example"}, "Namespaced\n"},               // prefix from -ns
+		{"item[x:lang|=en]", nil, ""},                                          // |= is not an operator
+		{"item[x:lang^=en]", nil, "Namespaced\n"},                              // namespaced attribute
+		{"item[lang^=en], item[lang=fr]", nil, "Namespaced\nNot namespaced\n"}, // any namespace
+		{"div", nil, "Title First note Nested note Plain\n"},                   // nested div is not reported
+		{`p[class="note big"], item[lang="fr,en"]`, nil, "First note\n"},       // comma within quotes
+		{"div[id=page]\t>\tp", nil, "First note\nPlain\n"},                     // tabs separate
+	} {
+		sels, err := selector.ParseList(test.sel)
+		if err != nil {
+			if test.want != "" {
+				t.Errorf("%s: %v", test.sel, err)
+			}
+			continue
+		}
+		var out bytes.Buffer
+		if err := selectXML(strings.NewReader(doc), &out, sels, test.res, "text"); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.sel, got, test.want)
+		}
+	}
+}
+
+func TestOutputModes(t *testing.T) {
+	// The path mode prints what xmlselect would for "div div p".
+	if got, want := run(t, "path", nil, "div div p"), "html body div div p: Nested note\n"; got != want {
+		t.Errorf("path: got %q, want %q", got, want)
+	}
+
+	if got, want := run(t, "xml", nil, "p.big"), `<p class="note big">First <b>note</b></p>`+"\n"; got != want {
+		t.Errorf("xml: got %q, want %q", got, want)
+	}
+
+	want := `{"name":"p","attr":{"class":"note big"},"children":["First",{"name":"b","children":["note"]}]}` + "\n" +
+		`{"name":"item","ns":"urn:example","attr":{"urn:example lang":"en-GB"},"children":["Namespaced"]}` + "\n"
+	if got := run(t, "json", nil, "p.big, x:item"); got != want {
+		t.Errorf("json: got\n%s\nwant\n%s", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := selectXML(strings.NewReader(doc), &out, nil, nil, "yaml"); err == nil {
+		t.Errorf("unknown mode accepted")
+	}
+	if err := selectXML(strings.NewReader("<a><b></a>"), &out, nil, nil, "text"); err == nil {
+		t.Errorf("malformed document accepted")
+	}
+}
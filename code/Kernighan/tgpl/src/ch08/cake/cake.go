@@ -6,6 +6,10 @@
 // Package cake provides a simulation of
 // a concurrent cake shop with numerous parameters.
 //
+// A Shop has exactly three stages. A Simulation generalizes it to any
+// number of stages, each with its own workers, buffer and timing, and
+// reports throughput, utilization, queue lengths and latency.
+//
 // Use this command to run the benchmarks:
 // 	$ go test -bench=. gopl.io/ch8/cake
 package cake
@@ -4,6 +4,8 @@
 package cake_test
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,7 +13,6 @@ import (
 )
 
 var defaults = cake.Shop{
-	Verbose:      testing.Verbose(),
 	Cakes:        20,
 	BakeTime:     10 * time.Millisecond,
 	NumIcers:     1,
@@ -23,12 +24,14 @@ func Benchmark(b *testing.B) {
 	// Baseline: one baker, one icer, one inscriber.
 	// Each step takes exactly 10ms.  No buffers.
 	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
 	cakeshop.Work(b.N) // 224 ms
 }
 
 func BenchmarkBuffers(b *testing.B) {
 	// Adding buffers has no effect.
 	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
 	cakeshop.BakeBuf = 10
 	cakeshop.IceBuf = 10
 	cakeshop.Work(b.N) // 224 ms
@@ -38,6 +41,7 @@ func BenchmarkVariable(b *testing.B) {
 	// Adding variability to rate of each step
 	// increases total time due to channel delays.
 	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
 	cakeshop.BakeStdDev = cakeshop.BakeTime / 4
 	cakeshop.IceStdDev = cakeshop.IceTime / 4
 	cakeshop.InscribeStdDev = cakeshop.InscribeTime / 4
@@ -48,6 +52,7 @@ func BenchmarkVariableBuffers(b *testing.B) {
 	// Adding channel buffers reduces
 	// delays resulting from variability.
 	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
 	cakeshop.BakeStdDev = cakeshop.BakeTime / 4
 	cakeshop.IceStdDev = cakeshop.IceTime / 4
 	cakeshop.InscribeStdDev = cakeshop.InscribeTime / 4
@@ -60,6 +65,7 @@ func BenchmarkSlowIcing(b *testing.B) {
 	// Making the middle stage slower
 	// adds directly to the critical path.
 	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
 	cakeshop.IceTime = 50 * time.Millisecond
 	cakeshop.Work(b.N) // 1.032 s
 }
@@ -68,7 +74,87 @@ func BenchmarkSlowIcingManyIcers(b *testing.B) {
 	// Adding more icing cooks reduces the cost of icing
 	// to its sequential component, following Amdahl's Law.
 	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
 	cakeshop.IceTime = 50 * time.Millisecond
 	cakeshop.NumIcers = 5
 	cakeshop.Work(b.N) // 288ms
 }
+
+func TestSimulation(t *testing.T) {
+	// The middle stage is the bottleneck: 4ms per item, one worker.
+	sim := &cake.Simulation{
+		Items: 40,
+		Stages: []cake.Stage{
+			{Name: "fast", Workers: 1, Buf: 5, Time: 1 * time.Millisecond},
+			{Name: "slow", Workers: 1, Buf: 5, Time: 4 * time.Millisecond},
+			{Name: "last", Workers: 2, Time: 1 * time.Millisecond},
+		},
+	}
+	r := sim.Run()
+	if r.Items != 40 || len(r.Stages) != 3 {
+		t.Fatalf("report has %d items, %d stages", r.Items, len(r.Stages))
+	}
+	// Each item takes at least 4ms in the one slow worker,
+	// and at least 6ms in all.
+	if min := 40 * 4 * time.Millisecond; r.Elapsed < min || r.Stages[1].Busy < min {
+		t.Errorf("elapsed %v, slow stage busy %v; want at least %v", r.Elapsed, r.Stages[1].Busy, min)
+	}
+	for i, s := range r.Stages {
+		if s.Name != sim.Stages[i].Name || s.Workers != sim.Stages[i].Workers {
+			t.Errorf("stage %d is %s with %d workers", i, s.Name, s.Workers)
+		}
+		if s.Utilization <= 0 || s.Utilization > 1 {
+			t.Errorf("%s utilization %.2f, want in (0, 1]", s.Name, s.Utilization)
+		}
+	}
+	if l := r.Latency; !(6*time.Millisecond <= l.P50 && l.P50 <= l.P90 && l.P90 <= l.P99 && l.P99 <= l.Max) {
+		t.Errorf("latency percentiles out of order: %+v", l)
+	}
+
+	// Each buffer has a histogram with a bucket per length,
+	// and both are sampled the same number of times.
+	q0, q1 := r.Stages[0].Queue, r.Stages[1].Queue
+	if len(q0) != 6 || len(q1) != 6 || r.Stages[2].Queue != nil {
+		t.Fatalf("queue histograms %v %v %v", q0, q1, r.Stages[2].Queue)
+	}
+	if n0, n1 := sum(q0), sum(q1); n0 != n1 || n0 == 0 {
+		t.Errorf("queue histograms %v %v have %d and %d samples", q0, q1, n0, n1)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "slow") {
+		t.Errorf("report does not mention stage:\n%s", buf.String())
+	}
+}
+
+func sum(counts []int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+func TestShopSimulation(t *testing.T) {
+	shop := defaults
+	shop.NumIcers = 3
+	sim := shop.Simulation()
+	if len(sim.Stages) != 3 || sim.Stages[1].Workers != 3 || sim.Items != shop.Cakes {
+		t.Errorf("Shop.Simulation() = %+v", sim)
+	}
+}
+
+func BenchmarkSimulationSlowIcingManyIcers(b *testing.B) {
+	// As BenchmarkSlowIcingManyIcers, but reporting utilization too.
+	cakeshop := defaults
+	cakeshop.Verbose = testing.Verbose()
+	cakeshop.IceTime = 50 * time.Millisecond
+	cakeshop.NumIcers = 5
+	for i := 0; i < b.N; i++ {
+		r := cakeshop.Simulation().Run()
+		b.ReportMetric(r.Stages[1].Utilization, "icing-util")
+	}
+}
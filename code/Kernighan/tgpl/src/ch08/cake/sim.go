@@ -0,0 +1,239 @@
+package cake
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// A Stage is one step of a Simulation, such as baking or icing.
+type Stage struct {
+	Name    string
+	Workers int           // number of cooks doing this step; 0 => 1
+	Buf     int           // buffer slots between this step and the next
+	Time    time.Duration // mean time for one item
+	StdDev  time.Duration // standard deviation of the time for one item
+}
+
+// A Simulation is a pipeline of stages, each fed by the one before,
+// through which Items items pass.
+type Simulation struct {
+	Verbose bool
+	Items   int
+	Stages  []Stage
+	Sample  time.Duration // interval between queue length samples; 0 => 1ms
+}
+
+// A Report describes one run of a Simulation.
+type Report struct {
+	Items      int
+	Elapsed    time.Duration
+	Throughput float64     // items per second
+	Latency    Percentiles // time from start of first stage to end of last
+	Stages     []StageReport
+}
+
+// A StageReport describes the behavior of one stage during a run.
+type StageReport struct {
+	Name        string
+	Workers     int
+	Busy        time.Duration // total time spent working, over all workers
+	Utilization float64       // Busy / (Workers × Elapsed)
+
+	// Queue is a histogram of the number of items waiting in the
+	// buffer after this stage: Queue[n] is the number of samples in
+	// which n items were waiting. It is nil for the last stage.
+	Queue []int
+}
+
+// Percentiles summarizes a distribution of durations.
+type Percentiles struct {
+	P50, P90, P99, Max time.Duration
+}
+
+// An item is a unit of work passing through the pipeline.
+type item struct {
+	id    int
+	start time.Time // when the first stage began work on it
+}
+
+// Simulation returns the Simulation equivalent to s,
+// with stages for baking, icing and inscribing.
+func (s *Shop) Simulation() *Simulation {
+	return &Simulation{
+		Verbose: s.Verbose,
+		Items:   s.Cakes,
+		Stages: []Stage{
+			{"baking", 1, s.BakeBuf, s.BakeTime, s.BakeStdDev},
+			{"icing", s.NumIcers, s.IceBuf, s.IceTime, s.IceStdDev},
+			{"inscribing", 1, 0, s.InscribeTime, s.InscribeStdDev},
+		},
+	}
+}
+
+// Run runs the simulation once and reports on it.
+func (sim *Simulation) Run() *Report {
+	n := len(sim.Stages)
+	if n == 0 {
+		return &Report{Items: sim.Items}
+	}
+	// chans[i] connects stage i to stage i+1.
+	// chans[n-1] is the unbuffered output of the last stage.
+	chans := make([]chan item, n)
+	for i, st := range sim.Stages {
+		if i == n-1 {
+			chans[i] = make(chan item)
+		} else {
+			chans[i] = make(chan item, st.Buf)
+		}
+	}
+	busy := make([]time.Duration, n)
+	var mu sync.Mutex // guards busy
+
+	// Queue length sampler.
+	queues := make([][]int, n-1)
+	for i := range queues {
+		queues[i] = make([]int, cap(chans[i])+1)
+	}
+	interval := sim.Sample
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick.C:
+				for i := range queues {
+					queues[i][len(chans[i])]++
+				}
+			}
+		}
+	}()
+
+	begin := time.Now()
+
+	// Source: one pseudo-stage that hands item ids to the first stage.
+	src := make(chan item)
+	go func() {
+		for i := 0; i < sim.Items; i++ {
+			src <- item{id: i}
+		}
+		close(src)
+	}()
+
+	var in <-chan item = src
+	for i, st := range sim.Stages {
+		workers := st.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(i int, st Stage, in <-chan item, out chan<- item) {
+				defer wg.Done()
+				for it := range in {
+					t0 := time.Now()
+					if i == 0 {
+						it.start = t0
+					}
+					if sim.Verbose {
+						fmt.Println(st.Name, it.id)
+					}
+					work(st.Time, st.StdDev)
+					mu.Lock()
+					busy[i] += time.Since(t0)
+					mu.Unlock()
+					out <- it
+				}
+			}(i, st, in, chans[i])
+		}
+		go func(out chan item) {
+			wg.Wait()
+			close(out)
+		}(chans[i])
+		in = chans[i]
+	}
+
+	var latencies []time.Duration
+	for it := range in {
+		if sim.Verbose {
+			fmt.Println("finished", it.id)
+		}
+		latencies = append(latencies, time.Since(it.start))
+	}
+	elapsed := time.Since(begin)
+	close(done)
+	<-sampled
+
+	r := &Report{
+		Items:   sim.Items,
+		Elapsed: elapsed,
+		Latency: percentiles(latencies),
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(sim.Items) / elapsed.Seconds()
+	}
+	for i, st := range sim.Stages {
+		workers := st.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		sr := StageReport{Name: st.Name, Workers: workers, Busy: busy[i]}
+		if elapsed > 0 {
+			sr.Utilization = float64(busy[i]) / (float64(workers) * float64(elapsed))
+		}
+		if i < n-1 {
+			sr.Queue = queues[i]
+		}
+		r.Stages = append(r.Stages, sr)
+	}
+	return r
+}
+
+// percentiles returns the percentiles of the durations in d,
+// by the nearest-rank method.
+func percentiles(d []time.Duration) Percentiles {
+	if len(d) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(d)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return d[i]
+	}
+	return Percentiles{rank(0.50), rank(0.90), rank(0.99), d[len(d)-1]}
+}
+
+// Write writes a human-readable form of the report to w.
+func (r *Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "%d items in %v: %.1f items/s\n", r.Items, r.Elapsed.Round(time.Millisecond), r.Throughput)
+	fmt.Fprintf(w, "latency: p50 %v  p90 %v  p99 %v  max %v\n",
+		r.Latency.P50.Round(time.Microsecond), r.Latency.P90.Round(time.Microsecond),
+		r.Latency.P99.Round(time.Microsecond), r.Latency.Max.Round(time.Microsecond))
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "stage\tworkers\tutilization\tqueue (samples per length 0, 1, ...)\n")
+	for _, s := range r.Stages {
+		queue := "-"
+		if s.Queue != nil {
+			queue = fmt.Sprint(s.Queue)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%5.1f%%\t%s\n", s.Name, s.Workers, 100*s.Utilization, queue)
+	}
+	return tw.Flush()
+}
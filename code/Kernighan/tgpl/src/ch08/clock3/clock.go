@@ -0,0 +1,240 @@
+// Clock3 is a TCP server that periodically writes the time
+// in a chosen time zone, format and interval.
+//
+// Each listener has its own default time zone: the -port listener
+// uses the local zone (set it with the TZ environment variable), and
+// each -listen zone=addr flag adds a listener for another zone. For
+// example:
+//
+//	TZ=US/Eastern clock3 -port 8010 -listen Asia/Tokyo=localhost:8020
+//
+// A client may change the settings of its connection at any time by
+// sending lines of the form
+//
+//	TZ=Europe/London
+//	FORMAT=rfc3339
+//	INTERVAL=500ms
+//
+// The server applies each setting and writes the time at once, or
+// replies with a line beginning "ERR". Clients that send nothing,
+// such as gopl.io/ch8/netcat1, see a plain stream of times as from
+// gopl.io/ch8/clock2.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	port     = flag.Int("port", 8000, "port on localhost to serve the local time zone")
+	format   = flag.String("format", "clock", "default time format: clock, rfc3339, kitchen, stamp, unix, or a Go layout")
+	interval = flag.Duration("interval", 1*time.Second, "default interval between times")
+	listens  listeners
+)
+
+func init() {
+	flag.Var(&listens, "listen", "also serve time zone `zone=addr`, e.g. Asia/Tokyo=localhost:8020 (repeatable)")
+}
+
+// listeners is a flag.Value that accumulates zone=addr pairs.
+type listeners []string
+
+func (l *listeners) String() string { return strings.Join(*l, ",") }
+
+func (l *listeners) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("want zone=addr, got %q", s)
+	}
+	*l = append(*l, s)
+	return nil
+}
+
+// formats maps names of formats to layouts.
+// The "unix" format is handled specially.
+var formats = map[string]string{
+	"clock":   "15:04:05",
+	"rfc3339": time.RFC3339,
+	"kitchen": time.Kitchen,
+	"stamp":   time.Stamp,
+	"unix":    "",
+}
+
+// sampleTimes differ in every field that a layout can show: year,
+// month, day, weekday, hour, AM/PM, minute, second, fraction, and zone.
+var sampleTimes = [2]time.Time{
+	time.Date(2001, 2, 3, 4, 5, 6, 700000000, time.FixedZone("AAA", 1*60*60)),
+	time.Date(2018, 12, 25, 17, 48, 59, 300000000, time.FixedZone("BBB", -(8*60+30)*60)),
+}
+
+// checkFormat reports an error if f is neither a key of formats nor
+// a layout. A string that time.Format returns unchanged for both
+// sampleTimes, such as "hello" or "", contains no elements of a layout.
+// (One time is not enough: "Jan" formats as itself in January.)
+func checkFormat(f string) error {
+	if _, ok := formats[f]; ok {
+		return nil
+	}
+	for _, t := range sampleTimes {
+		if t.Format(f) != f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+// settings control what a connection is sent.
+type settings struct {
+	loc      *time.Location
+	format   string // a key of formats, or a layout
+	interval time.Duration
+}
+
+// set applies a line from the client, such as "TZ=Asia/Tokyo".
+func (s *settings) set(line string) error {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+		return fmt.Errorf("want KEY=value, got %q", line)
+	}
+	switch strings.ToUpper(key) {
+	case "TZ":
+		loc, err := time.LoadLocation(value)
+		if err != nil {
+			return err
+		}
+		s.loc = loc
+	case "FORMAT":
+		if err := checkFormat(value); err != nil {
+			return err
+		}
+		s.format = value
+	case "INTERVAL":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if d < 10*time.Millisecond {
+			return fmt.Errorf("interval %v too short", d)
+		}
+		s.interval = d
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	return nil
+}
+
+// now returns the current time according to s.
+func (s *settings) now() string {
+	t := time.Now().In(s.loc)
+	if s.format == "unix" {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	layout, ok := formats[s.format]
+	if !ok {
+		layout = s.format
+	}
+	return t.Format(layout)
+}
+
+func handleConn(c net.Conn, s settings) {
+	defer c.Close()
+
+	// Read settings from the client until it closes its side
+	// or until we return.
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		input := bufio.NewScanner(c)
+		for input.Scan() {
+			select {
+			case lines <- input.Text():
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	tick := time.NewTicker(s.interval)
+	defer tick.Stop()
+	for {
+		if _, err := io.WriteString(c, s.now()+"\n"); err != nil {
+			return // e.g., client disconnected
+		}
+		if !wait(c, &s, lines, tick) {
+			return
+		}
+	}
+}
+
+// wait waits for the next tick, applying any settings received on
+// lines meanwhile. It returns false if the connection has failed.
+func wait(c net.Conn, s *settings, lines <-chan string, tick *time.Ticker) bool {
+	for {
+		select {
+		case <-tick.C:
+			return true
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil // client closed its side; keep writing
+				continue
+			}
+			if err := s.set(line); err != nil {
+				if _, err := fmt.Fprintf(c, "ERR %v\n", err); err != nil {
+					return false
+				}
+				continue
+			}
+			tick.Reset(s.interval)
+			return true // write the time at once
+		}
+	}
+}
+
+func serve(listener net.Listener, s settings) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Print(err) // e.g., connection aborted
+			continue
+		}
+		go handleConn(conn, s) // handle connections concurrently
+	}
+}
+
+func main() {
+	flag.Parse()
+	if err := checkFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+	def := settings{loc: time.Local, format: *format, interval: *interval}
+
+	for _, l := range listens {
+		zone, addr, _ := strings.Cut(l, "=")
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			log.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s := def
+		s.loc = loc
+		go serve(listener, s)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", *port))
+	if err != nil {
+		log.Fatal(err)
+	}
+	serve(listener, def)
+}
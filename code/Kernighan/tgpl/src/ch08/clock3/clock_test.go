@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandshake(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	go handleConn(server, settings{loc: time.UTC, format: "clock", interval: time.Hour})
+
+	input := bufio.NewScanner(client)
+	next := func() string {
+		t.Helper()
+		client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if !input.Scan() {
+			t.Fatalf("no line from server: %v", input.Err())
+		}
+		return input.Text()
+	}
+
+	// The first time is written at once, in the listener's settings.
+	if got := next(); len(got) != len("15:04:05") {
+		t.Errorf("first line %q, want hh:mm:ss", got)
+	}
+
+	fmt.Fprintln(client, "FORMAT=unix")
+	got := next()
+	if n, err := strconv.ParseInt(got, 10, 64); err != nil || time.Since(time.Unix(n, 0)) > time.Minute {
+		t.Errorf("after FORMAT=unix got %q", got)
+	}
+
+	fmt.Fprintln(client, "TZ=Asia/Tokyo")
+	fmt.Fprintln(client, "FORMAT=-0700")
+	next() // reply to TZ
+	if got := next(); got != "+0900" {
+		t.Errorf("after TZ=Asia/Tokyo got zone %q, want +0900", got)
+	}
+
+	for _, bad := range []string{"TZ=Nowhere/Special", "INTERVAL=1ms", "COLOR=red", "hello",
+		"FORMAT=", "FORMAT=hms", "FORMAT=iso"} {
+		fmt.Fprintln(client, bad)
+		if got := next(); !strings.HasPrefix(got, "ERR ") {
+			t.Errorf("after %q got %q, want ERR", bad, got)
+		}
+	}
+
+	// A shorter interval takes effect at once.
+	fmt.Fprintln(client, "INTERVAL=20ms")
+	next()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		next()
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("3 ticks at 20ms took %v", d)
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	for _, test := range []struct {
+		format string
+		ok     bool
+	}{
+		{"clock", true},
+		{"unix", true},
+		{"15:04", true},
+		{"Monday", true},
+		{time.RFC1123, true},
+		{"Jan", true},
+		{"01", true},
+		{"Mon", true},
+		{"15", true},
+		{"15:04", true},
+		{"MST", true},
+		{"", false},
+		{"hms", false},
+		{"iso", false},
+		{"hh:mm", false},
+	} {
+		if err := checkFormat(test.format); (err == nil) != test.ok {
+			t.Errorf("checkFormat(%q) = %v, want ok=%t", test.format, err, test.ok)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	for _, test := range []struct {
+		arg  string
+		want clock
+	}{
+		{"Tokyo=localhost:8020", clock{"Tokyo", "localhost:8020", ""}},
+		{"London=localhost:8000@Europe/London", clock{"London", "localhost:8000", "Europe/London"}},
+	} {
+		got, err := parseClock(test.arg)
+		if err != nil || got != test.want {
+			t.Errorf("parseClock(%q) = %+v, %v; want %+v", test.arg, got, err, test.want)
+		}
+	}
+	for _, bad := range []string{"Tokyo", "=localhost:1", "Tokyo="} {
+		if _, err := parseClock(bad); err == nil {
+			t.Errorf("parseClock(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestWatchReconnects(t *testing.T) {
+	*minBackoff, *maxBackoff = 10*time.Millisecond, 40*time.Millisecond
+
+	// The server reports the zone it was asked for, then hangs up.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 100)
+			k, _ := conn.Read(buf)
+			fmt.Fprintf(conn, "%d %s\n", n, strings.TrimSpace(string(buf[:k])))
+			conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan update)
+	done := make(chan struct{})
+	go func() {
+		watch(ctx, 3, clock{"Tokyo", l.Addr().String(), "Asia/Tokyo"}, updates)
+		close(done)
+	}()
+
+	var got []string
+	for len(got) < 4 {
+		u := <-updates
+		if u.i != 3 {
+			t.Errorf("update for clock %d, want 3", u.i)
+		}
+		if u.status != "" {
+			got = append(got, "status")
+		} else {
+			got = append(got, u.time)
+		}
+	}
+	want := "1 TZ=Asia/Tokyo,status,2 TZ=Asia/Tokyo,status"
+	if strings.Join(got, ",") != want {
+		t.Errorf("updates %q, want %q", got, want)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not return after cancel")
+	}
+}
+
+func TestReadTimesError(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	go fmt.Fprintln(server, "12:00:00")
+
+	updates := make(chan update, 1)
+	client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	received, err := readTimes(context.Background(), 0, clock{}, client, updates)
+	if !received || (<-updates).time != "12:00:00" {
+		t.Error("time not received")
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("readTimes error = %v, want deadline exceeded", err)
+	}
+
+	// The server hanging up is not an error.
+	server, client = net.Pipe()
+	server.Close()
+	if _, err := readTimes(context.Background(), 0, clock{}, client, updates); err != nil {
+		t.Errorf("after server closed, readTimes error = %v, want nil", err)
+	}
+}
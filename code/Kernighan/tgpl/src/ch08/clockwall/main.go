@@ -0,0 +1,192 @@
+// Clockwall displays a live table of the times reported by several
+// clock servers, such as gopl.io/ch8/clock3.
+//
+// Each argument names a city and the address of its server, and
+// optionally a time zone that clockwall asks the server to use:
+//
+//	clockwall NewYork=localhost:8010 Tokyo=localhost:8020 London=localhost:8000@Europe/London
+//
+// Clockwall connects to all the servers concurrently. If a server
+// drops the connection, or cannot be reached, clockwall tries again
+// after a delay that doubles after each failure, up to -max-backoff.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	format     = flag.String("format", "", "time format to request from each server, e.g. rfc3339")
+	plain      = flag.Bool("plain", false, "print each update on its own line instead of redrawing the table")
+	minBackoff = flag.Duration("min-backoff", 500*time.Millisecond, "delay before the first reconnection attempt")
+	maxBackoff = flag.Duration("max-backoff", 30*time.Second, "maximum delay between reconnection attempts")
+)
+
+// A clock is one server to watch.
+type clock struct {
+	name string
+	addr string
+	zone string // time zone to request; "" => server's default
+}
+
+// An update reports a line of output, or a change in
+// the state of the connection, of the i'th clock.
+type update struct {
+	i      int
+	time   string // latest time, if status is ""
+	status string // e.g., "reconnecting in 2s: connection refused"
+}
+
+func parseClock(arg string) (clock, error) {
+	name, addr, ok := strings.Cut(arg, "=")
+	if !ok || name == "" || addr == "" {
+		return clock{}, fmt.Errorf("want name=host:port[@zone], got %q", arg)
+	}
+	addr, zone, _ := strings.Cut(addr, "@")
+	return clock{name: name, addr: addr, zone: zone}, nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: clockwall [flags] name=host:port[@zone]...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	var clocks []clock
+	for _, arg := range flag.Args() {
+		c, err := parseClock(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "clockwall: %v\n", err)
+			os.Exit(2)
+		}
+		clocks = append(clocks, c)
+	}
+
+	updates := make(chan update)
+	for i, c := range clocks {
+		go watch(context.Background(), i, c, updates)
+	}
+
+	times := make([]string, len(clocks))
+	status := make([]string, len(clocks))
+	for i := range status {
+		status[i] = "connecting"
+	}
+	for u := range updates {
+		if u.status == "" {
+			times[u.i], status[u.i] = u.time, ""
+		} else {
+			status[u.i] = u.status
+		}
+		if *plain {
+			if u.status == "" {
+				fmt.Printf("%s\t%s\n", clocks[u.i].name, u.time)
+			} else {
+				fmt.Printf("%s\t(%s)\n", clocks[u.i].name, u.status)
+			}
+			continue
+		}
+		os.Stdout.Write(render(clocks, times, status))
+	}
+}
+
+// render returns the table of clocks, preceded by the
+// terminal escape sequence to clear the screen.
+func render(clocks []clock, times, status []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\033[H\033[2J")
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "City\tTime\t\n")
+	fmt.Fprintf(tw, "----\t----\t\n")
+	for i, c := range clocks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.name, times[i], status[i])
+	}
+	tw.Flush()
+	return buf.Bytes()
+}
+
+// watch connects to the clock's server and sends an update for each
+// line it writes. When the connection fails, watch waits and tries
+// again, with exponential backoff, until ctx is done.
+func watch(ctx context.Context, i int, c clock, updates chan<- update) {
+	backoff := *minBackoff
+	for {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", c.addr)
+		if err == nil {
+			var received bool
+			received, err = readTimes(ctx, i, c, conn, updates)
+			if received {
+				backoff = *minBackoff // the connection worked for a while
+			}
+			if err == nil {
+				err = fmt.Errorf("connection closed by server")
+			} else {
+				err = fmt.Errorf("connection failed: %w", err)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case updates <- update{i: i, status: fmt.Sprintf("reconnecting in %v: %v", backoff, err)}:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > *maxBackoff {
+			backoff = *maxBackoff
+		}
+	}
+}
+
+// readTimes sends the clock's settings on conn and then an update for
+// each line received until the connection fails or ctx is done.
+// It reports whether any time was received, and the error, if any,
+// that ended the connection; it is nil if the server closed it.
+func readTimes(ctx context.Context, i int, c clock, conn net.Conn, updates chan<- update) (bool, error) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if c.zone != "" {
+		if _, err := fmt.Fprintf(conn, "TZ=%s\n", c.zone); err != nil {
+			return false, err
+		}
+	}
+	if *format != "" {
+		if _, err := fmt.Fprintf(conn, "FORMAT=%s\n", *format); err != nil {
+			return false, err
+		}
+	}
+	received := false
+	input := bufio.NewScanner(conn)
+	for input.Scan() {
+		u := update{i: i, time: input.Text()}
+		if strings.HasPrefix(u.time, "ERR ") {
+			u.time, u.status = "", "server: "+strings.TrimPrefix(u.time, "ERR ")
+		} else {
+			received = true
+		}
+		select {
+		case updates <- u:
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+	}
+	return received, input.Err()
+}
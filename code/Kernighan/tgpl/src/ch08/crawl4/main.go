@@ -0,0 +1,74 @@
+// Crawl4 crawls web links starting with the command-line arguments.
+//
+// This version uses gopl.io/ch8/crawler, so it is polite to the
+// sites it visits and terminates when it runs out of links.
+// If interrupted, it saves its frontier to the -state file;
+// running it again with the same flags resumes the crawl.
+//
+// Usage:
+//
+//	crawl4 [-depth 3] [-same-host] [-per-host 2] [-delay 1s] [-state crawl.json] url...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"gopl.io/ch8/crawler"
+)
+
+var (
+	depth     = flag.Int("depth", 3, "max links to follow from a seed; 0 => no limit")
+	sameHost  = flag.Bool("same-host", true, "only follow links to the seeds' hosts")
+	workers   = flag.Int("workers", 20, "max concurrent fetches")
+	perHost   = flag.Int("per-host", 2, "max concurrent fetches per host")
+	delay     = flag.Duration("delay", 0, "min interval between requests to a host")
+	agent     = flag.String("agent", "", "user agent name for robots.txt")
+	noRobots  = flag.Bool("no-robots", false, "ignore robots.txt")
+	stateFile = flag.String("state", "", "file in which to save the frontier")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 && *stateFile == "" {
+		fmt.Fprintln(os.Stderr, "usage: crawl4 [flags] url...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := crawler.New(crawler.Config{
+		MaxDepth:     *depth,
+		SameHost:     *sameHost,
+		Workers:      *workers,
+		PerHost:      *perHost,
+		Delay:        *delay,
+		UserAgent:    *agent,
+		IgnoreRobots: *noRobots,
+		StateFile:    *stateFile,
+	})
+	err := c.Crawl(ctx, flag.Args(), func(p crawler.Page) {
+		if p.Err == crawler.ErrDisallowed {
+			log.Printf("%s: %v", p.URL, p.Err)
+			return
+		}
+		if p.Err != nil {
+			log.Print(p.Err)
+			return
+		}
+		fmt.Println(p.URL)
+	})
+	if err == context.Canceled && *stateFile != "" {
+		log.Printf("interrupted; frontier saved in %s", *stateFile)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,376 @@
+// Package crawler provides a polite, resumable web crawler
+// built on gopl.io/ch5/links.
+//
+// Unlike gopl.io/ch8/crawl3, a Crawler limits the depth of the crawl,
+// can stay on the seeds' hosts, bounds the number of concurrent
+// requests to each host and spaces them out, obeys robots.txt,
+// terminates when there is nothing left to fetch, and can save its
+// frontier to a file so that an interrupted crawl may be resumed.
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"gopl.io/ch5/links"
+	memo "gopl.io/ch9/memo4"
+)
+
+// ErrDisallowed is the error reported for a page
+// that robots.txt forbids the crawler to fetch.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// A Config controls a Crawler.
+// The zero value is a usable configuration.
+type Config struct {
+	MaxDepth     int           // max links followed from a seed; 0 => no limit
+	SameHost     bool          // follow only links to the seeds' hosts or their subdomains
+	Workers      int           // max concurrent fetches; 0 => 20
+	PerHost      int           // max concurrent fetches per host; 0 => 2
+	Delay        time.Duration // min interval between requests to a host
+	UserAgent    string        // agent name matched in robots.txt; "" => "Go-http-client"
+	IgnoreRobots bool          // don't consult robots.txt
+	StateFile    string        // where to save the frontier; "" => don't save
+	SaveInterval time.Duration // min interval between saves of the frontier; 0 => 10s
+
+	// Client makes the requests for robots.txt files, and for pages
+	// if Fetch is nil. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Fetch returns the links of the page at url.
+	// If nil, the page is requested with Client, sending UserAgent,
+	// and its links are extracted as by links.Extract.
+	Fetch func(url string) ([]string, error)
+}
+
+// A Page reports the outcome of visiting one URL.
+type Page struct {
+	URL   string
+	Depth int      // number of links followed from a seed
+	Links []string // links found on the page
+	Err   error    // error fetching the page, or ErrDisallowed
+}
+
+// A Crawler crawls the web. Create one with New.
+type Crawler struct {
+	cfg    Config
+	robots *memo.Memo // robots.txt rules, keyed by origin
+}
+
+// New returns a Crawler using the configuration cfg.
+func New(cfg Config) *Crawler {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 20
+	}
+	if cfg.PerHost <= 0 {
+		cfg.PerHost = 2
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "Go-http-client"
+	}
+	if cfg.SaveInterval <= 0 {
+		cfg.SaveInterval = 10 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	c := &Crawler{cfg: cfg}
+	if c.cfg.Fetch == nil {
+		c.cfg.Fetch = c.extract
+	}
+	c.robots = memo.New(c.fetchRobots)
+	return c
+}
+
+type result struct {
+	Page
+	delay time.Duration // host's Crawl-delay, if any
+}
+
+// Crawl crawls the web starting from seeds, calling visit for each
+// page fetched or disallowed. All calls to visit are made from the
+// goroutine that called Crawl.
+//
+// If Config.StateFile names an existing file, Crawl first resumes the
+// crawl saved there; seeds already seen in it are not visited again.
+// The file is updated at most once every Config.SaveInterval as pages
+// are visited, and when ctx is cancelled, and it is removed once the
+// crawl is complete. Pages visited since the last update are visited
+// again if the crawl ends some other way and is resumed.
+//
+// Crawl returns nil when there are no more pages to visit,
+// or ctx.Err() if ctx is cancelled first.
+func (c *Crawler) Crawl(ctx context.Context, seeds []string, visit func(Page)) error {
+	st := new(state)
+	if c.cfg.StateFile != "" {
+		var err error
+		if st, err = loadState(c.cfg.StateFile); err != nil {
+			return err
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, u := range st.Seen {
+		seen[u] = true
+	}
+	hosts := make(map[string]bool) // seeds' hosts
+	for _, h := range st.Hosts {
+		hosts[h] = true
+	}
+	queue := st.Frontier
+	for _, s := range seeds {
+		u, ok := canonical(s)
+		if !ok {
+			continue
+		}
+		hosts[u.Host] = true
+		if !seen[u.String()] {
+			seen[u.String()] = true
+			queue = append(queue, item{u.String(), 0})
+		}
+	}
+
+	inflight := make(map[string]item)
+	lastSave := time.Now()
+	save := func() error {
+		if c.cfg.StateFile == "" {
+			return nil
+		}
+		lastSave = time.Now()
+		var s state
+		for u := range seen {
+			s.Seen = append(s.Seen, u)
+		}
+		for h := range hosts {
+			s.Hosts = append(s.Hosts, h)
+		}
+		for _, it := range inflight {
+			s.Frontier = append(s.Frontier, it)
+		}
+		s.Frontier = append(s.Frontier, queue...)
+		return saveState(c.cfg.StateFile, &s)
+	}
+
+	work := make(chan item)
+	results := make(chan result, c.cfg.Workers) // never blocks a worker
+	for i := 0; i < c.cfg.Workers; i++ {
+		go c.worker(work, results)
+	}
+	defer close(work)
+
+	active := make(map[string]int)          // fetches in progress, by host
+	next := make(map[string]time.Time)      // earliest time of next fetch, by host
+	delay := make(map[string]time.Duration) // Crawl-delay, by host
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for len(queue) > 0 || len(inflight) > 0 {
+		// Find the first item whose host may be fetched now,
+		// or else the time at which one may be.
+		now := time.Now()
+		idx := -1
+		var wake time.Time
+		if len(inflight) < c.cfg.Workers {
+			for i, it := range queue {
+				h := host(it.URL)
+				if active[h] >= c.cfg.PerHost {
+					continue
+				}
+				if t := next[h]; t.After(now) {
+					if wake.IsZero() || t.Before(wake) {
+						wake = t
+					}
+					continue
+				}
+				idx = i
+				break
+			}
+		}
+
+		var send chan<- item // nil unless an item is ready
+		var it item
+		if idx >= 0 {
+			send, it = work, queue[idx]
+		}
+		var tick <-chan time.Time // nil unless waiting for a host
+		if idx < 0 && !wake.IsZero() {
+			timer.Reset(wake.Sub(now))
+			tick = timer.C
+		}
+
+		select {
+		case send <- it:
+			queue = append(queue[:idx], queue[idx+1:]...)
+			inflight[it.URL] = it
+			h := host(it.URL)
+			active[h]++
+			d := c.cfg.Delay
+			if delay[h] > d {
+				d = delay[h]
+			}
+			next[h] = now.Add(d)
+
+		case r := <-results:
+			delete(inflight, r.URL)
+			h := host(r.URL)
+			active[h]--
+			if r.delay > 0 {
+				delay[h] = r.delay
+			}
+			visit(r.Page)
+			if r.Err == nil && (c.cfg.MaxDepth == 0 || r.Depth < c.cfg.MaxDepth) {
+				for _, link := range r.Links {
+					u, ok := c.follow(link, hosts)
+					if ok && !seen[u] {
+						seen[u] = true
+						queue = append(queue, item{u, r.Depth + 1})
+					}
+				}
+			}
+			if time.Since(lastSave) >= c.cfg.SaveInterval {
+				if err := save(); err != nil {
+					return err
+				}
+			}
+
+		case <-tick:
+			// A host may now be fetched.
+
+		case <-ctx.Done():
+			if err := save(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+		if tick != nil && !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+
+	if c.cfg.StateFile != "" {
+		if err := os.Remove(c.cfg.StateFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// canonical parses an http or https URL and puts it in a canonical
+// form, without fragment, so that equivalent links are seen once.
+func canonical(rawurl string) (*url.URL, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, false
+	}
+	u.Fragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, true
+}
+
+// follow reports whether the crawler should follow link,
+// and if so returns its canonical form.
+func (c *Crawler) follow(link string, hosts map[string]bool) (string, bool) {
+	u, ok := canonical(link)
+	if !ok {
+		return "", false
+	}
+	if c.cfg.SameHost && !sameHost(u.Host, hosts) {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// sameHost reports whether h is one of hosts or a subdomain of one.
+func sameHost(h string, hosts map[string]bool) bool {
+	if hosts[h] {
+		return true
+	}
+	for s := range hosts {
+		if strings.HasSuffix(h, "."+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// host returns the host (and port, if any) of rawurl.
+func host(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func (c *Crawler) worker(work <-chan item, results chan<- result) {
+	for it := range work {
+		results <- c.fetch(it)
+	}
+}
+
+func (c *Crawler) fetch(it item) result {
+	r := result{Page: Page{URL: it.URL, Depth: it.Depth}}
+	if !c.cfg.IgnoreRobots {
+		u, err := url.Parse(it.URL)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		v, _ := c.robots.Get(u.Scheme + "://" + u.Host)
+		rules := v.(*robots)
+		if !rules.allowed(u.RequestURI()) {
+			r.Err = ErrDisallowed
+			return r
+		}
+		r.delay = rules.crawlDelay()
+	}
+	r.Links, r.Err = c.cfg.Fetch(it.URL)
+	return r
+}
+
+// extract is the default Config.Fetch function.
+func (c *Crawler) extract(rawurl string) ([]string, error) {
+	resp, err := c.get(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting %s: %s", rawurl, resp.Status)
+	}
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s as HTML: %v", rawurl, err)
+	}
+	var urls []string
+	for _, a := range links.Anchors(doc, resp.Request.URL) {
+		urls = append(urls, a.URL)
+	}
+	return urls, nil
+}
+
+// get requests rawurl with the configured client and user agent.
+func (c *Crawler) get(rawurl string) (*http.Response, error) {
+	return c.do(c.cfg.Client, rawurl)
+}
+
+// do requests rawurl with client and the configured user agent.
+func (c *Crawler) do(client *http.Client, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+	return client.Do(req)
+}
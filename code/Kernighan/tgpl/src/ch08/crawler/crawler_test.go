@@ -0,0 +1,388 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopl.io/ch5/links"
+)
+
+// newSite returns a test server serving a small graph of pages.
+// Each page links to the paths listed for it; an "EXT" link
+// refers to the page at the external server, ext.
+func newSite(t *testing.T, graph map[string][]string, robotsTxt string, ext string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			if robotsTxt == "" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, robotsTxt)
+			return
+		}
+		targets, ok := graph[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html><body>")
+		for _, t := range targets {
+			if t == "EXT" {
+				t = ext
+			}
+			fmt.Fprintf(w, "<a href='%s'>%s</a>\n", t, t)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+var graph = map[string][]string{
+	"/":          {"/a", "/b#top", "/private/x", "EXT"},
+	"/a":         {"/b", "/c"},
+	"/b":         {"/"},
+	"/c":         {"/d", "mailto:someone@example.com"},
+	"/d":         {},
+	"/private/x": {"/e"},
+	"/e":         {},
+}
+
+// crawl runs c from seed and returns the paths visited, sorted,
+// with a "!" suffix on those disallowed by robots.txt.
+func crawl(t *testing.T, ctx context.Context, c *Crawler, seed string, visit func(Page)) ([]string, error) {
+	t.Helper()
+	var got []string
+	err := c.Crawl(ctx, []string{seed}, func(p Page) {
+		path := strings.TrimPrefix(p.URL, seed)
+		if p.Err == ErrDisallowed {
+			path += "!"
+		} else if p.Err != nil {
+			t.Errorf("%s: %v", p.URL, p.Err)
+		}
+		got = append(got, path)
+		if visit != nil {
+			visit(p)
+		}
+	})
+	sort.Strings(got)
+	return got, err
+}
+
+func TestCrawl(t *testing.T) {
+	ext := newSite(t, map[string][]string{"/": {}}, "", "")
+	site := newSite(t, graph, "User-agent: *\nDisallow: /private\n", ext.URL+"/")
+
+	for _, test := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{SameHost: true},
+			"/ /a /b /c /d /private/x!"},
+		{Config{SameHost: true, MaxDepth: 1},
+			"/ /a /b /private/x!"},
+		{Config{SameHost: true, IgnoreRobots: true},
+			"/ /a /b /c /d /e /private/x"},
+		{Config{},
+			"/ /a /b /c /d /private/x! " + ext.URL + "/"},
+	} {
+		got, err := crawl(t, context.Background(), New(test.cfg), site.URL, nil)
+		if err != nil {
+			t.Errorf("%+v: %v", test.cfg, err)
+			continue
+		}
+		if s := strings.Join(got, " "); s != test.want {
+			t.Errorf("%+v: visited %s, want %s", test.cfg, s, test.want)
+		}
+	}
+}
+
+func TestPerHostLimit(t *testing.T) {
+	// A wide site: the root links to 20 leaves.
+	wide := map[string][]string{"/": nil}
+	for i := 0; i < 20; i++ {
+		p := fmt.Sprintf("/%d", i)
+		wide["/"] = append(wide["/"], p)
+		wide[p] = nil
+	}
+	site := newSite(t, wide, "", "")
+
+	var mu sync.Mutex
+	var cur, max int
+	fetch := func(url string) ([]string, error) {
+		mu.Lock()
+		cur++
+		if cur > max {
+			max = cur
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		defer func() {
+			mu.Lock()
+			cur--
+			mu.Unlock()
+		}()
+		return links.Extract(url)
+	}
+
+	c := New(Config{PerHost: 3, Fetch: fetch})
+	got, err := crawl(t, context.Background(), c, site.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 21 {
+		t.Errorf("visited %d pages, want 21", len(got))
+	}
+	if max > 3 {
+		t.Errorf("%d concurrent fetches from one host, want at most 3", max)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	site := newSite(t, map[string][]string{"/": {"/a", "/b"}, "/a": nil, "/b": nil}, "", "")
+	const delay = 20 * time.Millisecond
+	start := time.Now()
+	got, err := crawl(t, context.Background(), New(Config{Delay: delay}), site.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("visited %v, want 3 pages", got)
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("crawl of 3 pages took %v, want at least %v", elapsed, 2*delay)
+	}
+}
+
+func TestResume(t *testing.T) {
+	site := newSite(t, graph, "", "")
+	file := filepath.Join(t.TempDir(), "frontier.json")
+	cfg := Config{SameHost: true, IgnoreRobots: true, Workers: 1, StateFile: file}
+
+	// Interrupt the crawl after two pages.
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	first, err := crawl(t, ctx, New(cfg), site.URL, func(Page) {
+		if n++; n == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("interrupted crawl returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("state not saved: %v", err)
+	}
+
+	// Resume it.
+	rest, err := crawl(t, context.Background(), New(cfg), site.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("state file remains after complete crawl: %v", err)
+	}
+
+	all := append(first, rest...)
+	sort.Strings(all)
+	got := strings.Join(all, " ")
+	want := "/ /a /b /c /d /e /private/x"
+	if got != want {
+		t.Errorf("visited %s over two runs, want %s", got, want)
+	}
+}
+
+func TestRobots(t *testing.T) {
+	const txt = `# comment
+User-agent: *
+Disallow: /private
+Allow: /private/ok
+Disallow: /*.pdf$
+
+User-agent: gopl-bot
+User-agent: other
+Disallow: /
+Allow: /public
+Crawl-delay: 1.5
+`
+	for _, test := range []struct {
+		agent, path string
+		want        bool
+	}{
+		{"Go-http-client", "/", true},
+		{"Go-http-client", "/private", false},
+		{"Go-http-client", "/private/x", false},
+		{"Go-http-client", "/private/ok", true},
+		{"Go-http-client", "/doc.pdf", false},
+		{"Go-http-client", "/doc.pdf?x=1", true},
+		{"gopl-bot/1.0", "/", false},
+		{"gopl-bot/1.0", "/public/x", true},
+		{"Other", "/private/ok", false},
+	} {
+		r := parseRobots(strings.NewReader(txt), test.agent)
+		if got := r.allowed(test.path); got != test.want {
+			t.Errorf("%s: allowed(%q) = %t, want %t", test.agent, test.path, got, test.want)
+		}
+	}
+	if r := parseRobots(strings.NewReader(txt), "gopl-bot"); r.delay != 1500*time.Millisecond {
+		t.Errorf("Crawl-delay = %v, want 1.5s", r.delay)
+	}
+	if r := parseRobots(strings.NewReader(""), "x"); !r.allowed("/anything") {
+		t.Errorf("empty robots.txt disallows /anything")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/x", true},
+		{"/a", "/abc", true},
+		{"/a$", "/abc", false},
+		{"/a$", "/a", true},
+		{"/*.gif$", "/x/y.gif", true},
+		{"/*.gif$", "/x/y.gif.html", false},
+		{"/*/b*d", "/a/bcd/e", true},
+		{"/*/b*d", "/a/c", false},
+		{"/a*b*c$", "/abxbc", true},
+	} {
+		if got := match(test.pattern, test.path); got != test.want {
+			t.Errorf("match(%q, %q) = %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+// countingTransport records the User-Agent of each request.
+type countingTransport struct {
+	mu     sync.Mutex
+	agents map[string]int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.agents[req.URL.Path+" "+req.UserAgent()]++
+	t.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClient(t *testing.T) {
+	site := newSite(t, map[string][]string{"/": {"/a"}, "/a": nil}, "User-agent: *\nAllow: /\n", "")
+	tr := &countingTransport{agents: make(map[string]int)}
+	c := New(Config{UserAgent: "gopl-bot/1.0", Client: &http.Client{Transport: tr}})
+	got, err := crawl(t, context.Background(), c, site.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(got, " "); s != "/ /a" {
+		t.Errorf("visited %s, want / /a", s)
+	}
+	want := map[string]int{"/robots.txt gopl-bot/1.0": 1, "/ gopl-bot/1.0": 1, "/a gopl-bot/1.0": 1}
+	if fmt.Sprint(tr.agents) != fmt.Sprint(want) {
+		t.Errorf("requests by path and agent: %v, want %v", tr.agents, want)
+	}
+}
+
+func TestRobotsStatus(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		want   string
+	}{
+		{http.StatusNotFound, "/ /a"},
+		{http.StatusForbidden, "/ /a"},
+		{http.StatusInternalServerError, "/!"},
+		{http.StatusServiceUnavailable, "/!"},
+	} {
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/robots.txt":
+				w.WriteHeader(test.status)
+			case "/":
+				fmt.Fprint(w, `<a href="/a">a</a>`)
+			}
+		}))
+		got, err := crawl(t, context.Background(), New(Config{}), site.URL, nil)
+		site.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := strings.Join(got, " "); s != test.want {
+			t.Errorf("robots.txt status %d: visited %s, want %s", test.status, s, test.want)
+		}
+	}
+
+	// A robots.txt that redirects endlessly imposes no restrictions,
+	// but one reached after a few redirects is obeyed.
+	for _, test := range []struct {
+		redirects int
+		want      string
+	}{
+		{2, "/ /a!"},
+		{100, "/ /a"},
+	} {
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasPrefix(r.URL.Path, "/robots"):
+				n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/robots"))
+				if n < test.redirects {
+					http.Redirect(w, r, fmt.Sprintf("/robots%d", n+1), http.StatusFound)
+					return
+				}
+				fmt.Fprint(w, "User-agent: *\nDisallow: /a\n")
+			case r.URL.Path == "/":
+				fmt.Fprint(w, `<a href="/a">a</a>`)
+			}
+		}))
+		got, err := crawl(t, context.Background(), New(Config{}), site.URL, nil)
+		site.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := strings.Join(got, " "); s != test.want {
+			t.Errorf("robots.txt after %d redirects: visited %s, want %s", test.redirects, s, test.want)
+		}
+	}
+
+	// An unreachable site may not be crawled either.
+	site := httptest.NewServer(http.NotFoundHandler())
+	site.Close()
+	if got, _ := crawl(t, context.Background(), New(Config{}), site.URL, nil); strings.Join(got, " ") != "/!" {
+		t.Errorf("unreachable site: visited %s, want /!", got)
+	}
+}
+
+func TestSaveInterval(t *testing.T) {
+	site := newSite(t, graph, "", "")
+	file := filepath.Join(t.TempDir(), "frontier.json")
+	cfg := Config{SameHost: true, IgnoreRobots: true, Workers: 1, StateFile: file, SaveInterval: time.Hour}
+
+	// The frontier is not saved after each page,
+	// but is when the crawl is interrupted.
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	_, err := crawl(t, ctx, New(cfg), site.URL, func(Page) {
+		if _, err := os.Stat(file); err == nil {
+			t.Errorf("state saved after %d pages", n)
+		}
+		if n++; n == 3 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("interrupted crawl returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("state not saved on interruption: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// An item is a URL waiting to be crawled.
+type item struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// state is the on-disk form of an interrupted crawl.
+// Frontier includes the items that were being fetched
+// when the crawl stopped, so they are fetched again on resume.
+type state struct {
+	Hosts    []string `json:"hosts"` // seeds' hosts, for Config.SameHost
+	Seen     []string `json:"seen"`
+	Frontier []item   `json:"frontier"`
+}
+
+// loadState reads the state saved in file.
+// A missing file yields an empty state.
+func loadState(file string) (*state, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return new(state), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// saveState writes s to file, replacing it atomically
+// so that an interruption cannot leave a truncated file.
+func saveState(file string, s *state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
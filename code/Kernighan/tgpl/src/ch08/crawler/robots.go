@@ -0,0 +1,203 @@
+package crawler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A robots holds the rules of a robots.txt file
+// that apply to one user agent.
+type robots struct {
+	rules []rule
+	delay time.Duration // Crawl-delay, if any
+}
+
+type rule struct {
+	allow   bool
+	pattern string // path prefix, possibly with * and a trailing $
+}
+
+// The methods of *robots treat nil as imposing no restrictions.
+
+// allowed reports whether the rules permit fetching path,
+// which should include any query string.
+// The longest matching pattern wins; Allow wins a tie.
+func (r *robots) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !match(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || n == best && rule.allow {
+			best, allow = n, rule.allow
+		}
+	}
+	return allow
+}
+
+// match reports whether path matches a robots.txt pattern,
+// in which * matches any sequence of characters and a
+// trailing $ anchors the pattern to the end of the path.
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || path == ""
+	}
+	last := len(parts) - 1
+	for _, p := range parts[1:last] {
+		i := strings.Index(path, p)
+		if i < 0 {
+			return false
+		}
+		path = path[i+len(p):]
+	}
+	if anchored {
+		return strings.HasSuffix(path, parts[last])
+	}
+	return strings.Contains(path, parts[last])
+}
+
+// parseRobots parses a robots.txt file and returns the rules
+// of the group that best matches agent: the group naming the
+// longest substring of agent, or failing that the * group.
+func parseRobots(r io.Reader, agent string) *robots {
+	type group struct {
+		agents []string
+		robots
+	}
+	var groups []*group
+	var cur *group
+	inRules := false // a rule has been seen since the last User-agent line
+
+	agent = strings.ToLower(agent)
+	in := bufio.NewScanner(r)
+	for in.Scan() {
+		line := in.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+		switch key {
+		case "user-agent":
+			if cur == nil || inRules {
+				cur = new(group)
+				groups = append(groups, cur)
+				inRules = false
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if cur == nil {
+				continue // rule outside any group
+			}
+			inRules = true
+			if value == "" {
+				continue // "Disallow:" with no path allows everything
+			}
+			cur.rules = append(cur.rules, rule{key == "allow", value})
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.delay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	var best *group
+	bestLen := -1
+	for _, g := range groups {
+		for _, a := range g.agents {
+			n := -1
+			if a == "*" {
+				n = 0
+			} else if a != "" && strings.Contains(agent, a) {
+				n = len(a)
+			}
+			if n > bestLen {
+				best, bestLen = g, n
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &best.robots
+}
+
+// crawlDelay returns the minimum interval between requests.
+func (r *robots) crawlDelay() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.delay
+}
+
+// disallowAll is the rules for a site whose robots.txt
+// is unreachable.
+var disallowAll = &robots{rules: []rule{{allow: false, pattern: "/"}}}
+
+// maxRobotsRedirects is the number of redirects followed
+// in fetching robots.txt; RFC 9309 asks for at least five.
+const maxRobotsRedirects = 5
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// fetchRobots fetches and parses the robots.txt file of the site
+// whose scheme and host are given by origin, e.g. "http://example.com".
+// It is a memo.Func, so each site's file is fetched only once.
+//
+// As RFC 9309 requires, a site that has no robots.txt file (status
+// 4xx, or more than maxRobotsRedirects redirects) imposes no
+// restrictions, but one whose file is unreachable, because of a
+// server (5xx) or network error, may not be crawled.
+func (c *Crawler) fetchRobots(origin string) (interface{}, error) {
+	client := *c.cfg.Client
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRobotsRedirects {
+			return errTooManyRedirects
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		return nil
+	}
+	resp, err := c.do(&client, origin+"/robots.txt")
+	if errors.Is(err, errTooManyRedirects) {
+		return (*robots)(nil), nil
+	}
+	if err != nil {
+		return disallowAll, nil
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode / 100 {
+	case 2:
+		return parseRobots(resp.Body, c.cfg.UserAgent), nil
+	case 5:
+		return disallowAll, nil
+	}
+	return (*robots)(nil), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string, size int) {
+		t.Helper()
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, make([]byte, size), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/x", 100)
+	write("a/b/y", 200)
+	write("c/z", 400)
+	write("c/skip.o", 800)
+	write("d/big", 1000)
+	if err := os.Link(filepath.Join(root, "d/big"), filepath.Join(root, "d/big2")); err != nil {
+		t.Skip("hard links unsupported:", err)
+	}
+
+	w := newWalker([]string{"*.o"}, false)
+	trees := w.walk([]string{root, filepath.Join(root, "missing")})
+	if len(w.errors) != 1 {
+		t.Errorf("errors = %v, want one for missing root", w.errors)
+	}
+	if len(trees) != 1 {
+		t.Fatalf("got %d trees, want 1", len(trees))
+	}
+	if got, want := trees[0].Size, int64(100+200+400+1000); got != want {
+		t.Errorf("total size = %d, want %d", got, want)
+	}
+	if got, want := trees[0].Files, int64(4); got != want {
+		t.Errorf("total files = %d, want %d", got, want)
+	}
+
+	dirs := largest(trees, true, 2)
+	if len(dirs) != 2 || dirs[0] != trees[0] || dirs[1].Name != "d" {
+		t.Errorf("largest dirs = %v, want root then d", dirs)
+	}
+	files := largest(trees, false, 1)
+	if len(files) != 1 || files[0].Size != 1000 {
+		t.Errorf("largest file = %v, want one of 1000 bytes", files)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for _, test := range []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+	} {
+		if got := formatSize(test.n); got != test.want {
+			t.Errorf("formatSize(%d) = %q, want %q", test.n, got, test.want)
+		}
+	}
+}
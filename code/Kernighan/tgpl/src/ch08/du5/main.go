@@ -0,0 +1,152 @@
+// The du5 command computes the disk usage of the files in a directory.
+//
+// Unlike du1-du4, which print only running totals, du5 aggregates
+// the sizes of each directory and prints them as an indented tree,
+// followed by the largest files and directories. Files with several
+// hard links are counted once. Directories that cannot be read are
+// reported, not silently skipped.
+//
+// Usage:
+//
+//	du5 [-depth 2] [-top 10] [-exclude glob]... [-x] [-json] [dir...]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	depth   = flag.Int("depth", 2, "depth of directory tree to print")
+	topN    = flag.Int("top", 10, "number of largest files and directories to list")
+	oneFS   = flag.Bool("x", false, "stay on the file system of each root (one file system)")
+	asJSON  = flag.Bool("json", false, "print the full tree as JSON")
+	exclude globs
+)
+
+func init() {
+	flag.BoolVar(oneFS, "one-file-system", false, "same as -x")
+	flag.Var(&exclude, "exclude", "skip files and directories matching `glob` (repeatable)")
+}
+
+// globs is a flag.Value that accumulates patterns.
+type globs []string
+
+func (g *globs) String() string { return strings.Join(*g, ",") }
+
+func (g *globs) Set(s string) error {
+	*g = append(*g, s)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	w := newWalker(exclude, *oneFS)
+	tree := w.walk(roots)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Roots  []*node  `json:"roots"`
+			Errors []string `json:"errors,omitempty"`
+		}{tree, w.errors}); err != nil {
+			fmt.Fprintf(os.Stderr, "du: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		for _, root := range tree {
+			printTree(root, 0)
+		}
+		fmt.Println()
+		printTop("Largest directories", largest(tree, true, *topN))
+		fmt.Println()
+		printTop("Largest files", largest(tree, false, *topN))
+	}
+
+	for _, err := range w.errors {
+		fmt.Fprintf(os.Stderr, "du: %s\n", err)
+	}
+	if len(w.errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printTree prints the directory tree rooted at n, largest first,
+// down to the depth given by the -depth flag.
+func printTree(n *node, level int) {
+	fmt.Printf("%10s  %*s%s\n", formatSize(n.Size), level*2, "", n.path)
+	if level >= *depth {
+		return
+	}
+	var dirs []*node
+	for _, c := range n.Children {
+		if c.Dir {
+			dirs = append(dirs, c)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool { return bigger(dirs[i], dirs[j]) })
+	for _, c := range dirs {
+		printTree(c, level+1)
+	}
+}
+
+func printTop(title string, nodes []*node) {
+	fmt.Println(title + ":")
+	for _, n := range nodes {
+		fmt.Printf("%10s  %s\n", formatSize(n.Size), n.path)
+	}
+}
+
+// largest returns the n largest directories (or files) in the trees.
+func largest(roots []*node, dirs bool, n int) []*node {
+	var all []*node
+	var visit func(*node)
+	visit = func(x *node) {
+		if x.Dir == dirs {
+			all = append(all, x)
+		}
+		for _, c := range x.Children {
+			visit(c)
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	sort.Slice(all, func(i, j int) bool { return bigger(all[i], all[j]) })
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// bigger orders nodes by decreasing size, then by path.
+func bigger(x, y *node) bool {
+	if x.Size != y.Size {
+		return x.Size > y.Size
+	}
+	return x.path < y.path
+}
+
+// formatSize formats a number of bytes using binary prefixes.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
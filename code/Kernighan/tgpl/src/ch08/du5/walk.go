@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopl.io/ch8/fileid"
+)
+
+// A node is a file or directory in the tree built by a walker.
+type node struct {
+	Name     string  `json:"name"`
+	Dir      bool    `json:"dir,omitempty"`
+	Size     int64   `json:"size"`  // in bytes, including all descendants
+	Files    int64   `json:"files"` // number of files, including all descendants
+	Children []*node `json:"children,omitempty"`
+
+	path string // including the root
+}
+
+// A walker builds trees of nodes, traversing each directory in its
+// own goroutine like du4, with a counting semaphore to limit the
+// number of directories open at once.
+type walker struct {
+	exclude []string // glob patterns
+	oneFS   bool     // don't cross into other file systems (if files can be identified)
+
+	mu     sync.Mutex
+	links  map[fileid.ID]bool // files with several hard links, already counted
+	errors []string
+}
+
+func newWalker(exclude []string, oneFS bool) *walker {
+	return &walker{exclude: exclude, oneFS: oneFS, links: make(map[fileid.ID]bool)}
+}
+
+// walk returns the trees rooted at roots, with directory sizes totalled.
+// Errors encountered along the way are recorded in w.errors.
+func (w *walker) walk(roots []string) []*node {
+	var trees []*node
+	var n sync.WaitGroup
+	for _, root := range roots {
+		info, err := os.Lstat(root)
+		if err != nil {
+			w.error(err)
+			continue
+		}
+		t := &node{Name: root, path: root}
+		trees = append(trees, t)
+		if !info.IsDir() {
+			w.addFile(t, info)
+			continue
+		}
+		t.Dir = true
+		id, _, _ := fileid.Identify(info)
+		n.Add(1)
+		go w.walkDir(t, id.Dev, &n)
+	}
+	n.Wait()
+
+	for _, t := range trees {
+		total(t)
+	}
+	return trees
+}
+
+// walkDir adds the entries of dir to it, recursively.
+// dev is the device of the root of the walk.
+func (w *walker) walkDir(dir *node, dev uint64, n *sync.WaitGroup) {
+	defer n.Done()
+	for _, entry := range w.dirents(dir.path) {
+		path := filepath.Join(dir.path, entry.Name())
+		if w.excluded(path) {
+			continue
+		}
+		child := &node{Name: entry.Name(), path: path}
+		if entry.IsDir() {
+			if id, _, ok := fileid.Identify(entry); w.oneFS && ok && id.Dev != dev {
+				continue // mount point
+			}
+			child.Dir = true
+			n.Add(1)
+			go w.walkDir(child, dev, n)
+		} else if !w.addFile(child, entry) {
+			continue // another link to a file already counted
+		}
+		dir.Children = append(dir.Children, child)
+	}
+}
+
+// addFile sets the size of the file node x from info, reporting
+// false if it is a hard link to a file that has already been counted.
+func (w *walker) addFile(x *node, info os.FileInfo) bool {
+	if id, nlink, ok := fileid.Identify(info); ok && nlink > 1 {
+		w.mu.Lock()
+		seen := w.links[id]
+		w.links[id] = true
+		w.mu.Unlock()
+		if seen {
+			return false
+		}
+	}
+	x.Size = info.Size()
+	x.Files = 1
+	return true
+}
+
+// excluded reports whether path matches an -exclude pattern,
+// either as a whole or by its final element.
+func (w *walker) excluded(path string) bool {
+	base := filepath.Base(path)
+	for _, pattern := range w.exclude {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) error(err error) {
+	w.mu.Lock()
+	w.errors = append(w.errors, err.Error())
+	w.mu.Unlock()
+}
+
+// total computes the sizes and file counts of the directories in x.
+func total(x *node) {
+	for _, c := range x.Children {
+		if c.Dir {
+			total(c)
+		}
+		x.Size += c.Size
+		x.Files += c.Files
+	}
+}
+
+var sema = make(chan struct{}, 20) // concurrency-limiting counting semaphore
+
+// dirents returns the entries of directory dir.
+func (w *walker) dirents(dir string) []os.FileInfo {
+	sema <- struct{}{}        // acquire token
+	defer func() { <-sema }() // release token
+
+	f, err := os.Open(dir)
+	if err != nil {
+		w.error(err)
+		return nil
+	}
+	defer f.Close()
+
+	entries, err := f.Readdir(0) // 0 => no limit; read all entries
+	if err != nil {
+		w.error(err)
+		// Don't return: Readdir may return partial results.
+	}
+	return entries
+}
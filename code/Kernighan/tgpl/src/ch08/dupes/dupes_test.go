@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDupes(t *testing.T) {
+	root := t.TempDir()
+	big := bytes.Repeat([]byte("x"), 2*partialSize)
+	bigDiffersLate := append(bytes.Repeat([]byte("x"), 2*partialSize-1), 'y')
+	files := map[string][]byte{
+		"a/one":    []byte("hello"),
+		"b/one":    []byte("hello"),
+		"c/d/one":  []byte("hello"),
+		"a/other":  []byte("world"), // same size as "hello"
+		"big1":     big,
+		"b/big2":   big,
+		"big3":     bigDiffersLate,
+		"empty1":   nil,
+		"a/empty2": nil,
+	}
+	for name, data := range files {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A hard link is not a duplicate.
+	if err := os.Link(filepath.Join(root, "big1"), filepath.Join(root, "big1link")); err != nil {
+		t.Skip("hard links unsupported:", err)
+	}
+
+	var got []string
+	for _, g := range findDupes([]string{root}, 1) {
+		var rel []string
+		for _, p := range g.paths {
+			r, _ := filepath.Rel(root, p)
+			rel = append(rel, filepath.ToSlash(r))
+		}
+		got = append(got, strings.Join(rel, " "))
+	}
+	// Which of big1 and big1link is found first is unspecified.
+	if len(got) != 2 ||
+		got[0] != "b/big2 big1" && got[0] != "b/big2 big1link" ||
+		got[1] != "a/one b/one c/d/one" {
+		t.Errorf("got groups %q, want [b/big2 big1] [a/one b/one c/d/one]", got)
+	}
+}
+
+// tree creates the named files, each containing its data, under a
+// new temporary directory, and returns the directory.
+func tree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, data := range files {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func sameFile(t *testing.T, x, y string) bool {
+	t.Helper()
+	xi, err := os.Stat(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yi, err := os.Stat(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(xi, yi)
+}
+
+func dupes(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := run(args, &out); err != nil {
+		t.Fatalf("run(%q): %v", args, err)
+	}
+	return out.String()
+}
+
+func TestActions(t *testing.T) {
+	files := map[string]string{"a": "hello", "b/a": "hello", "c": "hello", "d": "world"}
+	root := tree(t, files)
+	a, ba, c := filepath.Join(root, "a"), filepath.Join(root, "b", "a"), filepath.Join(root, "c")
+	summary := "2 duplicate files, 10 bytes wasted\n"
+
+	// A dry run changes nothing.
+	for _, action := range []string{"link", "delete"} {
+		out := dupes(t, "-action", action, "-n", root)
+		want := fmt.Sprintf("\t%s (%s)\n\t%s (%s)\n", ba, action, c, action)
+		if !strings.Contains(out, want) || !strings.HasSuffix(out, summary) {
+			t.Errorf("-action=%s -n printed:\n%s", action, out)
+		}
+		if sameFile(t, a, ba) || sameFile(t, a, c) {
+			t.Fatalf("-action=%s -n linked files", action)
+		}
+	}
+
+	// A link that cannot be made leaves the duplicate in place.
+	blocker := c + ".dupes-tmp"
+	if err := os.Mkdir(blocker, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if out := dupes(t, "-action", "link", root); !strings.HasSuffix(out, summary) {
+		t.Errorf("-action=link printed:\n%s", out)
+	}
+	if !sameFile(t, a, ba) {
+		t.Error("b/a was not linked to a")
+	}
+	if data, err := os.ReadFile(c); err != nil || string(data) != "hello" || sameFile(t, a, c) {
+		t.Errorf("after failed link, c = %q, %v", data, err)
+	}
+	os.Remove(blocker)
+
+	// Hard links are not duplicates, and waste nothing.
+	out := dupes(t, "-action", "link", root)
+	if !strings.HasSuffix(out, "1 duplicate files, 5 bytes wasted\n") || strings.Contains(out, ba) {
+		t.Errorf("second -action=link printed:\n%s", out)
+	}
+	if !sameFile(t, a, c) {
+		t.Error("c was not linked to a")
+	}
+	if out := dupes(t, root); out != "0 duplicate files, 0 bytes wasted\n" {
+		t.Errorf("after linking, printed:\n%s", out)
+	}
+
+	root = tree(t, files)
+	dupes(t, "-action", "delete", root)
+	for name := range files {
+		_, err := os.Stat(filepath.Join(root, name))
+		if exists, kept := err == nil, name == "a" || name == "d"; exists != kept {
+			t.Errorf("after -action=delete, %s exists = %t", name, exists)
+		}
+	}
+
+	if err := run([]string{"-action", "rename", root}, new(bytes.Buffer)); err == nil {
+		t.Error("-action=rename succeeded")
+	}
+}
@@ -0,0 +1,192 @@
+// Dupes finds duplicate files in the directory trees named on the
+// command line and reports them, or replaces them with hard links,
+// or deletes them.
+//
+// Like du4, it walks each directory in its own goroutine, with a
+// counting semaphore limiting the number open at once. Candidates
+// are narrowed in stages, so that most files are never read in full:
+// first by size, then by the SHA-256 of their first few kilobytes,
+// and finally by the SHA-256 of their entire contents. Hashing too
+// proceeds in parallel.
+//
+// In each group of duplicates the file with the lexically first path
+// is kept; with -action=link or -action=delete the others are
+// replaced by hard links to it, or removed. Use -n to see what would
+// be done without doing it.
+//
+// Usage:
+//
+//	dupes [-min 1] [-action report|link|delete] [-n] [dir...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gopl.io/ch8/fileid"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "dupes: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+// run reports the duplicates under the roots named in args, or under
+// the current directory, on w, and links or deletes them as directed.
+func run(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("dupes", flag.ContinueOnError)
+	minSize := fs.Int64("min", 1, "ignore files smaller than this many bytes")
+	action := fs.String("action", "report", "what to do with duplicates: report, link or delete")
+	dryRun := fs.Bool("n", false, "dry run: print what -action would do, but don't do it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	switch *action {
+	case "report", "link", "delete":
+	default:
+		return fmt.Errorf("unknown action %q", *action)
+	}
+
+	groups := findDupes(roots, *minSize)
+
+	var nfiles, wasted int64
+	for _, g := range groups {
+		nfiles += int64(g.files - 1)
+		wasted += g.waste()
+		fmt.Fprintf(w, "%d files of %d bytes, sha256 %.16s\n", len(g.paths), g.size, g.hash)
+		fmt.Fprintf(w, "\t%s\n", g.paths[0])
+		for _, dup := range g.paths[1:] {
+			switch *action {
+			case "report":
+				fmt.Fprintf(w, "\t%s\n", dup)
+			case "link":
+				fmt.Fprintf(w, "\t%s (link)\n", dup)
+				if !*dryRun {
+					check(replaceWithLink(g.paths[0], dup))
+				}
+			case "delete":
+				fmt.Fprintf(w, "\t%s (delete)\n", dup)
+				if !*dryRun {
+					check(os.Remove(dup))
+				}
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d duplicate files, %d bytes wasted\n", nfiles, wasted)
+	return nil
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dupes: %v\n", err)
+	}
+}
+
+// replaceWithLink replaces dup by a hard link to keep.
+// The link is made under a temporary name and renamed over dup,
+// so dup is never missing.
+func replaceWithLink(keep, dup string) error {
+	tmp := dup + ".dupes-tmp"
+	if err := os.Link(keep, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dup); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// A group is a set of files with identical contents.
+type group struct {
+	size  int64
+	hash  string   // hex SHA-256 of contents
+	paths []string // sorted
+	files int      // number of distinct files, not counting hard links
+}
+
+// waste returns the number of bytes taken by all but one of the
+// files in g.
+func (g group) waste() int64 { return int64(g.files-1) * g.size }
+
+// findDupes returns the groups of duplicate files, of at least min
+// bytes, under roots, largest waste first. Several hard links to the
+// same file are not duplicates of each other.
+func findDupes(roots []string, min int64) []group {
+	// Stage 1: group by size.
+	bySize := make(map[int64][]file)
+	for f := range walk(roots) {
+		if f.size >= min {
+			bySize[f.size] = append(bySize[f.size], f)
+		}
+	}
+	var candidates [][]file
+	for _, files := range bySize {
+		if files = distinct(files); len(files) > 1 {
+			candidates = append(candidates, files)
+		}
+	}
+
+	// Stages 2 and 3: group by hash of prefix, then of whole file.
+	candidates, _ = refine(candidates, partialSize)
+	candidates, hashes := refine(candidates, -1)
+
+	var groups []group
+	for i, files := range candidates {
+		g := group{size: files[0].size, hash: hashes[i], files: countFiles(files)}
+		for _, f := range files {
+			g.paths = append(g.paths, f.path)
+		}
+		sort.Strings(g.paths)
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		wi, wj := groups[i].waste(), groups[j].waste()
+		if wi != wj {
+			return wi > wj
+		}
+		return groups[i].paths[0] < groups[j].paths[0]
+	})
+	return groups
+}
+
+// distinct removes from files any further links to the same file.
+func distinct(files []file) []file {
+	seen := make(map[fileid.ID]bool)
+	var out []file
+	for _, f := range files {
+		if f.idOK {
+			if seen[f.id] {
+				continue
+			}
+			seen[f.id] = true
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// countFiles returns the number of distinct files in files,
+// counting each name whose identity is unknown as a file.
+func countFiles(files []file) int {
+	ids := make(map[fileid.ID]bool)
+	n := 0
+	for _, f := range files {
+		if !f.idOK {
+			n++
+		} else if !ids[f.id] {
+			ids[f.id] = true
+			n++
+		}
+	}
+	return n
+}
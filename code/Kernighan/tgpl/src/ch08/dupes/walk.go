@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopl.io/ch8/fileid"
+)
+
+// A file is a regular file found by walk.
+type file struct {
+	path string
+	size int64
+	id   fileid.ID
+	idOK bool // id is known
+}
+
+// walk traverses the file trees rooted at roots in parallel
+// and sends each regular file found on the returned channel,
+// which is closed when the traversal is complete.
+func walk(roots []string) <-chan file {
+	files := make(chan file)
+	var n sync.WaitGroup
+	for _, root := range roots {
+		n.Add(1)
+		go walkDir(root, &n, files)
+	}
+	go func() {
+		n.Wait()
+		close(files)
+	}()
+	return files
+}
+
+// walkDir recursively walks the file tree rooted at dir
+// and sends each regular file found on files.
+func walkDir(dir string, n *sync.WaitGroup, files chan<- file) {
+	defer n.Done()
+	for _, entry := range dirents(dir) {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			n.Add(1)
+			go walkDir(path, n, files)
+		} else if entry.Mode().IsRegular() {
+			id, _, ok := fileid.Identify(entry)
+			files <- file{path, entry.Size(), id, ok}
+		}
+	}
+}
+
+var sema = make(chan struct{}, 20) // concurrency-limiting counting semaphore
+
+// dirents returns the entries of directory dir.
+func dirents(dir string) []os.FileInfo {
+	sema <- struct{}{}        // acquire token
+	defer func() { <-sema }() // release token
+
+	f, err := os.Open(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dupes: %v\n", err)
+		return nil
+	}
+	defer f.Close()
+
+	entries, err := f.Readdir(0) // 0 => no limit; read all entries
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dupes: %v\n", err)
+		// Don't return: Readdir may return partial results.
+	}
+	return entries
+}
+
+// partialSize is the length of the prefix hashed in the second stage.
+const partialSize = 4096
+
+// refine hashes the first limit bytes of every file in groups (all of
+// it, if limit < 0) in parallel, and splits each group by hash. It
+// returns the resulting groups of two or more files, and their hashes.
+// Files that cannot be read are reported and dropped.
+func refine(groups [][]file, limit int64) ([][]file, []string) {
+	type result struct {
+		group, index int
+		hash         string
+	}
+	results := make(chan result)
+	var n sync.WaitGroup
+	for i, files := range groups {
+		for j, f := range files {
+			n.Add(1)
+			go func(i, j int, path string) {
+				defer n.Done()
+				h, err := hashFile(path, limit)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "dupes: %v\n", err)
+					h = "" // drop
+				}
+				results <- result{i, j, h}
+			}(i, j, f.path)
+		}
+	}
+	go func() {
+		n.Wait()
+		close(results)
+	}()
+
+	hashes := make([][]string, len(groups))
+	for i, files := range groups {
+		hashes[i] = make([]string, len(files))
+	}
+	for r := range results {
+		hashes[r.group][r.index] = r.hash
+	}
+
+	var out [][]file
+	var outHashes []string
+	for i, files := range groups {
+		byHash := make(map[string][]file)
+		var order []string
+		for j, f := range files {
+			h := hashes[i][j]
+			if h == "" {
+				continue
+			}
+			if byHash[h] == nil {
+				order = append(order, h)
+			}
+			byHash[h] = append(byHash[h], f)
+		}
+		for _, h := range order {
+			if len(byHash[h]) > 1 {
+				out = append(out, byHash[h])
+				outHashes = append(outHashes, h)
+			}
+		}
+	}
+	return out, outHashes
+}
+
+// hashFile returns the hex SHA-256 of the first limit bytes
+// of the named file, or of all of it if limit < 0.
+func hashFile(path string, limit int64) (string, error) {
+	sema <- struct{}{}        // acquire token
+	defer func() { <-sema }() // release token
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("reading %s: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
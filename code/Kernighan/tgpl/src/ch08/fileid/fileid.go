@@ -0,0 +1,7 @@
+// Package fileid identifies files independent of their names,
+// so that programs such as du5 and dupes can recognize hard links.
+package fileid
+
+// An ID identifies a file: two names with the same ID are hard links
+// to the same file.
+type ID struct{ Dev, Ino uint64 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package fileid
+
+import "os"
+
+// Identify reports false: on this platform, files cannot be
+// identified, so hard links are not detected.
+func Identify(info os.FileInfo) (id ID, nlink uint64, ok bool) {
+	return ID{}, 0, false
+}
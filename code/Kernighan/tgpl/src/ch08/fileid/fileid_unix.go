@@ -0,0 +1,18 @@
+//go:build unix
+
+package fileid
+
+import (
+	"os"
+	"syscall"
+)
+
+// Identify returns the identity of the file described by info and its
+// number of hard links. It reports false if they cannot be determined.
+func Identify(info os.FileInfo) (id ID, nlink uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ID{}, 0, false
+	}
+	return ID{uint64(st.Dev), uint64(st.Ino)}, uint64(st.Nlink), true
+}
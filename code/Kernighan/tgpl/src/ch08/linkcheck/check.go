@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopl.io/ch5/links"
+)
+
+// A checker checks the links of one site.
+type checker struct {
+	client   *http.Client
+	tokens   chan struct{} // counting semaphore bounding concurrent requests
+	external bool          // check links to other hosts
+	host     string        // the site's host
+}
+
+// A ref is a reference to a URL from a page.
+type ref struct {
+	url  string // including any fragment
+	from string // the referring page; "" for the starting URL
+	text string // anchor text
+}
+
+// A result is the outcome of checking one URL.
+type result struct {
+	url    string
+	status int             // HTTP status; 0 if no response
+	err    error           // request error, if any
+	ids    map[string]bool // fragment identifiers, if the page was parsed
+	refs   []ref           // links found on the page, if it was parsed
+}
+
+// A Problem is a broken reference.
+type Problem struct {
+	URL    string `json:"url"`
+	From   string `json:"from,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error"`
+}
+
+// A Report is the outcome of checking a site.
+type Report struct {
+	Checked  []string  `json:"checked"` // URLs checked, without fragments
+	Problems []Problem `json:"problems"`
+}
+
+// run checks start and every link reachable from it on the same host.
+func (c *checker) run(start string) (*Report, error) {
+	u, err := url.Parse(start)
+	if err != nil {
+		return nil, err
+	}
+	c.host = u.Host
+
+	worklist := make(chan *result)
+	var n int // number of pending sends to worklist
+
+	results := make(map[string]*result)
+	refs := make(map[string][]ref) // by URL without fragment
+
+	// visit records references and starts checking any new URLs.
+	visit := func(list []ref) {
+		for _, r := range list {
+			key, internal, ok := c.classify(r.url)
+			if !ok {
+				continue
+			}
+			refs[key] = append(refs[key], r)
+			if _, seen := results[key]; !seen {
+				results[key] = nil // pending
+				n++
+				go func() { worklist <- c.check(key, internal) }()
+			}
+		}
+	}
+
+	visit([]ref{{url: start}})
+	for ; n > 0; n-- {
+		res := <-worklist
+		results[res.url] = res
+		visit(res.refs)
+	}
+
+	rep := new(Report)
+	for key, res := range results {
+		rep.Checked = append(rep.Checked, key)
+		rep.Problems = append(rep.Problems, problems(res, refs[key])...)
+	}
+	sort.Strings(rep.Checked)
+	sort.Slice(rep.Problems, func(i, j int) bool {
+		p, q := rep.Problems[i], rep.Problems[j]
+		if p.URL != q.URL {
+			return p.URL < q.URL
+		}
+		return p.From < q.From
+	})
+	return rep, nil
+}
+
+// classify returns the URL to check for link, without fragment,
+// and whether it is on the site being checked. It reports false
+// for links that should not be checked at all.
+func (c *checker) classify(link string) (key string, internal, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return "", false, false
+	}
+	u.Fragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	internal = u.Host == c.host
+	if !internal && !c.external {
+		return "", false, false
+	}
+	return u.String(), internal, true
+}
+
+// check requests rawurl. Internal HTML pages are fetched and parsed
+// for links and fragment identifiers; external URLs are only probed.
+func (c *checker) check(rawurl string, internal bool) *result {
+	c.tokens <- struct{}{} // acquire a token
+	defer func() { <-c.tokens }()
+
+	res := &result{url: rawurl}
+	if !internal {
+		resp, err := c.client.Head(rawurl)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed &&
+			resp.StatusCode != http.StatusNotImplemented {
+			res.status = resp.StatusCode
+			return res
+		}
+		// The server rejects HEAD; try GET.
+	}
+
+	resp, err := c.client.Get(rawurl)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer resp.Body.Close()
+	res.status = resp.StatusCode
+	if !internal || resp.StatusCode != http.StatusOK ||
+		resp.Request.URL.Host != c.host || // redirected off the site
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return res
+	}
+
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		res.err = fmt.Errorf("parsing %s as HTML: %v", rawurl, err)
+		return res
+	}
+	res.ids = links.IDs(doc)
+	for _, a := range links.Anchors(doc, resp.Request.URL) {
+		res.refs = append(res.refs, ref{url: a.URL, from: rawurl, text: a.Text})
+	}
+	return res
+}
+
+// problems returns the broken references among refs to the URL of res.
+func problems(res *result, refs []ref) []Problem {
+	var msg string
+	switch {
+	case res.err != nil:
+		msg = res.err.Error()
+		var nerr net.Error
+		if errors.As(res.err, &nerr) && nerr.Timeout() {
+			msg = "timeout"
+		}
+	case res.status >= 400:
+		msg = fmt.Sprintf("%d %s", res.status, http.StatusText(res.status))
+	}
+
+	var probs []Problem
+	for _, r := range refs {
+		p := Problem{URL: r.url, From: r.from, Text: r.text, Status: res.status}
+		if msg != "" {
+			p.Error = msg
+			probs = append(probs, p)
+			continue
+		}
+		u, _ := url.Parse(r.url)
+		if u.Fragment != "" && res.ids != nil && !res.ids[u.Fragment] {
+			p.Error = "no element with id " + u.Fragment
+			probs = append(probs, p)
+		}
+	}
+	return probs
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkcheck(t *testing.T) {
+	ext := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/nohead":
+			if r.Method == "HEAD" {
+				http.Error(w, "no HEAD", http.StatusMethodNotAllowed)
+			}
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.Error(w, "oops", http.StatusInternalServerError)
+		}
+	}))
+	defer ext.Close()
+
+	pages := map[string]string{
+		"/": `<h1 id="top">Home</h1>
+			<a href="/a">Page <b>A</b></a>
+			<a href="/a#sec">Section</a>
+			<a href="/a#nope">Nowhere</a>
+			<a href="/missing">Missing page</a>
+			<a href="mailto:x@example.com">Mail</a>
+			<a href="EXT/ok">ok</a> <a href="EXT/nohead">nohead</a>
+			<a href="EXT/slow">slow</a> <a href="EXT/err">err</a>`,
+		"/a": `<p id="sec">A</p><a name="old"></a>
+			<a href="/#top">Top</a> <a href="/#old">Old</a> <a href="/a#old">Self</a>`,
+	}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, strings.Replace(body, "EXT", ext.URL, -1))
+	}))
+	defer site.Close()
+
+	c := &checker{
+		client:   &http.Client{Timeout: 100 * time.Millisecond},
+		tokens:   make(chan struct{}, 4),
+		external: true,
+	}
+	rep, err := c.run(site.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range rep.Problems {
+		u := strings.NewReplacer(site.URL, "", ext.URL, "EXT").Replace(p.URL)
+		from := strings.Replace(p.From, site.URL, "", -1)
+		got = append(got, fmt.Sprintf("%s %q -> %s: %s", from, p.Text, u, p.Error))
+	}
+	sort.Strings(got)
+	want := []string{
+		`/ "Missing page" -> /missing: 404 Not Found`,
+		`/ "Nowhere" -> /a#nope: no element with id nope`,
+		`/ "err" -> EXT/err: 500 Internal Server Error`,
+		`/ "slow" -> EXT/slow: timeout`,
+		`/a "Old" -> /#old: no element with id old`,
+	}
+	if g, w := strings.Join(got, "\n"), strings.Join(want, "\n"); g != w {
+		t.Errorf("problems:\n%s\nwant:\n%s", g, w)
+	}
+	if len(rep.Checked) != 7 {
+		t.Errorf("checked %d URLs, want 7: %v", len(rep.Checked), rep.Checked)
+	}
+
+	// Each format should at least produce well-formed output.
+	for name, write := range writers {
+		var buf bytes.Buffer
+		if err := write(&buf, rep); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	var buf bytes.Buffer
+	writeJSON(&buf, rep)
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Problems) != len(rep.Problems) {
+		t.Errorf("JSON round trip: %v, %d problems", err, len(decoded.Problems))
+	}
+	buf.Reset()
+	writeJUnit(&buf, rep)
+	if s := buf.String(); !strings.Contains(s, `tests="9" failures="5"`) {
+		t.Errorf("JUnit output:\n%s", s)
+	}
+}
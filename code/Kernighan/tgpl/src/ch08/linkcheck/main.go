@@ -0,0 +1,65 @@
+// Linkcheck reports broken links on a web site.
+//
+// Starting from a URL, it crawls every page on the same host and
+// checks every link it finds there, internal or external, reporting
+// those that fail with a 4xx or 5xx status, time out, or refer to a
+// #fragment not defined by the target page, along with the page
+// and anchor text of each reference.
+//
+// Like gopl.io/ch8/crawl2, it uses a buffered channel as a counting
+// semaphore to bound the number of concurrent requests.
+// External links are checked with HEAD (falling back to GET for
+// servers that reject it) and are not followed further; fragments
+// are validated only for pages on the starting host.
+//
+// Usage:
+//
+//	linkcheck [-workers 20] [-timeout 10s] [-format text|json|junit] url
+//
+// The exit status is 1 if any problems are found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+var (
+	workers  = flag.Int("workers", 20, "max concurrent requests")
+	timeout  = flag.Duration("timeout", 10*time.Second, "timeout for each request")
+	format   = flag.String("format", "text", "output format: text, json or junit")
+	external = flag.Bool("external", true, "check links to other hosts")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: linkcheck [flags] url")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	write, ok := writers[*format]
+	if !ok {
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	c := &checker{
+		client:   &http.Client{Timeout: *timeout},
+		tokens:   make(chan struct{}, *workers),
+		external: *external,
+	}
+	rep, err := c.run(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := write(os.Stdout, rep); err != nil {
+		log.Fatal(err)
+	}
+	if len(rep.Problems) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// writers maps each -format to the function that writes a Report in it.
+var writers = map[string]func(io.Writer, *Report) error{
+	"text":  writeText,
+	"json":  writeJSON,
+	"junit": writeJUnit,
+}
+
+func writeText(w io.Writer, rep *Report) error {
+	for _, p := range rep.Problems {
+		if p.From == "" {
+			fmt.Fprintf(w, "%s: %s\n", p.URL, p.Error)
+		} else {
+			fmt.Fprintf(w, "%s: %q -> %s: %s\n", p.From, p.Text, p.URL, p.Error)
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d links checked, %d problems\n", len(rep.Checked), len(rep.Problems))
+	return err
+}
+
+func writeJSON(w io.Writer, rep *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// JUnit XML, as understood by most CI systems.
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Class   string        `xml:"classname,attr"`
+	Name    string        `xml:"name,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit reports each URL checked as a test case, and each URL
+// with a fragment as a separate case if the fragment is missing.
+// A case fails if any reference to its URL is broken.
+func writeJUnit(w io.Writer, rep *Report) error {
+	byURL := make(map[string][]Problem)
+	var fragURLs []string
+	for _, p := range rep.Problems {
+		if byURL[p.URL] == nil && strings.Contains(p.URL, "#") {
+			fragURLs = append(fragURLs, p.URL)
+		}
+		byURL[p.URL] = append(byURL[p.URL], p)
+	}
+
+	suite := junitSuite{Name: "linkcheck"}
+	for _, u := range append(rep.Checked, fragURLs...) {
+		c := junitCase{Class: host(u), Name: u}
+		if probs := byURL[u]; len(probs) > 0 {
+			var text strings.Builder
+			for _, p := range probs {
+				if p.From != "" {
+					fmt.Fprintf(&text, "linked from %s (%q)\n", p.From, p.Text)
+				}
+			}
+			c.Failure = &junitFailure{Message: probs[0].Error, Text: text.String()}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func host(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
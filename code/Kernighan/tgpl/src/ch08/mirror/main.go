@@ -0,0 +1,195 @@
+// Mirror crawls the sites named on the command line and saves a
+// browsable offline copy of each under the output directory,
+// in a host/path tree.
+//
+// Links between mirrored pages are rewritten as relative paths
+// to the local copies. Pages whose Last-Modified time has not
+// changed since the previous run are not downloaded again.
+// Mirror also writes a sitemap.xml for each host and a report,
+// broken-links.txt, of links that could not be fetched, including
+// links to other sites, which are checked but not copied.
+//
+// Usage:
+//
+//	mirror [-o dir] [-depth 0] [-per-host 2] [-delay 0] url...
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopl.io/ch8/crawler"
+)
+
+var (
+	outDir    = flag.String("o", "mirror", "output directory")
+	depth     = flag.Int("depth", 0, "max links to follow from a seed; 0 => no limit")
+	workers   = flag.Int("workers", 20, "max concurrent fetches")
+	perHost   = flag.Int("per-host", 2, "max concurrent fetches per host")
+	delay     = flag.Duration("delay", 0, "min interval between requests to a host")
+	stateFile = flag.String("state", "", "file in which to save the frontier of an interrupted crawl")
+	agent     = flag.String("agent", "Go-http-client", "User-Agent header, and agent name matched in robots.txt")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: mirror [flags] url...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	hosts := make(map[string]bool)
+	for _, arg := range flag.Args() {
+		u, err := url.Parse(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hosts[u.Host] = true
+	}
+	if err := os.MkdirAll(*outDir, 0777); err != nil {
+		log.Fatal(err)
+	}
+	m, err := newMirror(*outDir, hosts, http.DefaultClient, *agent)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := crawler.New(crawler.Config{
+		MaxDepth:  *depth,
+		SameHost:  true,
+		Workers:   *workers,
+		PerHost:   *perHost,
+		Delay:     *delay,
+		StateFile: *stateFile,
+		UserAgent: *agent,
+		Client:    m.client,
+		Fetch:     m.fetch,
+	})
+	var fetched []string
+	broken := make(map[string]error)
+	err = c.Crawl(ctx, flag.Args(), func(p crawler.Page) {
+		switch {
+		case p.Err == crawler.ErrDisallowed:
+			// Not broken, just not ours to copy.
+		case p.Err != nil:
+			log.Print(p.Err)
+			broken[p.URL] = p.Err
+		default:
+			fmt.Println(p.URL)
+			fetched = append(fetched, p.URL)
+		}
+	})
+	if err := m.saveManifest(); err != nil {
+		log.Fatal(err)
+	}
+	if err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+	if err == nil {
+		for link, err := range m.check(m.external(), *workers) {
+			log.Print(err)
+			broken[link] = err
+		}
+	}
+
+	if err := writeSitemaps(m, fetched); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeBrokenLinks(m, broken); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d fetched (%d unchanged), %d broken", len(fetched), m.unchanged, len(broken))
+	if err == context.Canceled {
+		os.Exit(1)
+	}
+}
+
+// A urlset is the root element of a sitemap.xml file.
+type urlset struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// writeSitemaps writes host/sitemap.xml listing the HTML pages
+// fetched from each host.
+func writeSitemaps(m *mirror, fetched []string) error {
+	sort.Strings(fetched)
+	sitemaps := make(map[string]*urlset) // by host
+	for _, rawurl := range fetched {
+		e := m.manifest[rawurl]
+		if e == nil || !e.HTML {
+			continue
+		}
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			continue
+		}
+		s := sitemaps[u.Host]
+		if s == nil {
+			s = new(urlset)
+			sitemaps[u.Host] = s
+		}
+		var lastmod string
+		if t, err := http.ParseTime(e.LastModified); err == nil {
+			lastmod = t.UTC().Format(time.RFC3339)
+		}
+		s.URLs = append(s.URLs, sitemapURL{rawurl, lastmod})
+	}
+
+	for host, s := range sitemaps {
+		data, err := xml.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append([]byte(xml.Header), data...)
+		filename := filepath.Join(*outDir, sanitize(host), "sitemap.xml")
+		if err := os.WriteFile(filename, append(data, '\n'), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBrokenLinks writes broken-links.txt, listing each URL that
+// could not be fetched and the pages that link to it.
+func writeBrokenLinks(m *mirror, broken map[string]error) error {
+	f, err := os.Create(filepath.Join(*outDir, "broken-links.txt"))
+	if err != nil {
+		return err
+	}
+	var urls []string
+	for u := range broken {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	for _, u := range urls {
+		fmt.Fprintf(f, "%s\n\t%v\n", u, broken[u])
+		for _, r := range m.refs[u] {
+			if r.text != "" {
+				fmt.Fprintf(f, "\tlinked from %s (%q)\n", r.from, r.text)
+			} else {
+				fmt.Fprintf(f, "\tlinked from %s\n", r.from)
+			}
+		}
+	}
+	return f.Close()
+}
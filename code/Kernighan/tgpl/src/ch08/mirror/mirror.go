@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// An entry records what is known about one mirrored URL.
+// The manifest of entries is saved between runs so that
+// unchanged pages need not be downloaded again.
+type entry struct {
+	File         string            `json:"file"` // slash-separated, relative to the output directory
+	LastModified string            `json:"lastModified,omitempty"`
+	HTML         bool              `json:"html,omitempty"`
+	Links        []string          `json:"links,omitempty"` // absolute URLs, without fragments
+	Texts        map[string]string `json:"texts,omitempty"` // anchor text, by link
+}
+
+// A ref is a link from one page to another.
+type ref struct {
+	from string // URL of the referring page
+	text string // anchor text, if any
+}
+
+// A mirror saves fetched pages under dir.
+// Its fetch method serves as a crawler.Config.Fetch function.
+type mirror struct {
+	dir    string
+	hosts  map[string]bool // links to these hosts and their subdomains are rewritten to local paths
+	client *http.Client    // as in the crawler's Config
+	agent  string          // User-Agent header, as in the crawler's Config
+
+	mu        sync.Mutex
+	manifest  map[string]*entry      // by URL
+	refs      map[string][]ref       // by target URL
+	files     map[string]*sync.Mutex // serializes writes to each file
+	unchanged int                    // pages skipped because not modified
+}
+
+const manifestName = ".mirror.json"
+
+// newMirror returns a mirror that saves pages under dir, making
+// requests with client and sending agent as the User-Agent header.
+func newMirror(dir string, hosts map[string]bool, client *http.Client, agent string) (*mirror, error) {
+	m := &mirror{
+		dir:      dir,
+		hosts:    hosts,
+		client:   client,
+		agent:    agent,
+		manifest: make(map[string]*entry),
+		refs:     make(map[string][]ref),
+		files:    make(map[string]*sync.Mutex),
+	}
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m.manifest); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", manifestName, err)
+	}
+	return m, nil
+}
+
+func (m *mirror) saveManifest() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m.manifest, "", "\t")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dir, manifestName), data, 0666)
+}
+
+// fetch downloads rawurl, saves it under m.dir, and returns its links.
+// If the page has not been modified since it was last saved, as
+// reported by its Last-Modified header, it is not downloaded again.
+func (m *mirror) fetch(rawurl string) ([]string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	old := m.manifest[rawurl]
+	m.mu.Unlock()
+
+	req, err := m.request("GET", rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if old != nil && old.LastModified != "" && exists(filepath.Join(m.dir, filepath.FromSlash(old.File))) {
+		req.Header.Set("If-Modified-Since", old.LastModified)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// A 304 is expected only in reply to If-Modified-Since,
+	// and so only if there is an old entry.
+	if resp.StatusCode == http.StatusNotModified && old != nil {
+		m.mu.Lock()
+		m.unchanged++
+		m.mu.Unlock()
+		m.addRefs(rawurl, old.Links, old.Texts)
+		return old.Links, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting %s: %s", rawurl, resp.Status)
+	}
+
+	e := &entry{
+		File:         localPath(u),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	var body io.Reader = resp.Body
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		doc, err := html.Parse(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s as HTML: %v", rawurl, err)
+		}
+		// Links are relative to the final URL, after any redirects.
+		e.HTML = true
+		e.Links, e.Texts = m.rewrite(doc, resp.Request.URL, e.File)
+		m.addRefs(rawurl, e.Links, e.Texts)
+
+		var buf bytes.Buffer
+		if err := html.Render(&buf, doc); err != nil {
+			return nil, err
+		}
+		body = &buf
+	}
+	if err := m.save(e.File, body); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.manifest[rawurl] = e
+	m.mu.Unlock()
+	return e.Links, nil
+}
+
+// save writes the contents of r to the slash-separated file name,
+// relative to m.dir, creating directories as needed.
+// Different URLs, such as /a and /a/, may be saved in the same file,
+// so writes to each file are serialized.
+func (m *mirror) save(name string, r io.Reader) error {
+	m.mu.Lock()
+	lock := m.files[name]
+	if lock == nil {
+		lock = new(sync.Mutex)
+		m.files[name] = lock
+	}
+	m.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	filename := filepath.Join(m.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (m *mirror) addRefs(from string, links []string, texts map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, link := range links {
+		m.refs[link] = append(m.refs[link], ref{from, texts[link]})
+	}
+}
+
+// mirrored reports whether pages on host are mirrored, that is,
+// whether host is one of m.hosts or a subdomain of one, like the
+// hosts followed by a crawler with SameHost set.
+func (m *mirror) mirrored(host string) bool {
+	if m.hosts[host] {
+		return true
+	}
+	for h := range m.hosts {
+		if strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// external returns the link targets on hosts that are not mirrored,
+// which the crawler does not follow, in order.
+func (m *mirror) external() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var urls []string
+	for link := range m.refs {
+		if u, err := url.Parse(link); err == nil && !m.mirrored(u.Host) {
+			urls = append(urls, link)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// request returns a request with the mirror's User-Agent header.
+func (m *mirror) request(method, rawurl string) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", m.agent)
+	return req, nil
+}
+
+// check requests each of urls, at most n at a time, and returns
+// the error for each one that could not be fetched. It tries HEAD
+// first, falling back to GET for servers that don't support it.
+func (m *mirror) check(urls []string, n int) map[string]error {
+	broken := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, n)
+	for _, link := range urls {
+		wg.Add(1)
+		go func(link string) {
+			defer wg.Done()
+			tokens <- struct{}{} // acquire a token
+			err := m.checkLink(link)
+			<-tokens // release the token
+			if err != nil {
+				mu.Lock()
+				broken[link] = err
+				mu.Unlock()
+			}
+		}(link)
+	}
+	wg.Wait()
+	return broken
+}
+
+func (m *mirror) checkLink(link string) error {
+	resp, err := m.do("HEAD", link)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed ||
+		resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = m.do("GET", link)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("getting %s: %s", link, resp.Status)
+	}
+	return nil
+}
+
+func (m *mirror) do(method, rawurl string) (*http.Response, error) {
+	req, err := m.request(method, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.Do(req)
+}
+
+// linkAttrs maps each element that refers to a page or asset
+// to the attribute holding its URL.
+var linkAttrs = map[string]string{
+	"a":      "href",
+	"link":   "href",
+	"img":    "src",
+	"script": "src",
+	"iframe": "src",
+	"source": "src",
+	"video":  "src",
+	"audio":  "src",
+}
+
+// rewrite finds the links in doc, whose URL is base and which will be
+// saved as file, and rewrites those to mirrored hosts as relative paths
+// to their local copies. It returns the links, made absolute and
+// without fragments, and the anchor text of each link from an <a>.
+func (m *mirror) rewrite(doc *html.Node, base *url.URL, file string) ([]string, map[string]string) {
+	var links []string
+	texts := make(map[string]string)
+	seen := make(map[string]bool)
+	forEachNode(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		key, ok := linkAttrs[n.Data]
+		if !ok {
+			return
+		}
+		for i, a := range n.Attr {
+			if a.Key != key {
+				continue
+			}
+			link, err := base.Parse(a.Val)
+			if err != nil || link.Scheme != "http" && link.Scheme != "https" {
+				continue // ignore bad URLs, mailto:, javascript:, etc.
+			}
+			frag := link.Fragment
+			link.Fragment = ""
+			if link.Path == "" {
+				link.Path = "/"
+			}
+			s := link.String()
+			if !seen[s] {
+				seen[s] = true
+				links = append(links, s)
+			}
+			if n.Data == "a" && texts[s] == "" {
+				texts[s] = strings.Join(strings.Fields(text(n)), " ")
+			}
+			if m.mirrored(link.Host) {
+				local := relPath(file, localPath(link))
+				if frag != "" {
+					local += "#" + frag
+				}
+				n.Attr[i].Val = local
+			}
+		}
+	}, nil)
+	return links, texts
+}
+
+// localPath returns the slash-separated path, relative to the output
+// directory, at which the resource u is saved: host/path, where a
+// path naming a directory (ending in a slash or lacking an extension)
+// gets an index.html file. A query, if any, is folded into the name.
+func localPath(u *url.URL) string {
+	p := u.Path
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	} else if path.Ext(p) == "" {
+		p += "/index.html"
+	}
+	if u.RawQuery != "" {
+		ext := path.Ext(p)
+		p = strings.TrimSuffix(p, ext) + "_" + sanitize(u.RawQuery) + ext
+	}
+	return path.Join(sanitize(u.Host), path.Clean("/"+p))
+}
+
+// sanitize replaces characters that are troublesome in file names.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9',
+			r == '-', r == '_', r == '.', r == '=':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// relPath returns the relative URL path from file from to file to,
+// both slash-separated and relative to the same directory.
+func relPath(from, to string) string {
+	fromDir := strings.Split(path.Dir(from), "/")
+	toParts := strings.Split(to, "/")
+	i := 0
+	for i < len(fromDir) && i < len(toParts)-1 && fromDir[i] == toParts[i] {
+		i++
+	}
+	var parts []string
+	for range fromDir[i:] {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, toParts[i:]...)
+	return strings.Join(parts, "/")
+}
+
+// text returns the concatenated text content of n.
+func text(n *html.Node) string {
+	var buf strings.Builder
+	forEachNode(n, func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+	}, nil)
+	return buf.String()
+}
+
+func exists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// Copied from gopl.io/ch5/outline2.
+func forEachNode(n *html.Node, pre, post func(n *html.Node)) {
+	if pre != nil {
+		pre(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		forEachNode(c, pre, post)
+	}
+	if post != nil {
+		post(n)
+	}
+}
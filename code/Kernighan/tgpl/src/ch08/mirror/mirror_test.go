@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+	"gopl.io/ch8/crawler"
+)
+
+func TestLocalPath(t *testing.T) {
+	for _, test := range []struct {
+		url, want string
+	}{
+		{"http://example.com", "example.com/index.html"},
+		{"http://example.com/", "example.com/index.html"},
+		{"http://example.com/a/b.png", "example.com/a/b.png"},
+		{"http://example.com/a/", "example.com/a/index.html"},
+		{"http://example.com/a", "example.com/a/index.html"},
+		{"http://example.com/a.html?x=1&y=2", "example.com/a_x=1_y=2.html"},
+		{"http://example.com:8080/../../etc/passwd", "example.com_8080/etc/passwd/index.html"},
+	} {
+		u, _ := url.Parse(test.url)
+		if got := localPath(u); got != test.want {
+			t.Errorf("localPath(%s) = %s, want %s", test.url, got, test.want)
+		}
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	for _, test := range []struct {
+		from, to, want string
+	}{
+		{"h/index.html", "h/a/index.html", "a/index.html"},
+		{"h/a/index.html", "h/index.html", "../index.html"},
+		{"h/a/index.html", "h/b/c.png", "../b/c.png"},
+		{"h/a/index.html", "h/a/x.css", "x.css"},
+		{"h/index.html", "g/index.html", "../g/index.html"},
+	} {
+		if got := relPath(test.from, test.to); got != test.want {
+			t.Errorf("relPath(%s, %s) = %s, want %s", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	// Another site, whose links are checked but not followed.
+	ext := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer ext.Close()
+
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	pages := map[string]string{
+		"/":           `<a href="/docs/">Docs</a> <img src="logo.png"> <a href="` + ext.URL + `/">Away</a>`,
+		"/docs/":      `<a href="../#top">Home</a> <a href="/missing">Gone</a> <a href="` + ext.URL + `/lost">Lost</a>`,
+		"/logo.png":   "PNG",
+		"/robots.txt": "",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "image/png")
+		}
+		http.ServeContent(w, r, "", modified, strings.NewReader(body))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	u, _ := url.Parse(ts.URL)
+	run := func() (*mirror, map[string]bool) {
+		m, err := newMirror(dir, map[string]bool{u.Host: true}, http.DefaultClient, "mirror-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := crawler.New(crawler.Config{SameHost: true, UserAgent: m.agent, Client: m.client, Fetch: m.fetch})
+		broken := make(map[string]bool)
+		c.Crawl(context.Background(), []string{ts.URL}, func(p crawler.Page) {
+			if p.Err != nil {
+				broken[p.URL] = true
+			}
+		})
+		if err := m.saveManifest(); err != nil {
+			t.Fatal(err)
+		}
+		return m, broken
+	}
+
+	m, broken := run()
+	if !broken[ts.URL+"/missing"] || len(broken) != 1 {
+		t.Errorf("broken = %v, want only /missing", broken)
+	}
+	if refs := m.refs[ts.URL+"/missing"]; len(refs) != 1 || refs[0].from != ts.URL+"/docs/" || refs[0].text != "Gone" {
+		t.Errorf("refs to /missing = %v", refs)
+	}
+	if got, want := strings.Join(m.external(), " "), ext.URL+"/ "+ext.URL+"/lost"; got != want {
+		t.Errorf("external links = %s, want %s", got, want)
+	}
+	if errs := m.check(m.external(), 2); errs[ext.URL+"/lost"] == nil || len(errs) != 1 {
+		t.Errorf("external link errors = %v, want only /lost", errs)
+	}
+
+	host := sanitize(u.Host)
+	index, err := os.ReadFile(filepath.Join(dir, host, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`href="docs/index.html"`, `src="logo.png"`, `href="` + ext.URL + `/"`} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.html lacks %s:\n%s", want, index)
+		}
+	}
+	docs, err := os.ReadFile(filepath.Join(dir, host, "docs", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(docs), `href="../index.html#top"`) {
+		t.Errorf("docs/index.html lacks link home:\n%s", docs)
+	}
+	if logo, _ := os.ReadFile(filepath.Join(dir, host, "logo.png")); string(logo) != "PNG" {
+		t.Errorf("logo.png = %q, want PNG", logo)
+	}
+
+	// A second run finds nothing modified, but still knows the link text.
+	m, _ = run()
+	if m.unchanged != 3 {
+		t.Errorf("second run: %d unchanged, want 3", m.unchanged)
+	}
+	if refs := m.refs[ts.URL+"/missing"]; len(refs) != 1 || refs[0].text != "Gone" {
+		t.Errorf("second run: refs to /missing = %v", refs)
+	}
+}
+
+func TestRewriteSubdomain(t *testing.T) {
+	m, err := newMirror(t.TempDir(), map[string]bool{"example.com": true}, http.DefaultClient, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := html.Parse(strings.NewReader(`<a href="http://www.example.com/a/">A</a>` +
+		`<a href="http://example.com/b">B</a><a href="http://notexample.com/">C</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/")
+	m.rewrite(doc, base, "example.com/index.html")
+	var got []string
+	forEachNode(doc, func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			got = append(got, n.Attr[0].Val)
+		}
+	}, nil)
+	want := "../www.example.com/a/index.html b/index.html http://notexample.com/"
+	if strings.Join(got, " ") != want {
+		t.Errorf("rewritten links = %s, want %s", got, want)
+	}
+}
+
+// TestSameFile checks that URLs saved in the same file,
+// such as /a and /a/, don't corrupt it when fetched at once.
+func TestSameFile(t *testing.T) {
+	bodies := map[string]string{
+		"/a":  strings.Repeat("x", 1<<20),
+		"/a/": strings.Repeat("y", 1<<19),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, bodies[r.URL.Path])
+	}))
+	defer ts.Close()
+
+	m, err := newMirror(t.TempDir(), nil, http.DefaultClient, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for p := range bodies {
+			wg.Add(1)
+			go func(p string) {
+				defer wg.Done()
+				if _, err := m.fetch(ts.URL + p); err != nil {
+					t.Error(err)
+				}
+			}(p)
+		}
+	}
+	wg.Wait()
+	u, _ := url.Parse(ts.URL + "/a")
+	data, err := os.ReadFile(filepath.Join(m.dir, filepath.FromSlash(localPath(u))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); s != bodies["/a"] && s != bodies["/a/"] {
+		t.Errorf("%s has %d bytes, mixing both pages", localPath(u), len(data))
+	}
+}
+
+// TestNotModified checks that a 304 in reply to an unconditional
+// request is an error, and that requests carry the User-Agent.
+func TestNotModified(t *testing.T) {
+	var agents []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents = append(agents, r.Method+" "+r.UserAgent())
+		mu.Unlock()
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer ts.Close()
+
+	m, err := newMirror(t.TempDir(), nil, ts.Client(), "mirror-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.fetch(ts.URL + "/"); err == nil || !strings.Contains(err.Error(), "304") {
+		t.Errorf("fetch of unknown page got 304: err = %v, want error", err)
+	}
+	m.check([]string{ts.URL + "/x"}, 1)
+	if got, want := strings.Join(agents, ", "), "GET mirror-test, HEAD mirror-test"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
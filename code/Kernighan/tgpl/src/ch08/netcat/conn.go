@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+)
+
+// dial connects to addr on the named network,
+// then, if config is not nil, performs a TLS handshake.
+func dial(network, addr string, config *tls.Config) (net.Conn, error) {
+	if config != nil && network == "udp" {
+		return nil, fmt.Errorf("TLS over UDP is not supported")
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return conn, nil
+	}
+	if config.ServerName == "" && network == "tcp" {
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tc := tls.Client(conn, config)
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// accept waits for a connection to addr on the named network, then,
+// if config is not nil, performs a TLS handshake. It stops listening
+// after the first connection. For UDP, the first datagram received
+// determines the peer, and later datagrams from anyone else are
+// ignored.
+func accept(network, addr string, config *tls.Config) (net.Conn, error) {
+	if network == "udp" {
+		if config != nil {
+			return nil, fmt.Errorf("TLS over UDP is not supported")
+		}
+		return acceptUDP(addr)
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	if *verbose {
+		log.Printf("listening on %s", l.Addr())
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return conn, nil
+	}
+	tc := tls.Server(conn, config)
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+func acceptUDP(addr string) (net.Conn, error) {
+	laddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	if *verbose {
+		log.Printf("listening on %s", conn.LocalAddr())
+	}
+	// Wait for the first datagram, and keep it for the first Read.
+	buf := make([]byte, 64*1024)
+	n, peer, err := conn.ReadFrom(buf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &udpPeer{UDPConn: conn, peer: peer, pending: buf[:n]}, nil
+}
+
+// A udpPeer is a listening UDP socket that
+// exchanges datagrams with a single peer.
+type udpPeer struct {
+	*net.UDPConn
+	peer    net.Addr
+	pending []byte // first datagram, not yet read
+}
+
+func (c *udpPeer) Read(p []byte) (int, error) {
+	if c.pending != nil {
+		n := copy(p, c.pending)
+		c.pending = nil
+		return n, nil
+	}
+	for {
+		n, addr, err := c.ReadFrom(p)
+		if err != nil || addr.String() == c.peer.String() {
+			return n, err
+		}
+	}
+}
+
+func (c *udpPeer) Write(p []byte) (int, error) { return c.WriteTo(p, c.peer) }
+
+func (c *udpPeer) RemoteAddr() net.Addr { return c.peer }
+
+// closeWrite closes the write half of conn, if it has one,
+// so that the peer reads end of file.
+func closeWrite(conn net.Conn) error {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil // e.g., UDP
+}
+
+// isTimeout reports whether err is the expiry of a deadline.
+func isTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,153 @@
+// Netcat is a read/write client and server for TCP, UDP and Unix
+// domain sockets, optionally over TLS.
+//
+// It generalizes gopl.io/ch8/netcat3. In dial mode it connects to the
+// address; with -l it waits for one connection to the address. Either
+// way it copies the standard input to the connection and the
+// connection to the standard output. When the standard input is
+// exhausted, netcat closes only the write half of the connection, so
+// the peer sees end of file while netcat goes on printing its replies;
+// for example, every echo from gopl.io/ch8/reverb3 arrives:
+//
+//	echo hello | netcat localhost:8000
+//
+// Netcat exits when the peer closes the connection, or, with -w,
+// when nothing has been received for that long. (A UDP "connection"
+// has no end of its own.)
+//
+// Usage:
+//
+//	netcat [-u | -U] [-tls] [flags] host:port|path
+//	netcat -l [-u | -U] [-tls -cert file -key file] [flags] [host]:port|path
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+)
+
+var (
+	listen   = flag.Bool("l", false, "listen for one incoming connection instead of dialing")
+	udp      = flag.Bool("u", false, "use UDP instead of TCP")
+	unix     = flag.Bool("U", false, "use a Unix domain socket; the address is a path")
+	idle     = flag.Duration("w", 0, "quit when nothing has been received for this long; 0 => never")
+	hexdump  = flag.Bool("x", false, "hex dump traffic to the standard error")
+	verbose  = flag.Bool("v", false, "log connection events to the standard error")
+	useTLS   = flag.Bool("tls", false, "use TLS")
+	certFile = flag.String("cert", "", "TLS certificate file (PEM); required with -l -tls")
+	keyFile  = flag.String("key", "", "TLS private key file (PEM)")
+	caFile   = flag.String("ca", "", "verify the peer's certificate against the CAs in this file (PEM)")
+	insecure = flag.Bool("insecure", false, "dial mode: do not verify the server's certificate")
+	name     = flag.String("servername", "", "dial mode: expected server name (default: host of address)")
+)
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("netcat: ")
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: netcat [-l] [-u | -U] [-tls] [flags] address")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	addr := flag.Arg(0)
+
+	network := "tcp"
+	switch {
+	case *udp && *unix:
+		log.Fatal("-u and -U are mutually exclusive")
+	case *udp:
+		network = "udp"
+	case *unix:
+		network = "unix"
+	}
+
+	var config *tls.Config
+	if *useTLS {
+		var err error
+		if config, err = tlsConfig(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var conn net.Conn
+	var err error
+	if *listen {
+		conn, err = accept(network, addr, config)
+	} else {
+		conn, err = dial(network, addr, config)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	if *verbose {
+		log.Printf("connected %s -> %s", conn.LocalAddr(), conn.RemoteAddr())
+	}
+
+	c := conn
+	if *idle > 0 {
+		c = idleConn{c, *idle}
+	}
+	var r relay
+	if *hexdump {
+		r.dump = log.New(os.Stderr, "", 0)
+	}
+	if *verbose {
+		r.log = log.Default()
+	}
+	if err := r.run(c, os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// tlsConfig returns the TLS configuration described by the flags.
+func tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{ServerName: *name, InsecureSkipVerify: *insecure}
+	if *certFile != "" || *keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if *caFile != "" {
+		data, err := os.ReadFile(*caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no certificates found", *caFile)
+		}
+		config.RootCAs = pool   // verifies servers, in dial mode
+		config.ClientCAs = pool // verifies clients, in listen mode
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if *listen && len(config.Certificates) == 0 {
+		return nil, fmt.Errorf("-l -tls requires -cert and -key")
+	}
+	return config, nil
+}
+
+// An idleConn is a connection whose reads time out
+// when nothing has been received for d.
+type idleConn struct {
+	net.Conn
+	d time.Duration
+}
+
+func (c idleConn) Read(p []byte) (int, error) {
+	c.SetReadDeadline(time.Now().Add(c.d))
+	return c.Conn.Read(p)
+}
+
+// CloseWrite closes the write half of the underlying
+// connection, if it has one.
+func (c idleConn) CloseWrite() error { return closeWrite(c.Conn) }
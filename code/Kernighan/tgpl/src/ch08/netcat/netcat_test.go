@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reverb serves like gopl.io/ch8/reverb3, waiting for
+// its echoes to finish before closing the connection.
+func reverb(t *testing.T, l net.Listener) {
+	t.Helper()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		input := bufio.NewScanner(conn)
+		for input.Scan() {
+			shout := input.Text()
+			time.Sleep(20 * time.Millisecond)
+			fmt.Fprintln(conn, strings.ToUpper(shout))
+			time.Sleep(20 * time.Millisecond)
+			fmt.Fprintln(conn, strings.ToLower(shout))
+		}
+	}()
+}
+
+func TestHalfClose(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		addr := "127.0.0.1:0"
+		if network == "unix" {
+			addr = filepath.Join(t.TempDir(), "sock")
+		}
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		reverb(t, l)
+
+		conn, err := dial(network, l.Addr().String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		var r relay
+		if err := r.run(conn, strings.NewReader("Hello\nGo\n"), &out); err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		// The echoes arrive after the input is exhausted.
+		if got, want := out.String(), "HELLO\nhello\nGO\ngo\n"; got != want {
+			t.Errorf("%s: got %q, want %q", network, got, want)
+		}
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	// A UDP peer that never replies: without -w, netcat would wait for ever.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	conn, err := dial("udp", pc.LocalAddr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var r relay
+	start := time.Now()
+	if err := r.run(idleConn{conn, 50 * time.Millisecond}, strings.NewReader("ping"), io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("idle timeout of 50ms took %v", d)
+	}
+	buf := make([]byte, 10)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	if n, _, err := pc.ReadFrom(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("peer received %q, %v", buf[:n], err)
+	}
+}
+
+func TestAcceptUDP(t *testing.T) {
+	// Reserve a free port, then listen on it.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := pc.LocalAddr().String()
+	pc.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := accept("udp", addr, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+
+	client, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var server net.Conn
+	for server == nil {
+		client.Write([]byte("hello")) // the listener may not be ready yet
+		select {
+		case server = <-accepted:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	defer server.Close()
+	if server.RemoteAddr().String() != client.LocalAddr().String() {
+		t.Errorf("peer is %s, want %s", server.RemoteAddr(), client.LocalAddr())
+	}
+	buf := make([]byte, 10)
+	if n, err := server.Read(buf); err != nil || string(buf[:n]) != "hello" {
+		t.Errorf("first read %q, %v", buf[:n], err)
+	}
+	server.Write([]byte("world"))
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := client.Read(buf); err != nil || string(buf[:n]) != "world" {
+		t.Errorf("reply %q, %v", buf[:n], err)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	// Borrow the test certificate of an httptest server.
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := srv.TLS.Certificates[0]
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	srv.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	done := make(chan string)
+	go func() {
+		conn, err := accept("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err != nil {
+			t.Error(err)
+			done <- ""
+			return
+		}
+		defer conn.Close()
+		var out bytes.Buffer
+		var r relay
+		if err := r.run(conn, strings.NewReader("from server\n"), &out); err != nil {
+			t.Error(err)
+		}
+		done <- out.String()
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 100; i++ {
+		// The server certificate is for example.com.
+		conn, err = dial("tcp", addr, &tls.Config{RootCAs: pool, ServerName: "example.com"})
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	var dump bytes.Buffer
+	r := relay{dump: log.New(&dump, "", 0)}
+	if err := r.run(conn, strings.NewReader("from client\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if got := out.String(); got != "from server\n" {
+		t.Errorf("client received %q", got)
+	}
+	if got := <-done; got != "from client\n" {
+		t.Errorf("server received %q", got)
+	}
+	if !strings.Contains(dump.String(), "> 12 bytes") || !strings.Contains(dump.String(), "|from client.|") {
+		t.Errorf("hex dump:\n%s", dump.String())
+	}
+
+	// An unverified certificate is refused.
+	go accept("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}})
+	time.Sleep(20 * time.Millisecond)
+	if _, err := dial("tcp", addr, &tls.Config{ServerName: "example.com"}); err == nil {
+		t.Errorf("dial succeeded without trusting the server's certificate")
+	}
+}
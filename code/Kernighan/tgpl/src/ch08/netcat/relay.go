@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+)
+
+// A relay copies data in both directions between a connection and
+// a pair of local streams.
+type relay struct {
+	dump *log.Logger // if non-nil, hex dumps of traffic
+	log  *log.Logger // if non-nil, connection events
+}
+
+func (r *relay) logf(format string, args ...interface{}) {
+	if r.log != nil {
+		r.log.Printf(format, args...)
+	}
+}
+
+// run copies in to conn and conn to out. When in is exhausted, it
+// closes the write half of conn but goes on copying conn to out until
+// the peer closes the connection or a read deadline expires.
+func (r *relay) run(conn net.Conn, in io.Reader, out io.Writer) error {
+	var dst io.Writer = conn
+	var src io.Reader = conn
+	if r.dump != nil {
+		dst = io.MultiWriter(conn, &dumper{r.dump, ">"})
+		src = io.TeeReader(conn, &dumper{r.dump, "<"})
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		n, err := io.Copy(dst, in)
+		r.logf("sent %d bytes", n)
+		if err == nil {
+			err = closeWrite(conn)
+			r.logf("closed write half")
+		}
+		sent <- err
+	}()
+	received := make(chan error, 1)
+	go func() {
+		n, err := io.Copy(out, src)
+		r.logf("received %d bytes", n)
+		received <- err
+	}()
+
+	for {
+		select {
+		case err := <-sent:
+			if err != nil {
+				return fmt.Errorf("sending: %v", err)
+			}
+			sent = nil // keep receiving
+		case err := <-received:
+			switch {
+			case isTimeout(err):
+				r.logf("idle timeout")
+			case err != nil:
+				return fmt.Errorf("receiving: %v", err)
+			default:
+				r.logf("connection closed by peer")
+			}
+			return nil
+		}
+	}
+}
+
+// A dumper is an io.Writer that logs a hex dump of each write,
+// labelled with a direction, ">" for sent or "<" for received.
+type dumper struct {
+	log *log.Logger
+	dir string
+}
+
+func (d *dumper) Write(p []byte) (int, error) {
+	d.log.Printf("%s %d bytes\n%s", d.dir, len(p), hex.Dump(p))
+	return len(p), nil
+}
@@ -0,0 +1,307 @@
+// Package pipeline provides typed, cancellable pipeline stages.
+//
+// It generalizes the counter, squarer and printer of gopl.io/ch8/pipeline3:
+// each stage runs in its own goroutines and communicates with the next
+// through a channel, but all stages share a Pipeline, whose context is
+// cancelled as soon as any stage fails. Every goroutine selects on that
+// context when sending, so a failure anywhere, or cancellation of the
+// parent context, makes every stage return and close its output.
+//
+// A typical pipeline starts with Source or Values and ends with Sink:
+//
+//	p := pipeline.New(ctx)
+//	nums := pipeline.Values(p, 1, 2, 3)
+//	squares := pipeline.Map(p, nums, 1, func(_ context.Context, x int) (int, error) {
+//		return x * x, nil
+//	})
+//	pipeline.Sink(p, squares, func(_ context.Context, x int) error {
+//		fmt.Println(x)
+//		return nil
+//	})
+//	err := p.Wait()
+//
+// The output of every stage must be consumed by another stage;
+// otherwise Wait will not return.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Pipeline is a group of connected stages that share a context.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error // first error; set once
+}
+
+// New returns a new Pipeline whose stages stop when ctx is done.
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the pipeline's context, which is cancelled when any
+// stage fails, when Cancel is called, or when Wait returns.
+func (p *Pipeline) Context() context.Context { return p.ctx }
+
+// Cancel stops all stages of the pipeline.
+func (p *Pipeline) Cancel() { p.cancel() }
+
+// Wait waits for all stages to return, and returns the first error
+// reported by any stage or, if the pipeline was cancelled before it
+// finished, the context's error.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.fail(p.ctx.Err()) // no-op if a stage failed first
+	p.cancel()
+	return p.err
+}
+
+// fail records err, if it is the first error, and cancels the pipeline.
+func (p *Pipeline) fail(err error) {
+	if err == nil {
+		return
+	}
+	p.once.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// run runs f in a new goroutine belonging to p.
+func (p *Pipeline) run(f func() error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.fail(f())
+	}()
+}
+
+// send sends v on out, and reports whether it did so
+// before the pipeline was cancelled.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Source starts a stage that calls gen to produce values. Gen should call
+// emit for each value, and return when emit returns false, which means
+// the pipeline has been cancelled. The output is closed when gen returns.
+func Source[T any](p *Pipeline, gen func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	out := make(chan T)
+	p.run(func() error {
+		defer close(out)
+		return gen(p.ctx, func(v T) bool { return send(p.ctx, out, v) })
+	})
+	return out
+}
+
+// Values starts a stage that produces the values vs.
+func Values[T any](p *Pipeline, vs ...T) <-chan T {
+	return Source(p, func(ctx context.Context, emit func(T) bool) error {
+		for _, v := range vs {
+			if !emit(v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Map starts a stage that applies f to each value from in, using the
+// specified number of goroutines. If workers > 1, the order of the
+// values is not preserved. If f fails, the pipeline is cancelled.
+func Map[T, U any](p *Pipeline, in <-chan T, workers int, f func(context.Context, T) (U, error)) <-chan U {
+	if workers < 1 {
+		workers = 1
+	}
+	out := make(chan U)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		p.run(func() error {
+			defer wg.Done()
+			for v := range in {
+				u, err := f(p.ctx, v)
+				if err != nil {
+					return err
+				}
+				if !send(p.ctx, out, u) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	p.run(func() error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Filter starts a stage that passes on only the values
+// from in for which keep returns true.
+func Filter[T any](p *Pipeline, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	p.run(func() error {
+		defer close(out)
+		for v := range in {
+			if keep(v) && !send(p.ctx, out, v) {
+				break
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// FanOut starts n stages that share the values from in: each value
+// is sent to exactly one of the n outputs, whichever is ready first.
+// It panics if n < 1, since nothing would then drain in.
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	if n < 1 {
+		panic(fmt.Sprintf("pipeline: FanOut of %d outputs", n))
+	}
+	outs := make([]<-chan T, n)
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+		p.run(func() error {
+			defer close(out)
+			for v := range in {
+				if !send(p.ctx, out, v) {
+					break
+				}
+			}
+			return nil
+		})
+	}
+	return outs
+}
+
+// FanIn starts a stage that merges the values from ins into one channel,
+// which is closed once all of ins are closed.
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		p.run(func() error {
+			defer wg.Done()
+			for v := range in {
+				if !send(p.ctx, out, v) {
+					break
+				}
+			}
+			return nil
+		})
+	}
+	p.run(func() error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Batch starts a stage that groups the values from in into slices
+// of up to size values. If maxWait > 0, a partial batch is sent once
+// its first value has waited that long. The last batch may be short.
+func Batch[T any](p *Pipeline, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan []T)
+	p.run(func() error {
+		defer close(out)
+		var (
+			batch   []T
+			timer   *time.Timer
+			timeout <-chan time.Time // nil while batch is empty
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timeout = nil
+			}
+			b := batch
+			batch = nil
+			return len(b) == 0 || send(p.ctx, out, b)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+				if len(batch) == size && !flush() {
+					return nil
+				}
+			case <-timeout:
+				if !flush() {
+					return nil
+				}
+			case <-p.ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Throttle starts a stage that passes on the values from in
+// at a rate of at most one per interval.
+func Throttle[T any](p *Pipeline, in <-chan T, interval time.Duration) <-chan T {
+	out := make(chan T)
+	p.run(func() error {
+		defer close(out)
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for v := range in {
+			select {
+			case <-tick.C:
+			case <-p.ctx.Done():
+				return nil
+			}
+			if !send(p.ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Sink starts a final stage that calls f for each value from in.
+// If f fails, the pipeline is cancelled.
+func Sink[T any](p *Pipeline, in <-chan T, f func(context.Context, T) error) {
+	p.run(func() error {
+		for v := range in {
+			if p.ctx.Err() != nil {
+				return nil
+			}
+			if err := f(p.ctx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
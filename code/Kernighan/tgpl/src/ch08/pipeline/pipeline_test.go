@@ -0,0 +1,251 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"gopl.io/ch8/pipeline"
+)
+
+// checkLeaks fails the test if, shortly after it ends,
+// more goroutines are running than when it began.
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		var after int
+		for i := 0; i < 100; i++ {
+			if after = runtime.NumGoroutine(); after <= before {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		buf := make([]byte, 1<<16)
+		buf = buf[:runtime.Stack(buf, true)]
+		t.Errorf("%d goroutines before, %d after:\n%s", before, after, buf)
+	})
+}
+
+// counter produces 0, 1, 2, ... until cancelled.
+func counter(ctx context.Context, emit func(int) bool) error {
+	for x := 0; emit(x); x++ {
+	}
+	return nil
+}
+
+func square(_ context.Context, x int) (int, error) { return x * x, nil }
+
+// collect returns a Sink function that appends to *out.
+func collect[T any](out *[]T) func(context.Context, T) error {
+	return func(_ context.Context, v T) error {
+		*out = append(*out, v)
+		return nil
+	}
+}
+
+func TestMapFilter(t *testing.T) {
+	checkLeaks(t)
+	p := pipeline.New(context.Background())
+	nums := pipeline.Values(p, 1, 2, 3, 4, 5, 6)
+	odd := pipeline.Filter(p, nums, func(x int) bool { return x%2 == 1 })
+	squares := pipeline.Map(p, odd, 1, square)
+	var got []int
+	pipeline.Sink(p, squares, collect(&got))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[1 9 25]" {
+		t.Errorf("got %v, want [1 9 25]", got)
+	}
+}
+
+func TestFirstErrorCancelsUpstream(t *testing.T) {
+	checkLeaks(t)
+	errBoom := errors.New("boom")
+	p := pipeline.New(context.Background())
+	nums := pipeline.Source(p, counter) // infinite
+	squares := pipeline.Map(p, nums, 4, func(ctx context.Context, x int) (int, error) {
+		if x == 100 {
+			return 0, errBoom
+		}
+		return x * x, nil
+	})
+	batches := pipeline.Batch(p, squares, 10, 0)
+	pipeline.Sink(p, batches, func(context.Context, []int) error { return nil })
+
+	done := make(chan error)
+	go func() { done <- p.Wait() }()
+	select {
+	case err := <-done:
+		if err != errBoom {
+			t.Errorf("Wait returned %v, want %v", err, errBoom)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not stop after error")
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	checkLeaks(t)
+	errFull := errors.New("full")
+	p := pipeline.New(context.Background())
+	n := 0
+	pipeline.Sink(p, pipeline.Source(p, counter), func(context.Context, int) error {
+		if n++; n == 10 {
+			return errFull
+		}
+		return nil
+	})
+	if err := p.Wait(); err != errFull {
+		t.Errorf("Wait returned %v, want %v", err, errFull)
+	}
+}
+
+func TestParentCancel(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := pipeline.New(ctx)
+	nums := pipeline.Source(p, counter)
+	outs := pipeline.FanOut(p, nums, 3)
+	merged := pipeline.FanIn(p, outs...)
+	pipeline.Sink(p, merged, func(_ context.Context, x int) error {
+		if x == 50 {
+			cancel()
+		}
+		return nil
+	})
+	if err := p.Wait(); err != context.Canceled {
+		t.Errorf("Wait returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFanOutFanIn(t *testing.T) {
+	checkLeaks(t)
+	p := pipeline.New(context.Background())
+	var nums []int
+	for i := 0; i < 1000; i++ {
+		nums = append(nums, i)
+	}
+	outs := pipeline.FanOut(p, pipeline.Values(p, nums...), 4)
+	var squared []<-chan int
+	for _, out := range outs {
+		squared = append(squared, pipeline.Map(p, out, 1, square))
+	}
+	var got []int
+	pipeline.Sink(p, pipeline.FanIn(p, squared...), collect(&got))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1000 {
+		t.Fatalf("got %d values, want 1000", len(got))
+	}
+	sort.Ints(got)
+	for i, v := range got {
+		if v != i*i {
+			t.Fatalf("got[%d] = %d, want %d", i, v, i*i)
+		}
+	}
+}
+
+func TestFanOutNone(t *testing.T) {
+	p := pipeline.New(context.Background())
+	defer p.Cancel()
+	defer func() {
+		if recover() == nil {
+			t.Error("FanOut of 0 outputs did not panic")
+		}
+	}()
+	pipeline.FanOut(p, pipeline.Values(p, 1, 2, 3), 0)
+}
+
+func TestBatch(t *testing.T) {
+	checkLeaks(t)
+	p := pipeline.New(context.Background())
+	var got [][]int
+	pipeline.Sink(p, pipeline.Batch(p, pipeline.Values(p, 1, 2, 3, 4, 5, 6, 7), 3, 0), collect(&got))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[[1 2 3] [4 5 6] [7]]" {
+		t.Errorf("got %v", got)
+	}
+
+	// A slow source: partial batches are flushed after maxWait.
+	p = pipeline.New(context.Background())
+	slow := pipeline.Source(p, func(ctx context.Context, emit func(int) bool) error {
+		for i := 0; i < 4; i++ {
+			if !emit(i) {
+				return nil
+			}
+			time.Sleep(30 * time.Millisecond)
+		}
+		return nil
+	})
+	got = nil
+	pipeline.Sink(p, pipeline.Batch(p, slow, 100, 5*time.Millisecond), collect(&got))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Errorf("got batches %v, want four of one value each", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	checkLeaks(t)
+	p := pipeline.New(context.Background())
+	start := time.Now()
+	var got []int
+	pipeline.Sink(p, pipeline.Throttle(p, pipeline.Values(p, 1, 2, 3, 4, 5), 20*time.Millisecond), collect(&got))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("5 values at 20ms intervals took %v", elapsed)
+	}
+	if len(got) != 5 {
+		t.Errorf("got %v", got)
+	}
+
+	// Cancelling a throttled pipeline does not wait for the ticker.
+	p = pipeline.New(context.Background())
+	pipeline.Sink(p, pipeline.Throttle(p, pipeline.Source(p, counter), time.Hour), collect(&got))
+	time.AfterFunc(10*time.Millisecond, p.Cancel)
+	if err := p.Wait(); err != context.Canceled {
+		t.Errorf("Wait returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func Example() {
+	// The pipeline of gopl.io/ch8/pipeline3: counter, squarer, printer.
+	p := pipeline.New(context.Background())
+	naturals := pipeline.Source(p, func(ctx context.Context, emit func(int) bool) error {
+		for x := 0; x < 5; x++ {
+			if !emit(x) {
+				break
+			}
+		}
+		return nil
+	})
+	squares := pipeline.Map(p, naturals, 1, func(_ context.Context, x int) (int, error) {
+		return x * x, nil
+	})
+	pipeline.Sink(p, squares, func(_ context.Context, x int) error {
+		fmt.Println(x)
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// 0
+	// 1
+	// 4
+	// 9
+	// 16
+}
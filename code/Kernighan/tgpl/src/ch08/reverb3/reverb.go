@@ -0,0 +1,57 @@
+// Reverb3 is a TCP server that simulates an echo.
+//
+// Unlike gopl.io/ch8/reverb2, which closes the connection as soon as
+// the client stops shouting, even if echoes are still due, reverb3
+// counts the echoes in flight for each connection with a
+// sync.WaitGroup and closes the connection only when the last one
+// has finished (Exercise 8.4). A client that closes only its write
+// half, such as gopl.io/ch8/netcat, thus hears every echo.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+func echo(c net.Conn, shout string, delay time.Duration) {
+	fmt.Fprintln(c, "\t", strings.ToUpper(shout))
+	time.Sleep(delay)
+	fmt.Fprintln(c, "\t", shout)
+	time.Sleep(delay)
+	fmt.Fprintln(c, "\t", strings.ToLower(shout))
+}
+
+func handleConn(c net.Conn) {
+	var wg sync.WaitGroup // number of echoes in flight
+	input := bufio.NewScanner(c)
+	for input.Scan() {
+		wg.Add(1)
+		go func(shout string) {
+			defer wg.Done()
+			echo(c, shout, 1*time.Second)
+		}(input.Text())
+	}
+	// NOTE: ignoring potential errors from input.Err()
+	wg.Wait()
+	c.Close()
+}
+
+func main() {
+	l, err := net.Listen("tcp", "localhost:8000")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Print(err) // e.g., connection aborted
+			continue
+		}
+		go handleConn(conn)
+	}
+}
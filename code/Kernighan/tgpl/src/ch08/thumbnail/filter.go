@@ -0,0 +1,66 @@
+package thumbnail
+
+import "math"
+
+// A Filter is a resampling kernel used by Resize.
+type Filter struct {
+	Name    string
+	Support float64                 // the kernel is zero outside [-Support, Support]
+	Kernel  func(x float64) float64 // weight of a sample at distance x
+}
+
+// The available filters, from fastest and blurriest to slowest and sharpest.
+var (
+	// Box averages the source pixels covered by each destination pixel.
+	Box = Filter{"box", 0.5, func(x float64) float64 {
+		if -0.5 <= x && x < 0.5 {
+			return 1
+		}
+		return 0
+	}}
+
+	// Bilinear interpolates linearly between neighbouring pixels.
+	Bilinear = Filter{"bilinear", 1, func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1 {
+			return 1 - x
+		}
+		return 0
+	}}
+
+	// CatmullRom is a cubic filter that keeps edges sharp.
+	CatmullRom = Filter{"catmull-rom", 2, func(x float64) float64 {
+		x = math.Abs(x)
+		switch {
+		case x < 1:
+			return (1.5*x-2.5)*x*x + 1
+		case x < 2:
+			return ((-0.5*x+2.5)*x-4)*x + 2
+		}
+		return 0
+	}}
+
+	// Lanczos is a three-lobed windowed sinc filter.
+	Lanczos = Filter{"lanczos", 3, func(x float64) float64 {
+		if x = math.Abs(x); x < 3 {
+			return sinc(x) * sinc(x/3)
+		}
+		return 0
+	}}
+)
+
+// Filters maps the name of each filter to the filter.
+var Filters = map[string]Filter{
+	Box.Name:        Box,
+	Bilinear.Name:   Bilinear,
+	CatmullRom.Name: CatmullRom,
+	Lanczos.Name:    Lanczos,
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
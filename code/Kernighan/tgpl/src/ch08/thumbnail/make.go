@@ -0,0 +1,230 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A Size is the bounding box of a thumbnail, in pixels.
+type Size struct{ Width, Height int }
+
+// A Mode determines how an image is fitted to a Size.
+type Mode int
+
+const (
+	Fit  Mode = iota // scale to fit within the box, preserving aspect ratio
+	Fill             // scale to cover the box, then crop the excess from the center
+)
+
+// Options controls the thumbnails made by Make and MakeThumbnails.
+// The zero value, like a nil *Options, makes one thumbnail that fits
+// within 128×128, in the input's format, using CatmullRom.
+type Options struct {
+	Sizes   []Size // one thumbnail per size; nil => {{128, 128}}
+	Mode    Mode
+	Filter  Filter // zero => CatmullRom
+	Format  string // "jpeg", "png" or "gif"; "" => same as input
+	Quality int    // JPEG quality, 1-100; 0 => jpeg.DefaultQuality
+}
+
+// Decode reads an image from r, rotating or flipping it upright
+// as directed by any EXIF orientation. It also returns the name
+// of the image's format, as reported by image.Decode.
+func Decode(r io.Reader) (image.Image, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+	return img, format, nil
+}
+
+// Thumbnail returns a version of src scaled to size using filter f.
+func Thumbnail(src image.Image, size Size, mode Mode, f Filter) image.Image {
+	if f.Kernel == nil {
+		f = CatmullRom
+	}
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw == 0 || sh == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+	xscale := float64(size.Width) / float64(sw)
+	yscale := float64(size.Height) / float64(sh)
+
+	if mode == Fill {
+		// Crop src to the aspect ratio of size, then scale.
+		scale := xscale
+		if yscale > scale {
+			scale = yscale
+		}
+		cw := int(float64(size.Width)/scale + 0.5)
+		ch := int(float64(size.Height)/scale + 0.5)
+		min := src.Bounds().Min.Add(image.Pt((sw-cw)/2, (sh-ch)/2))
+		src = crop(src, image.Rectangle{min, min.Add(image.Pt(cw, ch))})
+		return Resize(src, size.Width, size.Height, f)
+	}
+
+	scale := xscale
+	if yscale < scale {
+		scale = yscale
+	}
+	w := int(float64(sw)*scale + 0.5)
+	h := int(float64(sh)*scale + 0.5)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return Resize(src, w, h, f)
+}
+
+// crop returns the part of src within r.
+func crop(src image.Image, r image.Rectangle) image.Image {
+	if s, ok := src.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			dst.Set(x, y, src.At(r.Min.X+x, r.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// Encode writes img to w in the named format.
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "jpeg":
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "png":
+		return png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// Make reads an image from infile and writes a thumbnail of it for
+// each of opts.Sizes in the same directory, decoding infile just once.
+// It returns the generated file names, e.g. "foo.thumb-128x128.jpeg".
+func Make(infile string, opts *Options) ([]string, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	in, err := os.Open(infile)
+	if err != nil {
+		return nil, err
+	}
+	src, format, err := Decode(in)
+	in.Close()
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", infile, err)
+	}
+
+	ext := filepath.Ext(infile) // e.g., ".jpg", ".JPEG"
+	if opts.Format != "" && opts.Format != format {
+		format = opts.Format
+		ext = "." + format
+	}
+	sizes := opts.Sizes
+	if sizes == nil {
+		sizes = []Size{{128, 128}}
+	}
+
+	var outfiles []string
+	for _, size := range sizes {
+		outfile := fmt.Sprintf("%s.thumb-%dx%d%s",
+			strings.TrimSuffix(infile, filepath.Ext(infile)), size.Width, size.Height, ext)
+		dst := Thumbnail(src, size, opts.Mode, opts.Filter)
+		if err := writeFile(outfile, dst, format, opts.Quality); err != nil {
+			return outfiles, fmt.Errorf("scaling %s to %s: %v", infile, outfile, err)
+		}
+		outfiles = append(outfiles, outfile)
+	}
+	return outfiles, nil
+}
+
+func writeFile(filename string, img image.Image, format string, quality int) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := Encode(out, img, format, quality); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// MakeThumbnails makes thumbnails of the specified files in parallel,
+// with at most workers files in progress at once. It returns the
+// generated file names in an arbitrary order. If any file fails, it
+// returns the first error, and files not yet started are skipped.
+func MakeThumbnails(ctx context.Context, filenames []string, opts *Options, workers int) ([]string, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		sema     = make(chan struct{}, workers) // counting semaphore
+		mu       sync.Mutex                     // guards thumbs and firstErr
+		thumbs   []string
+		firstErr error
+	)
+loop:
+	for _, f := range filenames {
+		select {
+		case sema <- struct{}{}: // acquire token
+		case <-ctx.Done():
+			break loop
+		}
+		wg.Add(1)
+		go func(f string) {
+			defer wg.Done()
+			defer func() { <-sema }() // release token
+			if ctx.Err() != nil {
+				return // cancelled while waiting
+			}
+			names, err := Make(f, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			thumbs = append(thumbs, names...)
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}(f)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return thumbs, firstErr
+	}
+	return thumbs, ctx.Err()
+}
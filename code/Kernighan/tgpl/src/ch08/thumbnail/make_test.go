@@ -0,0 +1,214 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func uniform(w, h int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestResizePreservesUniformColor(t *testing.T) {
+	c := color.RGBA{200, 100, 50, 255}
+	src := uniform(37, 23, c)
+	for _, f := range Filters {
+		for _, size := range []Size{{10, 7}, {74, 46}, {1, 1}} {
+			dst := Resize(src, size.Width, size.Height, f)
+			if got := dst.Bounds().Size(); got != image.Pt(size.Width, size.Height) {
+				t.Errorf("%s: size %v, want %v", f.Name, got, size)
+			}
+			for _, pt := range []image.Point{{0, 0}, {size.Width / 2, size.Height / 2}, {size.Width - 1, size.Height - 1}} {
+				if got := dst.RGBAAt(pt.X, pt.Y); got != c {
+					t.Errorf("%s %v: pixel %v = %v, want %v", f.Name, size, pt, got, c)
+				}
+			}
+		}
+	}
+}
+
+func TestThumbnailModes(t *testing.T) {
+	src := uniform(400, 200, color.White)
+	for _, test := range []struct {
+		size Size
+		mode Mode
+		want image.Point
+	}{
+		{Size{128, 128}, Fit, image.Pt(128, 64)},
+		{Size{128, 128}, Fill, image.Pt(128, 128)},
+		{Size{50, 100}, Fit, image.Pt(50, 25)},
+		{Size{50, 100}, Fill, image.Pt(50, 100)},
+	} {
+		got := Thumbnail(src, test.size, test.mode, Bilinear).Bounds().Size()
+		if got != test.want {
+			t.Errorf("Thumbnail(400x200, %v, %v) is %v, want %v", test.size, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestFillCropsCenter(t *testing.T) {
+	// A wide image: red left third, green middle, blue right third.
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := [3]color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}[x/100]
+			src.SetRGBA(x, y, c)
+		}
+	}
+	dst := Thumbnail(src, Size{10, 10}, Fill, Box).(*image.RGBA)
+	if got := dst.RGBAAt(0, 5); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("left edge of square crop = %v, want green", got)
+	}
+}
+
+// withOrientation returns the JPEG data with an EXIF APP1 segment
+// recording orientation o inserted after the SOI marker.
+func withOrientation(data []byte, o uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))     // IFD0 offset
+	binary.Write(&tiff, binary.BigEndian, uint16(1))     // entry count
+	binary.Write(&tiff, binary.BigEndian, uint16(0x112)) // Orientation
+	binary.Write(&tiff, binary.BigEndian, uint16(3))     // SHORT
+	binary.Write(&tiff, binary.BigEndian, uint32(1))     // count
+	binary.Write(&tiff, binary.BigEndian, o)
+	binary.Write(&tiff, binary.BigEndian, uint16(0))
+	binary.Write(&tiff, binary.BigEndian, uint32(0)) // no next IFD
+
+	seg := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(data[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(seg)+2))
+	out.Write(seg)
+	out.Write(data[2:])
+	return out.Bytes()
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	// A 40x20 image, white with a black top-left quadrant.
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if x < 20 && y < 10 {
+				src.Set(x, y, color.Black)
+			} else {
+				src.Set(x, y, color.White)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		o     uint16
+		size  image.Point
+		black image.Point // a point in the black quadrant
+	}{
+		{1, image.Pt(40, 20), image.Pt(5, 5)},
+		{2, image.Pt(40, 20), image.Pt(35, 5)},
+		{3, image.Pt(40, 20), image.Pt(35, 15)},
+		{4, image.Pt(40, 20), image.Pt(5, 15)},
+		{5, image.Pt(20, 40), image.Pt(5, 5)},
+		{6, image.Pt(20, 40), image.Pt(15, 5)},
+		{7, image.Pt(20, 40), image.Pt(15, 35)},
+		{8, image.Pt(20, 40), image.Pt(5, 35)},
+	} {
+		img, format, err := Decode(bytes.NewReader(withOrientation(buf.Bytes(), test.o)))
+		if err != nil {
+			t.Fatalf("orientation %d: %v", test.o, err)
+		}
+		if format != "jpeg" {
+			t.Errorf("orientation %d: format %s", test.o, format)
+		}
+		if got := img.Bounds().Size(); got != test.size {
+			t.Errorf("orientation %d: size %v, want %v", test.o, got, test.size)
+			continue
+		}
+		if r, _, _, _ := img.At(test.black.X, test.black.Y).RGBA(); r > 0x2000 {
+			t.Errorf("orientation %d: pixel %v is not black", test.o, test.black)
+		}
+	}
+}
+
+func writePNG(t *testing.T, filename string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMakeThumbnails(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		name = filepath.Join(dir, name)
+		writePNG(t, name, uniform(300, 200, color.Gray{128}))
+		files = append(files, name)
+	}
+
+	opts := &Options{Sizes: []Size{{64, 64}, {32, 32}}, Format: "jpeg", Mode: Fill}
+	thumbs, err := MakeThumbnails(context.Background(), files, opts, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(thumbs)
+	var names []string
+	for _, th := range thumbs {
+		names = append(names, filepath.Base(th))
+	}
+	want := "a.thumb-32x32.jpeg a.thumb-64x64.jpeg b.thumb-32x32.jpeg b.thumb-64x64.jpeg c.thumb-32x32.jpeg c.thumb-64x64.jpeg"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("thumbnails %s, want %s", got, want)
+	}
+	f, err := os.Open(thumbs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil || format != "jpeg" || cfg.Width != 32 || cfg.Height != 32 {
+		t.Errorf("%s: %s %dx%d, %v; want 32x32 jpeg", thumbs[0], format, cfg.Width, cfg.Height, err)
+	}
+
+	// A bad file fails the batch, and later files are not started.
+	bad := filepath.Join(dir, "bad.png")
+	os.WriteFile(bad, []byte("not an image"), 0666)
+	var many []string
+	for i := 0; i < 50; i++ {
+		many = append(many, files[0])
+	}
+	_, err = MakeThumbnails(context.Background(), append([]string{bad}, many...), nil, 1)
+	if err == nil || !strings.Contains(err.Error(), "bad.png") {
+		t.Errorf("MakeThumbnails with bad file returned %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := MakeThumbnails(ctx, files, nil, 2); err != context.Canceled {
+		t.Errorf("MakeThumbnails with cancelled context returned %v", err)
+	}
+}
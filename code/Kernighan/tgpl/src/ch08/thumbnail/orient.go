@@ -0,0 +1,110 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// exifOrientation returns the value of the EXIF Orientation tag
+// of the JPEG file data, or 1 (normal) if it has none.
+func exifOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1 // not a JPEG
+	}
+	data = data[2:]
+	for len(data) >= 4 && data[0] == 0xFF {
+		marker := data[1]
+		if marker == 0xDA || marker == 0xD9 {
+			break // start of scan or end of image: no more metadata
+		}
+		n := int(binary.BigEndian.Uint16(data[2:4]))
+		if n < 2 || len(data) < 2+n {
+			break
+		}
+		seg := data[4 : 2+n]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		data = data[2+n:]
+	}
+	return 1
+}
+
+// tiffOrientation returns the Orientation tag of the first IFD
+// of the TIFF structure in an EXIF segment, or 1 if it has none.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 0 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(tiff) {
+			break
+		}
+		const orientationTag, shortType = 0x0112, 3
+		if order.Uint16(tiff[e:]) == orientationTag && order.Uint16(tiff[e+2:]) == shortType {
+			if o := int(order.Uint16(tiff[e+8:])); 1 <= o && o <= 8 {
+				return o
+			}
+		}
+	}
+	return 1
+}
+
+// orient returns src transformed as required by EXIF orientation o,
+// so that it displays upright.
+func orient(src image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return src
+	}
+	b := src.Bounds()
+	in := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w // transposed
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			// (sx, sy) is the source of destination pixel (x, y).
+			var sx, sy int
+			switch o {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs rotating 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs rotating 90° counterclockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], in.Pix[in.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
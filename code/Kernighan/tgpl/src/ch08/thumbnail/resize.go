@@ -0,0 +1,127 @@
+package thumbnail
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Resize returns a width×height copy of src, resampled with filter f.
+// It resamples rows and then columns, so its cost is proportional to
+// the kernel's support, not its area.
+func Resize(src image.Image, width, height int, f Filter) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	b := src.Bounds()
+	if width <= 0 || height <= 0 || b.Empty() {
+		return dst
+	}
+
+	// Work on a premultiplied copy; draw has fast paths for common types.
+	in, ok := src.(*image.RGBA)
+	if !ok {
+		in = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+	}
+	sw, sh := in.Rect.Dx(), in.Rect.Dy()
+
+	// Horizontal pass: sw×sh -> width×sh.
+	tmp := make([]float64, width*sh*4)
+	xw := weights(sw, width, f)
+	for y := 0; y < sh; y++ {
+		row := in.Pix[y*in.Stride:]
+		for x, ws := range xw {
+			var r, g, bl, a float64
+			for _, w := range ws {
+				p := row[w.index*4:]
+				r += w.weight * float64(p[0])
+				g += w.weight * float64(p[1])
+				bl += w.weight * float64(p[2])
+				a += w.weight * float64(p[3])
+			}
+			t := tmp[(y*width+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, bl, a
+		}
+	}
+
+	// Vertical pass: width×sh -> width×height.
+	yw := weights(sh, height, f)
+	for y, ws := range yw {
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < width; x++ {
+			var r, g, bl, a float64
+			for _, w := range ws {
+				t := tmp[(w.index*width+x)*4:]
+				r += w.weight * t[0]
+				g += w.weight * t[1]
+				bl += w.weight * t[2]
+				a += w.weight * t[3]
+			}
+			// Filters with negative lobes can overshoot; a premultiplied
+			// color component must not exceed alpha.
+			alpha := clamp(a, 255)
+			o := out[x*4:]
+			o[0], o[1], o[2], o[3] = clamp(r, alpha), clamp(g, alpha), clamp(bl, alpha), alpha
+		}
+	}
+	return dst
+}
+
+func clamp(v float64, max uint8) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= float64(max):
+		return max
+	}
+	return uint8(v + 0.5)
+}
+
+type weight struct {
+	index  int // source pixel
+	weight float64
+}
+
+// weights returns, for each of the n destination pixels along an axis,
+// the source pixels (out of m) that contribute to it and their weights.
+func weights(m, n int, f Filter) [][]weight {
+	scale := float64(m) / float64(n)
+	fscale := math.Max(scale, 1) // widen the kernel when shrinking
+	support := f.Support * fscale
+
+	ws := make([][]weight, n)
+	for i := range ws {
+		center := (float64(i)+0.5)*scale - 0.5
+		lo := int(math.Ceil(center - support))
+		hi := int(math.Floor(center + support))
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			w := f.Kernel((float64(j) - center) / fscale)
+			if w == 0 {
+				continue
+			}
+			k := j
+			if k < 0 {
+				k = 0
+			} else if k >= m {
+				k = m - 1
+			}
+			ws[i] = append(ws[i], weight{k, w})
+			sum += w
+		}
+		if sum == 0 {
+			// Degenerate kernel: use the nearest pixel.
+			k := int(math.Round(center))
+			if k < 0 {
+				k = 0
+			} else if k >= m {
+				k = m - 1
+			}
+			ws[i] = []weight{{k, 1}}
+			continue
+		}
+		for j := range ws[i] {
+			ws[i][j].weight /= sum
+		}
+	}
+	return ws
+}
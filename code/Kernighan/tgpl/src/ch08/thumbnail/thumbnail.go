@@ -4,7 +4,12 @@
 // See page 234.
 
 // The thumbnail package produces thumbnail-size images from
-// larger images.  Only JPEG images are currently supported.
+// larger images.
+//
+// Image, ImageStream and ImageFile use crude nearest-neighbour
+// scaling to a fixed size and always write JPEG. Make and
+// MakeThumbnails offer a choice of resampling filters, sizes,
+// fit or fill modes and output formats, and honor EXIF orientation.
 package thumbnail
 
 import (
@@ -0,0 +1,132 @@
+// Webchat is a chat server that lets TCP clients (such as netcat3)
+// and browser clients (via WebSocket or Server-Sent Events) chat
+// with each other through a single broadcaster.
+//
+// Usage:
+//
+//	webchat [-tcp localhost:8000] [-http localhost:8080] [-history 50]
+//
+// Browsers should visit the -http address; TCP clients connect
+// to the -tcp address exactly as with gopl.io/ch8/chat.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+)
+
+var (
+	tcpAddr  = flag.String("tcp", "localhost:8000", "TCP listen address")
+	httpAddr = flag.String("http", "localhost:8080", "HTTP listen address")
+	history  = flag.Int("history", 50, "number of messages replayed to new clients")
+)
+
+type client chan<- string // an outgoing message channel
+
+var (
+	entering = make(chan client)
+	leaving  = make(chan client)
+	messages = make(chan string) // all incoming client messages
+)
+
+// broadcaster is the broadcaster of gopl.io/ch8/chat, extended to
+// remember the most recent messages and replay them to each client
+// as it enters.
+func broadcaster() {
+	clients := make(map[client]bool) // all connected clients
+	var recent []string              // the last *history messages
+	for {
+		select {
+		case msg := <-messages:
+			if *history > 0 {
+				if len(recent) == *history {
+					recent = recent[1:]
+				}
+				recent = append(recent, msg)
+			}
+			// Broadcast incoming message to all
+			// clients' outgoing message channels.
+			for cli := range clients {
+				cli <- msg
+			}
+
+		case cli := <-entering:
+			for _, msg := range recent {
+				cli <- msg
+			}
+			clients[cli] = true
+
+		case cli := <-leaving:
+			delete(clients, cli)
+			close(cli)
+		}
+	}
+}
+
+// leave announces that who has left and removes ch from the
+// broadcaster. It keeps draining ch until the broadcaster closes it,
+// so that a message sent to ch in the meantime cannot deadlock the
+// broadcaster.
+func leave(ch chan string, who string) {
+	go func() {
+		leaving <- ch
+		messages <- who + " has left"
+	}()
+	for range ch {
+		// Discard.
+	}
+}
+
+func handleConn(conn net.Conn) {
+	ch := make(chan string) // outgoing client messages
+	go clientWriter(conn, ch)
+
+	who := conn.RemoteAddr().String()
+	ch <- "You are " + who
+	messages <- who + " has arrived"
+	entering <- ch
+
+	input := bufio.NewScanner(conn)
+	for input.Scan() {
+		messages <- who + ": " + input.Text()
+	}
+	if err := input.Err(); err != nil {
+		log.Printf("%s: %v", who, err)
+	}
+
+	leaving <- ch
+	messages <- who + " has left"
+	conn.Close()
+}
+
+func clientWriter(conn net.Conn, ch <-chan string) {
+	for msg := range ch {
+		fmt.Fprintln(conn, msg) // NOTE: ignoring network errors
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *tcpAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go broadcaster()
+	go func() {
+		log.Fatal(http.ListenAndServe(*httpAddr, newMux()))
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		go handleConn(conn)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handlePage)
+	mux.Handle("/ws", websocket.Handler(handleWebSocket))
+	mux.HandleFunc("/events", handleEvents)
+	mux.HandleFunc("/send", handleSend)
+	return mux
+}
+
+func handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, page)
+}
+
+// handleWebSocket is the WebSocket analogue of handleConn:
+// each text frame received is one chat message.
+func handleWebSocket(ws *websocket.Conn) {
+	ch := make(chan string) // outgoing client messages
+	go wsWriter(ws, ch)
+
+	who := ws.Request().RemoteAddr
+	ch <- "You are " + who
+	messages <- who + " has arrived"
+	entering <- ch
+
+	for {
+		var text string
+		if err := websocket.Message.Receive(ws, &text); err != nil {
+			if err != io.EOF {
+				log.Printf("%s: %v", who, err)
+			}
+			break
+		}
+		messages <- who + ": " + text
+	}
+
+	leaving <- ch
+	messages <- who + " has left"
+	ws.Close()
+}
+
+func wsWriter(ws *websocket.Conn, ch <-chan string) {
+	for msg := range ch {
+		websocket.Message.Send(ws, msg) // NOTE: ignoring network errors
+	}
+}
+
+// SSE is one-way, so a browser using it receives messages from
+// /events and posts its own to /send. The two requests are tied
+// together by a random token sent, to that client alone, in the
+// first event. Unlike the name, which every client sees, the token
+// is a secret, and unlike the remote address, which HTTP/2 clients
+// may share, it is unique to one stream.
+var sse struct {
+	sync.Mutex
+	clients map[string]string // name of each connected SSE client, by token
+}
+
+// newToken returns a random token identifying an SSE stream.
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
+
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	who := r.RemoteAddr
+	token := newToken()
+	sse.Lock()
+	if sse.clients == nil {
+		sse.clients = make(map[string]string)
+	}
+	sse.clients[token] = who
+	sse.Unlock()
+	defer func() {
+		sse.Lock()
+		delete(sse.clients, token)
+		sse.Unlock()
+	}()
+
+	fmt.Fprintf(w, "event: token\ndata: %s\n\n", token)
+	writeEvent(w, "You are "+who)
+	flusher.Flush()
+
+	ch := make(chan string) // outgoing client messages
+	messages <- who + " has arrived"
+	entering <- ch
+	for {
+		select {
+		case msg := <-ch:
+			writeEvent(w, msg)
+			flusher.Flush()
+		case <-r.Context().Done():
+			leave(ch, who)
+			return
+		}
+	}
+}
+
+// writeEvent writes msg as a single SSE message event.
+func writeEvent(w io.Writer, msg string) {
+	for _, line := range strings.Split(msg, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+func handleSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sse.Lock()
+	who, ok := sse.clients[r.FormValue("token")]
+	sse.Unlock()
+	if !ok {
+		http.Error(w, "unknown client", http.StatusForbidden)
+		return
+	}
+	messages <- who + ": " + r.FormValue("text")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// page tries a WebSocket first and falls back to SSE+POST.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Chat</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+#log { height: 24em; overflow-y: scroll; border: 1px solid #ccc; padding: 0.5em; white-space: pre-wrap; }
+#text { width: 80%; }
+</style>
+</head>
+<body>
+<h1>Chat</h1>
+<div id="log"></div>
+<form id="form"><input id="text" autocomplete="off" autofocus> <button>Send</button></form>
+<p><small id="transport"></small></p>
+<script>
+var log = document.getElementById("log");
+var text = document.getElementById("text");
+var send;
+
+function show(msg) {
+	var div = document.createElement("div");
+	div.textContent = msg;
+	log.appendChild(div);
+	log.scrollTop = log.scrollHeight;
+}
+
+function transport(name) {
+	document.getElementById("transport").textContent = "connected via " + name;
+}
+
+function useSSE() {
+	var token = "";
+	var es = new EventSource("/events");
+	es.addEventListener("token", function(e) { token = e.data; });
+	es.onmessage = function(e) { show(e.data); };
+	es.onopen = function() { transport("Server-Sent Events"); };
+	send = function(msg) {
+		var body = new URLSearchParams({token: token, text: msg});
+		fetch("/send", {method: "POST", body: body});
+	};
+}
+
+function useWebSocket() {
+	var proto = location.protocol === "https:" ? "wss:" : "ws:";
+	var ws = new WebSocket(proto + "//" + location.host + "/ws");
+	var opened = false;
+	ws.onopen = function() { opened = true; transport("WebSocket"); };
+	ws.onmessage = function(e) { show(e.data); };
+	ws.onclose = function() {
+		if (!opened) {
+			useSSE();
+		} else {
+			show("(disconnected)");
+		}
+	};
+	send = function(msg) { ws.send(msg); };
+}
+
+if (window.WebSocket) {
+	useWebSocket();
+} else {
+	useSSE();
+}
+
+document.getElementById("form").onsubmit = function(e) {
+	e.preventDefault();
+	if (text.value !== "") {
+		send(text.value);
+		text.value = "";
+	}
+};
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var startOnce sync.Once
+
+// start starts the broadcaster, which runs until the tests end.
+func start() { startOnce.Do(func() { go broadcaster() }) }
+
+// await receives from ch until it gets want, failing after a while.
+func await(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed while awaiting %q", want)
+			}
+			if msg == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out awaiting %q", want)
+		}
+	}
+}
+
+// backlog is the capacity of the channels returned by lines and
+// events, so that a client the test is not reading cannot block
+// the broadcaster.
+const backlog = 1000
+
+// lines sends each line read from r on the returned channel.
+func lines(r io.Reader) <-chan string {
+	ch := make(chan string, backlog)
+	go func() {
+		input := bufio.NewScanner(r)
+		for input.Scan() {
+			ch <- input.Text()
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func TestBroadcaster(t *testing.T) {
+	start()
+	messages <- "before"
+	ch := make(chan string, *history+10)
+	entering <- ch
+	await(t, ch, "before") // replayed from history
+	messages <- "after"
+	await(t, ch, "after")
+	leaving <- ch
+	for range ch {
+		// Drain until the broadcaster closes ch.
+	}
+}
+
+func TestTCP(t *testing.T) {
+	start()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handleConn(conn)
+		}
+	}()
+
+	a, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	fromA := lines(a)
+	b, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := b.LocalAddr().String()
+	await(t, fromA, who+" has arrived")
+	io.WriteString(b, "hello\n")
+	await(t, fromA, who+": hello")
+	b.Close()
+	await(t, fromA, who+" has left")
+}
+
+// readEvent reads one event from an SSE stream.
+func readEvent(r *bufio.Reader) (event, data string, err error) {
+	var datas []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, strings.Join(datas, "\n"), nil
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			datas = append(datas, strings.TrimPrefix(line, "data: "))
+		}
+	}
+}
+
+// events sends the data of each message event read from r on the
+// returned channel, after returning the token from the first event.
+func events(t *testing.T, r io.Reader) (string, <-chan string) {
+	t.Helper()
+	in := bufio.NewReader(r)
+	event, token, err := readEvent(in)
+	if err != nil || event != "token" || len(token) != 32 {
+		t.Fatalf("first event = %q %q, %v; want token", event, token, err)
+	}
+	ch := make(chan string, backlog)
+	go func() {
+		for {
+			_, data, err := readEvent(in)
+			if err != nil {
+				close(ch)
+				return
+			}
+			ch <- data
+		}
+	}()
+	return token, ch
+}
+
+func send(url, token, text string) (int, error) {
+	resp, err := http.PostForm(url+"/send", map[string][]string{"token": {token}, "text": {text}})
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestSSE(t *testing.T) {
+	start()
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	token, ch := events(t, resp.Body)
+	first := <-ch
+	who, ok := strings.CutPrefix(first, "You are ")
+	if !ok {
+		t.Fatalf("first message = %q", first)
+	}
+
+	if code, err := send(srv.URL, token, "hi"); err != nil || code != http.StatusNoContent {
+		t.Fatalf("send: %d, %v", code, err)
+	}
+	await(t, ch, who+": hi")
+
+	// The name, which all clients see, does not identify the client.
+	for _, bad := range []string{who, "", "0123456789abcdef0123456789abcdef"} {
+		if code, err := send(srv.URL, bad, "forged"); err != nil || code != http.StatusForbidden {
+			t.Errorf("send with token %q: %d, %v; want 403", bad, code, err)
+		}
+	}
+	if resp, err := http.Get(srv.URL + "/send"); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /send: %v, %v", resp.Status, err)
+	}
+}
+
+// streamWriter is a ResponseWriter that streams its body to a pipe.
+type streamWriter struct {
+	*io.PipeWriter
+	header http.Header
+}
+
+func (w streamWriter) Header() http.Header { return w.header }
+func (w streamWriter) WriteHeader(int)     {}
+func (w streamWriter) Flush()              {}
+
+// TestSharedAddress checks that streams from the same remote address,
+// as HTTP/2 multiplexes them, are told apart.
+func TestSharedAddress(t *testing.T) {
+	start()
+	stream := func() (string, <-chan string, *io.PipeReader, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+		req.RemoteAddr = "192.0.2.1:443"
+		pr, pw := io.Pipe()
+		go handleEvents(streamWriter{pw, make(http.Header)}, req)
+		token, ch := events(t, pr)
+		return token, ch, pr, cancel
+	}
+	token1, _, pr1, cancel1 := stream()
+	token2, ch2, pr2, cancel2 := stream()
+	defer pr2.Close()
+	defer cancel2()
+	if token1 == token2 {
+		t.Fatalf("both streams have token %s", token1)
+	}
+
+	// Closing the first stream leaves the second one able to send.
+	cancel1()
+	pr1.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		sse.Lock()
+		_, ok := sse.clients[token1]
+		sse.Unlock()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first stream was not removed")
+		}
+	}
+	w := httptest.NewRecorder()
+	handleSend(w, httptest.NewRequest("POST", "/send?"+url.Values{"token": {token2}, "text": {"still here"}}.Encode(), nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("send after other stream closed: %d %s", w.Code, w.Body)
+	}
+	await(t, ch2, "192.0.2.1:443: still here")
+}
@@ -0,0 +1,62 @@
+// Package memo provides a concurrency-safe memoization of a function,
+// as gopl.io/ch9/memo4 does, except that a call that fails is not
+// cached, so that a later request for the same key tries again.
+// Requests for different keys proceed in parallel.
+// Concurrent requests for the same key block until the first completes,
+// and share its result even if it fails.
+package memo
+
+import "sync"
+
+// Func is the type of the function to memoize.
+type Func func(string) (interface{}, error)
+
+type result struct {
+	value interface{}
+	err   error
+}
+
+type entry struct {
+	res   result
+	ready chan struct{} // closed when res is ready
+}
+
+func New(f Func) *Memo {
+	return &Memo{f: f, cache: make(map[string]*entry)}
+}
+
+type Memo struct {
+	f     Func
+	mu    sync.Mutex // guards cache
+	cache map[string]*entry
+}
+
+func (memo *Memo) Get(key string) (value interface{}, err error) {
+	memo.mu.Lock()
+	e := memo.cache[key]
+	if e == nil {
+		// This is the first request for this key.
+		// This goroutine becomes responsible for computing
+		// the value and broadcasting the ready condition.
+		e = &entry{ready: make(chan struct{})}
+		memo.cache[key] = e
+		memo.mu.Unlock()
+
+		e.res.value, e.res.err = memo.f(key)
+
+		if e.res.err != nil {
+			// Forget the failure. Requests already
+			// waiting for e still see it.
+			memo.mu.Lock()
+			delete(memo.cache, key)
+			memo.mu.Unlock()
+		}
+		close(e.ready) // broadcast ready condition
+	} else {
+		// This is a repeat request for this key.
+		memo.mu.Unlock()
+
+		<-e.ready // wait for ready condition
+	}
+	return e.res.value, e.res.err
+}
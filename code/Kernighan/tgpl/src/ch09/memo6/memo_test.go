@@ -0,0 +1,51 @@
+package memo_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"gopl.io/ch9/memo6"
+)
+
+func TestFailure(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fail := true
+	m := memo.New(func(key string) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if fail {
+			return nil, errors.New("no space left")
+		}
+		return len(key), nil
+	})
+
+	// A failure is not remembered.
+	if _, err := m.Get("hello"); err == nil {
+		t.Fatal("Get succeeded")
+	}
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if v, err := m.Get("hello"); err != nil || v != 5 {
+		t.Fatalf("Get after failure = %v, %v; want 5", v, err)
+	}
+
+	// A success is, and concurrent requests share it.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := m.Get("hello"); err != nil || v != 5 {
+				t.Errorf("Get = %v, %v; want 5", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 2 {
+		t.Errorf("f called %d times, want 2", calls)
+	}
+}
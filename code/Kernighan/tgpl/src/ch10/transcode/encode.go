@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sort"
+)
+
+// An encoder writes images in one format with fixed options.
+type encoder struct {
+	format string
+	jpeg   jpeg.Options
+	png    png.Encoder
+	gif    gif.Options
+}
+
+func newEncoder(format string, quality int, compression string, colors int, pal string, dither bool) (*encoder, error) {
+	enc := &encoder{format: format}
+	switch format {
+	case "jpeg":
+		if quality < 1 || quality > 100 {
+			return nil, fmt.Errorf("JPEG quality %d out of range 1-100", quality)
+		}
+		enc.jpeg.Quality = quality
+	case "png":
+		levels := map[string]png.CompressionLevel{
+			"default": png.DefaultCompression,
+			"none":    png.NoCompression,
+			"speed":   png.BestSpeed,
+			"best":    png.BestCompression,
+		}
+		level, ok := levels[compression]
+		if !ok {
+			return nil, fmt.Errorf("unknown PNG compression %q", compression)
+		}
+		enc.png.CompressionLevel = level
+	case "gif":
+		switch pal {
+		case "adaptive":
+			if colors < 2 || colors > 256 {
+				return nil, fmt.Errorf("GIF palette size %d out of range 2-256", colors)
+			}
+			enc.gif.NumColors = colors
+			enc.gif.Quantizer = medianCut{}
+		case "plan9":
+			enc.gif.NumColors = len(palette.Plan9)
+			enc.gif.Quantizer = fixed(palette.Plan9)
+		case "websafe":
+			enc.gif.NumColors = len(palette.WebSafe)
+			enc.gif.Quantizer = fixed(palette.WebSafe)
+		default:
+			return nil, fmt.Errorf("unknown GIF palette %q", pal)
+		}
+		enc.gif.Drawer = draw.Src // nearest color
+		if dither {
+			enc.gif.Drawer = draw.FloydSteinberg
+		}
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+	return enc, nil
+}
+
+func (enc *encoder) encode(w io.Writer, img image.Image) error {
+	switch enc.format {
+	case "jpeg":
+		return jpeg.Encode(w, img, &enc.jpeg)
+	case "png":
+		return enc.png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, &enc.gif)
+	}
+	panic("unreachable")
+}
+
+// ext returns the file name extension for the encoder's format.
+func (enc *encoder) ext() string {
+	if enc.format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + enc.format
+}
+
+// fixed is a draw.Quantizer that always chooses the same palette.
+type fixed color.Palette
+
+func (f fixed) Quantize(p color.Palette, m image.Image) color.Palette {
+	return append(p[:0], f...)
+}
+
+// medianCut is a draw.Quantizer that chooses a palette adapted to
+// the image by repeatedly splitting the box of colors with the
+// widest range at its median, and averaging the colors in each box.
+// A fully transparent color is included if any pixel is transparent.
+type medianCut struct{}
+
+func (medianCut) Quantize(p color.Palette, m image.Image) color.Palette {
+	n := cap(p) - len(p)
+	if n <= 0 {
+		return p
+	}
+
+	// Sample at most about 64K pixels.
+	b := m.Bounds()
+	step := 1
+	for b.Dx()*b.Dy()/(step*step) > 1<<16 {
+		step++
+	}
+	var pixels [][3]uint8
+	transparent := false
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				transparent = true
+				continue
+			}
+			pixels = append(pixels, [3]uint8{c.R, c.G, c.B})
+		}
+	}
+	if transparent {
+		p = append(p, color.RGBA{})
+		n--
+	}
+	if len(pixels) == 0 || n <= 0 {
+		return p
+	}
+
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < n {
+		// Find the box with the widest range in any channel.
+		best, bestCh, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			ch, r := widest(box)
+			if r > bestRange {
+				best, bestCh, bestRange = i, ch, r
+			}
+		}
+		if best < 0 {
+			break // every box holds a single color
+		}
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestCh] < box[j][bestCh] })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	for _, box := range boxes {
+		var sum [3]int
+		for _, px := range box {
+			for ch := range sum {
+				sum[ch] += int(px[ch])
+			}
+		}
+		k := len(box)
+		p = append(p, color.RGBA{uint8(sum[0] / k), uint8(sum[1] / k), uint8(sum[2] / k), 0xFF})
+	}
+	return p
+}
+
+// widest returns the channel with the widest range in box, and the range.
+func widest(box [][3]uint8) (ch, r int) {
+	min := [3]uint8{255, 255, 255}
+	var max [3]uint8
+	for _, px := range box {
+		for c := range px {
+			if px[c] < min[c] {
+				min[c] = px[c]
+			}
+			if px[c] > max[c] {
+				max[c] = px[c]
+			}
+		}
+	}
+	for c := range min {
+		if d := int(max[c]) - int(min[c]); d > r {
+			ch, r = c, d
+		}
+	}
+	return ch, r
+}
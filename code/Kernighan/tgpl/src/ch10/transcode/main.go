@@ -0,0 +1,233 @@
+// The transcode command converts images between formats.
+//
+// Like gopl.io/ch10/jpeg, it accepts input in any format whose decoder
+// is registered, but it can write JPEG, PNG or GIF, the last with an
+// adaptive (median cut) or fixed palette and optional dithering.
+//
+// With no file arguments, it reads the standard input and writes the
+// standard output. Otherwise it converts each named file, and each
+// image file in each named directory, using a pool of workers, and
+// reports the detected input format of each.
+//
+// Usage:
+//
+//	transcode [-from fmt] [-to jpeg|png|gif] [flags] <in >out
+//	transcode [-from fmt] [-to jpeg|png|gif] [-o dir] [flags] file|dir...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF decoder
+	_ "image/jpeg" // register JPEG decoder
+	_ "image/png"  // register PNG decoder
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	from        = flag.String("from", "", "required input format: jpeg, png or gif (default: any)")
+	to          = flag.String("to", "jpeg", "output format: jpeg, png or gif")
+	quality     = flag.Int("quality", 95, "JPEG quality, 1-100")
+	compression = flag.String("compression", "default", "PNG compression: default, none, speed or best")
+	colors      = flag.Int("colors", 256, "GIF palette size, 2-256, for -palette=adaptive")
+	pal         = flag.String("palette", "adaptive", "GIF palette: adaptive, plan9 or websafe")
+	dither      = flag.Bool("dither", true, "GIF: use Floyd-Steinberg error diffusion")
+	outDir      = flag.String("o", "", "output directory for batch mode (default: alongside each input)")
+	workers     = flag.Int("workers", runtime.NumCPU(), "number of files to convert in parallel")
+)
+
+func main() {
+	flag.Parse()
+	enc, err := newEncoder(*to, *quality, *compression, *colors, *pal, *dither)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "transcode: %v\n", err)
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 {
+		kind, err := transcode(os.Stdin, os.Stdout, *from, enc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "transcode: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "Input format =", kind)
+		return
+	}
+
+	files, err := collect(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "transcode: %v\n", err)
+		os.Exit(1)
+	}
+	failed := false
+	for r := range batch(files, *outDir, *from, enc, *workers) {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "transcode: %s: %v\n", r.in, r.err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s (%s) -> %s (%s)\n", r.in, r.kind, r.out, enc.format)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// transcode decodes an image from in and encodes it to out with enc,
+// returning the name of the input format. If want is not empty,
+// the input must be in that format.
+func transcode(in io.Reader, out io.Writer, want string, enc *encoder) (string, error) {
+	img, kind, err := image.Decode(in)
+	if err != nil {
+		return "", err
+	}
+	if want != "" && kind != want {
+		return kind, fmt.Errorf("input format is %s, not %s", kind, want)
+	}
+	return kind, enc.encode(out, img)
+}
+
+// imageExts are the file extensions that batch mode looks for in directories.
+var imageExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true}
+
+// collect returns the named files and the image files
+// within the named directories.
+func collect(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() && imageExts[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// A result reports the conversion of one file.
+type result struct {
+	in, out string
+	kind    string // detected input format
+	err     error
+}
+
+// outputs returns the result for each file, with the name of its
+// output file alongside it or, if dir is not empty, in dir. Files
+// whose outputs would collide, such as a.png and a.gif, or a/x.png
+// and b/x.png with -o, are given an error and are not converted.
+func outputs(files []string, dir, ext string) []result {
+	rs := make([]result, len(files))
+	first := make(map[string]int) // index of first file for each output
+	for i, in := range files {
+		out := strings.TrimSuffix(in, filepath.Ext(in)) + ext
+		if dir != "" {
+			out = filepath.Join(dir, filepath.Base(out))
+		}
+		rs[i] = result{in: in, out: out}
+		key := filepath.Clean(out)
+		if j, ok := first[key]; ok {
+			err := fmt.Errorf("%s and %s would both be converted to %s", files[j], in, out)
+			rs[i].err, rs[j].err = err, err
+			continue
+		}
+		first[key] = i
+	}
+	return rs
+}
+
+// batch converts files using n worker goroutines, sending a result
+// for each file on the returned channel, which is closed when done.
+func batch(files []string, dir, want string, enc *encoder, n int) <-chan result {
+	if n < 1 {
+		n = 1
+	}
+	jobs := make(chan result)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				if r.err == nil {
+					r.kind, r.err = convertFile(r.in, r.out, want, enc)
+				}
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		for _, r := range outputs(files, dir, enc.ext()) {
+			jobs <- r
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// convertFile converts the file in to the file out, returning the
+// detected input format. The output is written to a temporary file
+// in the same directory, which replaces out only if the conversion
+// succeeds, so out is never left half-written and in, even if it is
+// out under another name, is never harmed.
+func convertFile(in, out, want string, enc *encoder) (string, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if outInfo, err := os.Stat(out); err == nil && os.SameFile(info, outInfo) {
+		return "", fmt.Errorf("output %s would overwrite input; use -o", out)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".*")
+	if err != nil {
+		return "", err
+	}
+	kind, err := transcode(f, tmp, want, enc)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), out)
+	}
+	if err != nil {
+		os.Remove(tmp.Name()) // ours, not out
+		return kind, err
+	}
+	return kind, nil
+}
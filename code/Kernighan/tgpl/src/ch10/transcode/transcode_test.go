@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// gradient returns a w×h image whose red and green components vary across it.
+func gradient(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / w), uint8(y * 255 / h), 128, 255})
+		}
+	}
+	return img
+}
+
+func TestTranscode(t *testing.T) {
+	var in bytes.Buffer
+	if err := png.Encode(&in, gradient(64, 32)); err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{"jpeg", "png", "gif"} {
+		enc, err := newEncoder(format, 80, "best", 16, "adaptive", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		kind, err := transcode(bytes.NewReader(in.Bytes()), &out, "", enc)
+		if err != nil || kind != "png" {
+			t.Errorf("transcode to %s = %q, %v", format, kind, err)
+			continue
+		}
+		cfg, got, err := image.DecodeConfig(&out)
+		if err != nil || got != format || cfg.Width != 64 || cfg.Height != 32 {
+			t.Errorf("output: %s %dx%d, %v; want %s 64x32", got, cfg.Width, cfg.Height, err, format)
+		}
+	}
+
+	enc, _ := newEncoder("png", 0, "default", 0, "", false)
+	if _, err := transcode(bytes.NewReader(in.Bytes()), new(bytes.Buffer), "gif", enc); err == nil {
+		t.Errorf("transcode with -from=gif accepted PNG input")
+	}
+}
+
+func TestBadOptions(t *testing.T) {
+	for _, test := range []struct {
+		format, compression, pal string
+		quality, colors          int
+	}{
+		{"bmp", "default", "adaptive", 90, 256},
+		{"jpeg", "default", "adaptive", 0, 256},
+		{"png", "maximum", "adaptive", 90, 256},
+		{"gif", "default", "adaptive", 90, 1},
+		{"gif", "default", "vga", 90, 256},
+	} {
+		if _, err := newEncoder(test.format, test.quality, test.compression, test.colors, test.pal, true); err == nil {
+			t.Errorf("newEncoder(%+v) succeeded", test)
+		}
+	}
+}
+
+func TestMedianCut(t *testing.T) {
+	img := gradient(100, 100)
+	img.Set(0, 0, color.RGBA{}) // one transparent pixel
+	p := medianCut{}.Quantize(make(color.Palette, 0, 8), img)
+	if len(p) != 8 {
+		t.Fatalf("palette has %d colors, want 8", len(p))
+	}
+	if _, _, _, a := p[0].RGBA(); a != 0 {
+		t.Errorf("palette[0] = %v, want transparent", p[0])
+	}
+
+	// A two-color image needs only two entries.
+	two := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			if x < 5 {
+				two.Set(x, y, color.White)
+			} else {
+				two.Set(x, y, color.Black)
+			}
+		}
+	}
+	p = medianCut{}.Quantize(make(color.Palette, 0, 256), two)
+	if len(p) != 2 {
+		t.Errorf("palette for two-color image has %d colors, want 2", len(p))
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0777)
+	for _, name := range []string{filepath.Join(dir, "a.png"), filepath.Join(sub, "b.PNG")} {
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, gradient(8, 8))
+		f.Close()
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0666)
+	os.WriteFile(filepath.Join(dir, "bad.jpg"), []byte("not a JPEG"), 0666)
+
+	files, err := collect([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("collect found %v, want 3 images", files)
+	}
+
+	enc, _ := newEncoder("gif", 0, "", 256, "plan9", false)
+	var ok, failed []string
+	for r := range batch(files, "", "", enc, 2) {
+		if r.err != nil {
+			failed = append(failed, filepath.Base(r.in))
+			if _, err := os.Stat(r.out); err == nil {
+				t.Errorf("failed conversion left %s behind", r.out)
+			}
+			continue
+		}
+		if r.kind != "png" {
+			t.Errorf("%s: detected %s, want png", r.in, r.kind)
+		}
+		f, err := os.Open(r.out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := gif.Decode(f)
+		f.Close()
+		if err != nil || g.Bounds().Dx() != 8 {
+			t.Errorf("%s: %v", r.out, err)
+		}
+		ok = append(ok, filepath.Base(r.out))
+	}
+	sort.Strings(ok)
+	if len(ok) != 2 || ok[0] != "a.gif" || ok[1] != "b.gif" {
+		t.Errorf("converted %v, want [a.gif b.gif]", ok)
+	}
+	if len(failed) != 1 || failed[0] != "bad.jpg" {
+		t.Errorf("failed %v, want [bad.jpg]", failed)
+	}
+}
+
+// TestBatchSafety checks that batch mode never harms its inputs.
+func TestBatchSafety(t *testing.T) {
+	dir := t.TempDir()
+	writePNG := func(name string) {
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, gradient(4, 4))
+		f.Close()
+	}
+	a, b := filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png")
+	writePNG(a)
+	writePNG(b)
+	orig, _ := os.ReadFile(a)
+	enc, _ := newEncoder("png", 0, "default", 0, "", false)
+
+	// An alias of the input is the same file, though not the same name.
+	alias := filepath.Join(dir, ".", "sub", "..", "a.png")
+	os.Mkdir(filepath.Join(dir, "sub"), 0777)
+	if _, err := convertFile(a, alias, "", enc); err == nil {
+		t.Errorf("convertFile(%s, %s) succeeded", a, alias)
+	}
+	link := filepath.Join(dir, "link.png")
+	if err := os.Link(a, link); err == nil {
+		if _, err := convertFile(a, link, "", enc); err == nil {
+			t.Errorf("convertFile(%s, hard link) succeeded", a)
+		}
+	}
+	if got, _ := os.ReadFile(a); !bytes.Equal(got, orig) {
+		t.Fatal("input was modified")
+	}
+
+	// A failed conversion leaves an existing output as it was.
+	bad := filepath.Join(dir, "bad.jpg")
+	os.WriteFile(bad, []byte("not a JPEG"), 0666)
+	out := filepath.Join(dir, "bad.png")
+	os.WriteFile(out, []byte("keep"), 0666)
+	if _, err := convertFile(bad, out, "", enc); err == nil {
+		t.Error("converting bad.jpg succeeded")
+	}
+	if got, _ := os.ReadFile(out); string(got) != "keep" {
+		t.Errorf("failed conversion changed output to %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+
+	// Inputs with the same output are not converted.
+	other := filepath.Join(dir, "sub", "a.png")
+	writePNG(other)
+	outDir := filepath.Join(dir, "out")
+	os.Mkdir(outDir, 0777)
+	gifEnc, _ := newEncoder("gif", 0, "", 256, "plan9", false)
+	for _, test := range []struct {
+		files []string
+		dir   string
+		enc   *encoder
+	}{
+		{[]string{a, other}, outDir, gifEnc},
+		{[]string{a, filepath.Join(dir, "a.gif")}, "", gifEnc},
+		{[]string{a, a}, "", gifEnc},
+	} {
+		for r := range batch(test.files, test.dir, "", test.enc, 2) {
+			if r.err == nil {
+				t.Errorf("batch(%q, %q): %s converted to %s", test.files, test.dir, r.in, r.out)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "a.gif")); err == nil {
+		t.Error("colliding output was written")
+	}
+}
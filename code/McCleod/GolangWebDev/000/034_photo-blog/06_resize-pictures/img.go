@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopl.io/ch8/thumbnail"
+	"gopl.io/ch9/memo6"
+)
+
+// resized holds the paths of resized pictures, by request key.
+// Concurrent identical requests share one call to resize,
+// and a failed call is not remembered.
+var resized = memo.New(func(key string) (interface{}, error) {
+	return resize(parseKey(key))
+})
+
+// decodeTokens limits the number of images being resized at once.
+var decodeTokens = make(chan struct{}, 4)
+
+var (
+	picsDir  = "public/pics"
+	cacheDir = "cache"
+)
+
+// sizes are the allowed widths and heights. Allowing only a few
+// bounds the number of resized pictures, in memory and on disk.
+var sizes = []int{50, 100, 200, 400, 800, 1600}
+
+var shaPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// img serves /img/{sha}?w=200&h=200&fit=cover&fmt=png: the picture
+// in public/pics whose name is {sha}, scaled to fit within (or, with
+// fit=cover, to cover) w×h, in format fmt (by default, the format of
+// the upload). The width and height must be among sizes.
+//
+// Results are cached on disk in the cache directory under a name
+// made from the source hash and the parameters, so a result never
+// changes and browsers may cache it for ever.
+func img(w http.ResponseWriter, req *http.Request) {
+	// Accept "/img/{sha}" or "/img/{sha}.jpg".
+	sha := strings.TrimPrefix(req.URL.Path, "/img/")
+	if i := strings.IndexByte(sha, '.'); i >= 0 {
+		sha = sha[:i]
+	}
+	if !shaPattern.MatchString(sha) {
+		http.NotFound(w, req)
+		return
+	}
+	src, err := source(sha)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	q := req.URL.Query()
+	width, err1 := dimension(q.Get("w"))
+	height, err2 := dimension(q.Get("h"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "w and h must be one of "+strings.Trim(fmt.Sprint(sizes), "[]"), http.StatusBadRequest)
+		return
+	}
+	fit := q.Get("fit")
+	switch fit {
+	case "":
+		fit = "contain"
+	case "contain", "cover":
+	default:
+		http.Error(w, "fit must be contain or cover", http.StatusBadRequest)
+		return
+	}
+	format := q.Get("fmt")
+	switch format {
+	case "":
+		switch ext := strings.ToLower(filepath.Ext(src)); ext {
+		case ".png", ".gif":
+			format = ext[1:]
+		default:
+			format = "jpeg"
+		}
+	case "jpeg", "png", "gif":
+	default:
+		http.Error(w, "fmt must be jpeg, png or gif", http.StatusBadRequest)
+		return
+	}
+
+	r := request{sha, width, height, fit, format}
+	key := r.name()
+	etag := `"` + key + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if req.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	v, err := resized.Get(r.key())
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "can't resize image", http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(v.(string))
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "can't read image", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "can't read image", http.StatusInternalServerError)
+		return
+	}
+	// ServeContent sets Content-Type from the extension of key.
+	http.ServeContent(w, req, key, info.ModTime(), f)
+}
+
+// source returns the name of the uploaded picture with the given hash.
+func source(sha string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(picsDir, sha+".*"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", os.ErrNotExist
+	}
+	return matches[0], nil
+}
+
+func dimension(s string) (int, error) {
+	if s == "" {
+		return 200, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	for _, size := range sizes {
+		if n == size {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("dimension %d not allowed", n)
+}
+
+// A request describes a resized picture.
+type request struct {
+	sha           string // of the source
+	width, height int
+	fit, format   string
+}
+
+// name returns the name of the cache file for r,
+// "{sha}-{w}x{h}-{fit}.{fmt}".
+func (r request) name() string {
+	return fmt.Sprintf("%s-%dx%d-%s.%s", r.sha, r.width, r.height, r.fit, r.format)
+}
+
+// key returns the memo key for r, which parseKey turns back into r.
+func (r request) key() string {
+	return fmt.Sprintf("%s %d %d %s %s", r.sha, r.width, r.height, r.fit, r.format)
+}
+
+func parseKey(key string) request {
+	var r request
+	fmt.Sscan(key, &r.sha, &r.width, &r.height, &r.fit, &r.format)
+	return r
+}
+
+// resize is the function behind resized. It creates the cache file
+// for r if it does not exist yet, and returns its path.
+func resize(r request) (string, error) {
+	key := r.name()
+	path := filepath.Join(cacheDir, key)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil // cached by an earlier run
+	}
+
+	src, err := source(r.sha)
+	if err != nil {
+		return "", err
+	}
+
+	decodeTokens <- struct{}{} // acquire token
+	defer func() { <-decodeTokens }()
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	picture, _, err := thumbnail.Decode(in)
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %v", src, err)
+	}
+	mode := thumbnail.Fit
+	if r.fit == "cover" {
+		mode = thumbnail.Fill
+	}
+	dst := thumbnail.Thumbnail(picture, thumbnail.Size{Width: r.width, Height: r.height}, mode, thumbnail.CatmullRom)
+
+	// Write to a temporary file and rename it into place, so that
+	// a request never sees a partly written file.
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(cacheDir, key+".*")
+	if err != nil {
+		return "", err
+	}
+	if err := thumbnail.Encode(tmp, dst, r.format, 0); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSHA = "f382716c1600421f127c7414a072016434fcd43a" // in public/pics
+
+func TestKey(t *testing.T) {
+	r := request{testSHA, 100, 400, "cover", "jpeg"}
+	if got := parseKey(r.key()); got != r {
+		t.Errorf("parseKey(%q) = %+v, want %+v", r.key(), got, r)
+	}
+}
+
+func TestDimension(t *testing.T) {
+	for _, s := range []string{"", "50", "1600"} {
+		if _, err := dimension(s); err != nil {
+			t.Errorf("dimension(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"0", "-200", "201", "3200", "2e2", "x"} {
+		if n, err := dimension(s); err == nil {
+			t.Errorf("dimension(%q) = %d, want error", s, n)
+		}
+	}
+}
+
+func TestImg(t *testing.T) {
+	cacheDir = t.TempDir()
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		img(w, req)
+		return w
+	}
+
+	w := get("/img/"+testSHA+"?w=100&h=50&fit=cover&fmt=png", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("status %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	pic, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := pic.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Errorf("cover picture is %v, want 100×50", b)
+	}
+	name := testSHA + "-100x50-cover.png"
+	if _, err := os.Stat(filepath.Join(cacheDir, name)); err != nil {
+		t.Errorf("not cached: %v", err)
+	}
+	etag := w.Header().Get("ETag")
+	if etag != `"`+name+`"` {
+		t.Errorf("ETag = %s", etag)
+	}
+	if w := get("/img/"+testSHA+".jpg?w=100&h=50&fit=cover&fmt=png", etag); w.Code != http.StatusNotModified {
+		t.Errorf("with If-None-Match: status %d, want 304", w.Code)
+	}
+
+	for _, test := range []struct {
+		url  string
+		code int
+	}{
+		{"/img/" + testSHA, http.StatusOK},
+		{"/img/" + testSHA + "?w=250", http.StatusBadRequest},
+		{"/img/" + testSHA + "?fit=stretch", http.StatusBadRequest},
+		{"/img/" + testSHA + "?fmt=bmp", http.StatusBadRequest},
+		{"/img/0000000000000000000000000000000000000000", http.StatusNotFound},
+		{"/img/../main.go", http.StatusNotFound},
+	} {
+		if w := get(test.url, ""); w.Code != test.code {
+			t.Errorf("GET %s: status %d, want %d", test.url, w.Code, test.code)
+		}
+	}
+}